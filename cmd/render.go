@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/handlers"
+	"github.com/Octops/gameserver-ingress-controller/pkg/render"
+)
+
+var (
+	renderFilename           string
+	renderNamespace          string
+	renderName               string
+	renderState              string
+	renderControllerClass    string
+	renderDefaultAnnotations map[string]string
+)
+
+// renderCmd prints the resources the controller creates for the game servers of a manifest
+var renderCmd = &cobra.Command{
+	Use:   "render -f fleet.yaml",
+	Short: "Print the Service, Ingress and HTTPRoute generated for a manifest",
+	Long: `Render reads a Fleet, GameServerSet or GameServer manifest and prints the Service, Ingress and HTTPRoute
+the controller creates for a representative game server. No cluster is required.
+
+The random suffixes of the game server name are replaced by xxxxx and dynamic ports are given host ports
+starting at 7000. Namespace annotations are not read, use --default-annotations to simulate them.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := gameserver.ValidateDefaults(renderDefaultAnnotations); err != nil {
+			return errors.Wrap(err, "error parsing default-annotations flag")
+		}
+
+		f, err := os.Open(renderFilename)
+		if err != nil {
+			return errors.Wrap(err, "failed to open manifest")
+		}
+		defer f.Close()
+
+		sources, err := render.Decode(f, render.Options{
+			Namespace: renderNamespace,
+			Name:      renderName,
+			State:     agonesv1.GameServerState(renderState),
+		})
+		if err != nil {
+			return err
+		}
+
+		planner := handlers.NewPlanner(
+			handlers.WithControllerClass(renderControllerClass),
+			handlers.WithDefaults(nil, renderDefaultAnnotations),
+		)
+
+		out := cmd.OutOrStdout()
+		failed := false
+		for _, source := range sources {
+			gs := source.GameServer
+			fmt.Fprintf(out, "# %s %s/%s, gameserver %s/%s\n", source.Kind, source.Namespace, source.Name, gs.Namespace, gs.Name)
+
+			plan, err := planner.Plan(cmd.Context(), gs)
+			if err != nil {
+				return err
+			}
+
+			if !plan.Managed {
+				fmt.Fprintf(out, "# skipped: %s\n", plan.Reason)
+				continue
+			}
+
+			for _, err := range plan.Errors {
+				failed = true
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s %s/%s: %s\n", source.Kind, source.Namespace, source.Name, err)
+			}
+
+			if err := render.Write(out, render.Objects(plan)...); err != nil {
+				return err
+			}
+		}
+
+		if failed {
+			return errors.New("some resources can't be generated")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	renderCmd.Flags().StringVarP(&renderFilename, "filename", "f", "", "Fleet, GameServerSet or GameServer manifest, YAML or JSON. Multiple documents are supported")
+	renderCmd.Flags().StringVarP(&renderNamespace, "namespace", "n", "default", "Namespace of manifests without a namespace")
+	renderCmd.Flags().StringVar(&renderName, "name", "", "Name of the representative game server. Defaults to the name Agones would generate, with xxxxx as random suffixes")
+	renderCmd.Flags().StringVar(&renderState, "state", string(agonesv1.GameServerStateReady), "State of the representative game server")
+	renderCmd.Flags().StringVar(&renderControllerClass, "controller-class", "", "Controller class of the controller instance, see the controller flag with the same name")
+	renderCmd.Flags().StringToStringVar(&renderDefaultAnnotations, "default-annotations", nil, "Comma separated octops.io annotations used when the game server doesn't set them, e.g. octops.io/terminate-tls=true")
+	_ = renderCmd.MarkFlagRequired("filename")
+}
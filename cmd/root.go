@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
@@ -37,8 +38,47 @@ var (
 	healthProbeBindAddress  string
 	metricsBindAddress      string
 	verbose                 bool
+	logFormat               string
+	logLevel                string
 	maxConcurrentReconciles int
+	retryBaseDelay          time.Duration
+	retryMaxDelay           time.Duration
+	queueStallTimeout       time.Duration
+	leaderElect             bool
+	leaderElectionID        string
+	leaderElectionNamespace string
+	leaseDuration           time.Duration
+	renewDeadline           time.Duration
+	retryPeriod             time.Duration
+	shardingEnabled         bool
+	shardIdentity           string
+	shardNamespace          string
+	shardGroup              string
+	shardLeaseDuration      time.Duration
+	shardRenewInterval      time.Duration
+	watchNamespaces         []string
+	namespaceSelector       string
+	gameServerSelector      string
+	controllerClass         string
+	namespaceDefaults       bool
+	defaultAnnotations      map[string]string
+	domainPolicy            string
+	domainPolicyWebhook     bool
 	enableGatewayAPI        string
+	placeholderAddress      string
+	placeholderService      string
+	placeholderServicePort  int32
+	placeholderFormat       string
+	placeholderRetryAfter   time.Duration
+	placeholderPod          string
+	clusterDomain           string
+	orphanSweeper           string
+	orphanSweeperInterval   time.Duration
+	tracingEndpoint         string
+	tracingInsecure         bool
+	tracingSampleRatio      float64
+	debugAddress            string
+	dryRun                  bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -51,7 +91,17 @@ makes the traffic to be routed to the game server using an Ingress Controller.`,
 		ctx, stop := runtime.SetupSignal(context.Background())
 		defer stop()
 
-		logger := runtime.NewLogger(verbose)
+		level := logLevel
+		if verbose && !cmd.Flags().Changed("log-level") {
+			level = "debug"
+		}
+
+		logger, err := runtime.SetupLogger(runtime.LogOptions{Format: logFormat, Level: level})
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
 		app.StartController(ctx, logger, app.Config{
 			Kubeconfig:              kubeconfig,
 			SyncPeriod:              syncPeriod,
@@ -60,7 +110,44 @@ makes the traffic to be routed to the game server using an Ingress Controller.`,
 			MetricsBindAddress:      metricsBindAddress,
 			Verbose:                 verbose,
 			MaxConcurrentReconciles: maxConcurrentReconciles,
+			RetryBaseDelay:          retryBaseDelay,
+			RetryMaxDelay:           retryMaxDelay,
+			QueueStallTimeout:       queueStallTimeout,
+			LeaderElect:             leaderElect,
+			LeaderElectionID:        leaderElectionID,
+			LeaderElectionNamespace: leaderElectionNamespace,
+			LeaseDuration:           leaseDuration,
+			RenewDeadline:           renewDeadline,
+			RetryPeriod:             retryPeriod,
+			Sharding:                shardingEnabled,
+			ShardIdentity:           shardIdentity,
+			ShardNamespace:          shardNamespace,
+			ShardGroup:              shardGroup,
+			ShardLeaseDuration:      shardLeaseDuration,
+			ShardRenewInterval:      shardRenewInterval,
+			Namespaces:              watchNamespaces,
+			NamespaceSelector:       namespaceSelector,
+			GameServerSelector:      gameServerSelector,
+			ControllerClass:         controllerClass,
+			NamespaceDefaults:       namespaceDefaults,
+			DefaultAnnotations:      defaultAnnotations,
+			DomainPolicy:            domainPolicy,
+			DomainPolicyWebhook:     domainPolicyWebhook,
 			EnableGatewayAPI:        enableGatewayAPI,
+			PlaceholderAddress:      placeholderAddress,
+			PlaceholderService:      placeholderService,
+			PlaceholderServicePort:  placeholderServicePort,
+			PlaceholderFormat:       placeholderFormat,
+			PlaceholderRetryAfter:   placeholderRetryAfter,
+			PlaceholderPod:          placeholderPod,
+			ClusterDomain:           clusterDomain,
+			OrphanSweeper:           orphanSweeper,
+			OrphanSweeperInterval:   orphanSweeperInterval,
+			TracingEndpoint:         tracingEndpoint,
+			TracingInsecure:         tracingInsecure,
+			TracingSampleRatio:      tracingSampleRatio,
+			DebugAddress:            debugAddress,
+			DryRun:                  dryRun,
 		})
 	},
 }
@@ -86,11 +173,69 @@ func init() {
 	rootCmd.Flags().StringVar(&metricsBindAddress, "metrics-addrs", ":9090", "TCP address that the controller should bind to for serving prometheus metrics")
 	rootCmd.Flags().IntVar(&webhookPort, "webhook-port", 30234, "Port used by the controller for webhooks")
 	rootCmd.Flags().IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 10, "Maximum number of concurrent reconciles which can be run simultaneously")
+	rootCmd.Flags().DurationVar(&retryBaseDelay, "retry-base-delay", time.Millisecond*5, "Initial delay before retrying a failed reconcile. The delay doubles on every consecutive failure")
+	rootCmd.Flags().DurationVar(&retryMaxDelay, "retry-max-delay", time.Minute*5, "Maximum delay between retries of a failed reconcile")
+	rootCmd.Flags().DurationVar(&queueStallTimeout, "queue-stall-timeout", time.Minute*5, "Liveness fails if requests are waiting in the work queue and no reconcile completed for this duration")
+	rootCmd.Flags().BoolVar(&leaderElect, "leader-elect", false, "Enable leader election. Required when running more than one replica")
+	rootCmd.Flags().StringVar(&leaderElectionID, "leader-election-id", "octops-gameserver-ingress-controller", "Name of the Lease used for leader election")
+	rootCmd.Flags().StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "Namespace of the Lease used for leader election. Defaults to the namespace of the controller when running in-cluster")
+	rootCmd.Flags().DurationVar(&leaseDuration, "leader-election-lease-duration", time.Second*15, "Duration standbys wait before forcing to acquire leadership")
+	rootCmd.Flags().DurationVar(&renewDeadline, "leader-election-renew-deadline", time.Second*10, "Duration the leader retries refreshing leadership before giving it up")
+	rootCmd.Flags().DurationVar(&retryPeriod, "leader-election-retry-period", time.Second*2, "Duration between attempts to acquire or renew leadership")
+	rootCmd.Flags().StringSliceVar(&watchNamespaces, "namespaces", nil, "Comma separated list of namespaces to watch. All namespaces are watched if empty and no namespace selector is set")
+	rootCmd.Flags().StringVar(&namespaceSelector, "namespace-selector", "", "Label selector of the namespaces to watch, e.g. octops.io/ingress=enabled. Namespaces are added and removed while the controller runs")
+	rootCmd.Flags().StringVar(&gameServerSelector, "gameserver-selector", "", "Label selector of the game servers managed by this controller instance, e.g. octops.io/edge=public")
+	rootCmd.Flags().StringVar(&controllerClass, "controller-class", "", "Only manage game servers whose octops.io/controller-class annotation matches. If empty only game servers without the annotation are managed")
+	rootCmd.Flags().BoolVar(&namespaceDefaults, "namespace-defaults", true, "Use the octops.io annotations of a namespace as defaults for its game servers")
+	rootCmd.Flags().StringToStringVar(&defaultAnnotations, "default-annotations", nil, "Comma separated octops.io annotations used when neither the game server nor its namespace set them, e.g. octops.io/terminate-tls=true")
+	rootCmd.Flags().StringVar(&domainPolicy, "domain-policy", "", "Path of the policy file mapping domains to the namespaces allowed to use them. Every namespace can use any domain if empty")
+	rootCmd.Flags().BoolVar(&domainPolicyWebhook, "domain-policy-webhook", false, "Serve a validating webhook on the webhook port that rejects Fleets and GameServers violating the domain policy")
+	rootCmd.Flags().BoolVar(&shardingEnabled, "sharding", false, "Split game servers across replicas using consistent hashing. Can't be combined with --leader-elect")
+	rootCmd.Flags().StringVar(&shardIdentity, "shard-identity", defaultShardIdentity(), "Unique identity of the replica. Defaults to $POD_NAME or the hostname")
+	rootCmd.Flags().StringVar(&shardNamespace, "shard-namespace", os.Getenv("POD_NAMESPACE"), "Namespace of the shard membership Leases. Defaults to $POD_NAMESPACE")
+	rootCmd.Flags().StringVar(&shardGroup, "shard-group", "octops-gameserver-ingress-controller", "Prefix of the shard membership Leases. Replicas only share game servers with members of the same group")
+	rootCmd.Flags().DurationVar(&shardLeaseDuration, "shard-lease-duration", time.Second*15, "Duration after which a replica that stopped renewing its Lease leaves the ring")
+	rootCmd.Flags().DurationVar(&shardRenewInterval, "shard-renew-interval", time.Second*5, "Interval between renewals of the shard membership Lease")
 	rootCmd.Flags().BoolVar(&verbose, "verbose", false, "Produce verbose log")
+	_ = rootCmd.Flags().MarkDeprecated("verbose", "use --log-level=debug instead")
+	rootCmd.Flags().StringVar(&logFormat, "log-format", runtime.LogFormatText, "Log format: text or json")
+	rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "Log level: trace, debug, info, warn or error. Verbose client-go logs are written at debug and trace levels")
 	rootCmd.Flags().StringVar(&enableGatewayAPI, "enable-gateway-api", "auto", `Enable the Kubernetes Gateway API backend.
   auto  – enable if Gateway API CRDs are present in the cluster (default)
   true  – always enable; fail at startup if CRDs are missing
   false – always disable; no informer or client is created`)
+	rootCmd.Flags().StringVar(&placeholderAddress, "placeholder-addrs", "", "TCP address the placeholder backend binds to. The placeholder backend is disabled if empty")
+	rootCmd.Flags().StringVar(&placeholderService, "placeholder-service", "octops-system/octops-placeholder", "Service in the format namespace/name that exposes the placeholder backend")
+	rootCmd.Flags().Int32Var(&placeholderServicePort, "placeholder-service-port", 80, "Port of the placeholder backend Service")
+	rootCmd.Flags().StringVar(&placeholderFormat, "placeholder-format", "json", "Response format of the placeholder backend: json or html")
+	rootCmd.Flags().DurationVar(&placeholderRetryAfter, "placeholder-retry-after", time.Second*5, "Value of the Retry-After header returned for starting and draining game servers")
+	rootCmd.Flags().StringVar(&placeholderPod, "placeholder-pod", defaultPlaceholderPod(), "Pod in the format namespace/name labelled while the replica serves the placeholder backend. Defaults to $POD_NAMESPACE/$POD_NAME")
+	rootCmd.Flags().StringVar(&clusterDomain, "cluster-domain", "cluster.local", "DNS domain of the cluster, used to resolve the placeholder Service from other namespaces")
+	rootCmd.Flags().StringVar(&orphanSweeper, "orphan-sweeper", "off", "Orphan sweeper mode for Services, Ingresses and HTTPRoutes without a live GameServer: off, report or delete")
+	rootCmd.Flags().DurationVar(&orphanSweeperInterval, "orphan-sweeper-interval", time.Minute*10, "Interval between orphan sweeps")
+	rootCmd.Flags().StringVar(&tracingEndpoint, "tracing-endpoint", "", "host:port of the OTLP gRPC collector traces are exported to. Tracing is disabled if empty, unless $OTEL_EXPORTER_OTLP_ENDPOINT is set")
+	rootCmd.Flags().BoolVar(&tracingInsecure, "tracing-insecure", false, "Export traces without TLS")
+	rootCmd.Flags().Float64Var(&tracingSampleRatio, "tracing-sample-ratio", 1, "Fraction of game server reconciles traced, between 0 and 1")
+	rootCmd.Flags().StringVar(&debugAddress, "debug-addrs", "", "TCP address serving the routing state of game servers on /debug/gameservers. The debug endpoint is disabled if empty")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Send every write with server-side dry-run and log it with a diff instead, e.g. to shadow a live controller. Game servers are never annotated and no event is created")
+}
+
+func defaultShardIdentity() string {
+	if name := os.Getenv("POD_NAME"); len(name) > 0 {
+		return name
+	}
+
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+func defaultPlaceholderPod() string {
+	namespace, name := os.Getenv("POD_NAMESPACE"), os.Getenv("POD_NAME")
+	if len(namespace) == 0 || len(name) == 0 {
+		return ""
+	}
+
+	return namespace + "/" + name
 }
 
 // initConfig reads in config file and ENV variables if set.
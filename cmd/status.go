@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"agones.dev/agones/pkg/client/clientset/versioned"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/status"
+)
+
+var (
+	statusKubeconfig         string
+	statusNamespace          string
+	statusAllNamespaces      bool
+	statusFleet              string
+	statusOutput             string
+	statusControllerClass    string
+	statusNamespaceDefaults  bool
+	statusDefaultAnnotations map[string]string
+)
+
+// statusCmd reports the routing health of the game servers of a live cluster
+var statusCmd = &cobra.Command{
+	Use:   "status [-n namespace] [--fleet name]",
+	Short: "Report the routing health of the game servers managed by the controller",
+	Long: `Status lists the game servers managed by the controller and reports their public URL, the Service, the Ingress
+load balancer address, the HTTPRoute Accepted condition and the octops.io/ingress-ready annotation.
+
+Game servers whose routing state doesn't match are flagged with MISMATCH and the reasons are listed after the table.
+The command exits with an error if any game server has a mismatch.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if statusOutput != validateOutputText && statusOutput != validateOutputJSON {
+			return errors.Errorf("output %q is not supported, use text or json", statusOutput)
+		}
+
+		if err := gameserver.ValidateDefaults(statusDefaultAnnotations); err != nil {
+			return errors.Wrap(err, "error parsing default-annotations flag")
+		}
+
+		clients, err := statusClients(statusKubeconfig)
+		if err != nil {
+			return err
+		}
+
+		namespace := statusNamespace
+		if statusAllNamespaces {
+			namespace = ""
+		}
+
+		rows, err := status.Check(cmd.Context(), clients, status.Options{
+			Namespace:         namespace,
+			Fleet:             statusFleet,
+			ControllerClass:   statusControllerClass,
+			NamespaceDefaults: statusNamespaceDefaults,
+			Defaults:          statusDefaultAnnotations,
+		})
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if statusOutput == validateOutputJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(struct {
+				GameServers []status.Row `json:"gameservers"`
+			}{append([]status.Row{}, rows...)}); err != nil {
+				return err
+			}
+		} else if len(rows) == 0 {
+			fmt.Fprintln(out, "No managed game servers found")
+		} else if err := status.Write(out, rows); err != nil {
+			return err
+		}
+
+		mismatches := 0
+		for _, r := range rows {
+			if !r.Healthy() {
+				mismatches++
+			}
+		}
+		if mismatches > 0 {
+			return errors.Errorf("%d of %d game servers have mismatches", mismatches, len(rows))
+		}
+
+		return nil
+	},
+}
+
+func statusClients(kubeconfig string) (status.Clients, error) {
+	config, err := k8sutil.NewClusterConfig(kubeconfig)
+	if err != nil {
+		return status.Clients{}, errors.Wrap(err, "failed to load kubeconfig")
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return status.Clients{}, errors.Wrap(err, "failed to create kubernetes client")
+	}
+
+	agones, err := versioned.NewForConfig(config)
+	if err != nil {
+		return status.Clients{}, errors.Wrap(err, "failed to create agones client")
+	}
+
+	gateway, err := gatewayclient.NewForConfig(config)
+	if err != nil {
+		return status.Clients{}, errors.Wrap(err, "failed to create gateway-api client")
+	}
+
+	return status.Clients{Agones: agones, Kubernetes: client, Gateway: gateway}, nil
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVar(&statusKubeconfig, "kubeconfig", "", "Set KUBECONFIG")
+	statusCmd.Flags().StringVarP(&statusNamespace, "namespace", "n", "default", "Namespace of the game servers")
+	statusCmd.Flags().BoolVarP(&statusAllNamespaces, "all-namespaces", "A", false, "Report the game servers of all namespaces")
+	statusCmd.Flags().StringVar(&statusFleet, "fleet", "", "Only report the game servers of the fleet")
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", validateOutputText, "Output format: text or json")
+	statusCmd.Flags().StringVar(&statusControllerClass, "controller-class", "", "Controller class of the controller instance, see the controller flag with the same name")
+	statusCmd.Flags().BoolVar(&statusNamespaceDefaults, "namespace-defaults", true, "Controller instance uses the octops.io annotations of a namespace as defaults, see the controller flag with the same name")
+	statusCmd.Flags().StringToStringVar(&statusDefaultAnnotations, "default-annotations", nil, "Controller default annotations, see the controller flag with the same name")
+}
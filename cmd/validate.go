@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/lint"
+)
+
+const (
+	validateOutputText = "text"
+	validateOutputJSON = "json"
+)
+
+var (
+	validateFilenames          []string
+	validateOutput             string
+	validateDefaultAnnotations map[string]string
+)
+
+// validateCmd reports problems in the Octops annotations of manifests
+var validateCmd = &cobra.Command{
+	Use:   "validate -f fleet.yaml",
+	Short: "Check the Octops annotations of Fleets, GameServerSets and GameServers",
+	Long: `Validate reads Fleet, GameServerSet and GameServer manifests and reports every problem found in their Octops
+annotations with the file and line. Manifests are read from stdin if no file is given or the file is "-", so the
+output of helm template can be piped in. No cluster is required.
+
+The command exits with an error if any problem is an error. Warnings are only reported.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if validateOutput != validateOutputText && validateOutput != validateOutputJSON {
+			return errors.Errorf("output %q is not supported, use text or json", validateOutput)
+		}
+
+		if err := gameserver.ValidateDefaults(validateDefaultAnnotations); err != nil {
+			return errors.Wrap(err, "error parsing default-annotations flag")
+		}
+
+		filenames := validateFilenames
+		if len(filenames) == 0 {
+			filenames = []string{"-"}
+		}
+
+		linter := lint.NewLinter(lint.Options{Defaults: validateDefaultAnnotations})
+		problems := []lint.Problem{}
+		for _, filename := range filenames {
+			found, err := lintFile(cmd, linter, filename)
+			if err != nil {
+				return err
+			}
+			problems = append(problems, found...)
+		}
+
+		out := cmd.OutOrStdout()
+		if validateOutput == validateOutputJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(struct {
+				Problems []lint.Problem `json:"problems"`
+			}{problems}); err != nil {
+				return err
+			}
+		} else {
+			for _, p := range problems {
+				fmt.Fprintln(out, p)
+			}
+		}
+
+		if lint.HasErrors(problems) {
+			return errors.New("validation failed")
+		}
+
+		return nil
+	},
+}
+
+func lintFile(cmd *cobra.Command, linter *lint.Linter, filename string) ([]lint.Problem, error) {
+	var r io.Reader = cmd.InOrStdin()
+	name := "<stdin>"
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open manifest")
+		}
+		defer f.Close()
+		r, name = f, filename
+	}
+
+	return linter.Lint(cmd.Context(), name, r)
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringSliceVarP(&validateFilenames, "filename", "f", nil, "Manifests to validate, YAML or JSON. Multiple documents are supported. Reads stdin if empty or -")
+	validateCmd.Flags().StringVarP(&validateOutput, "output", "o", validateOutputText, "Output format: text or json")
+	validateCmd.Flags().StringToStringVar(&validateDefaultAnnotations, "default-annotations", nil, "Comma separated octops.io annotations used when the game server doesn't set them, e.g. octops.io/terminate-tls=true")
+}
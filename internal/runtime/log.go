@@ -1,32 +1,105 @@
 package runtime
 
-import "github.com/sirupsen/logrus"
+import (
+	"flag"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/klog/v2"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Formats of the log output.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Fields set on every log line about a GameServer, no matter which component writes it.
+const (
+	FieldComponent   = "component"
+	FieldGameServer  = "gameserver"
+	FieldNamespace   = "namespace"
+	FieldBackend     = "backend"
+	FieldReconcileID = "reconcile_id"
+)
 
 var (
 	log    *logrus.Logger
 	logger *logrus.Entry
 )
 
-func NewLogger(verbose bool) *logrus.Entry {
-	if log == nil {
-		log := logrus.New()
-		if verbose {
-			log.SetLevel(logrus.DebugLevel)
-		}
+// LogOptions configure the format and the level of the logger.
+type LogOptions struct {
+	// Format is either text or json.
+	Format string
+	// Level is any level supported by logrus, e.g. info or debug.
+	Level string
+}
 
-		logger = logrus.NewEntry(log)
+// SetupLogger configures the logger returned by Logger and makes controller-runtime and client-go write to it, so
+// every component shares the same format and level.
+func SetupLogger(options LogOptions) (*logrus.Entry, error) {
+	l, err := newLogrus(options)
+	if err != nil {
+		return nil, err
 	}
 
-	if logger == nil {
-		logger = logrus.NewEntry(log)
+	log = l
+	logger = logrus.NewEntry(l)
+
+	ctrllog.SetLogger(logr.New(newLogSink(logger)))
+	klog.SetLogger(logr.New(newLogSink(logger)).WithName("client-go"))
+	setKlogVerbosity(l.GetLevel())
+
+	return logger, nil
+}
+
+func newLogrus(options LogOptions) (*logrus.Logger, error) {
+	l := logrus.New()
+
+	switch options.Format {
+	case "", LogFormatText:
+		l.SetFormatter(&logrus.TextFormatter{})
+	case LogFormatJSON:
+		l.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return nil, errors.Errorf("invalid log format %q, must be one of text or json", options.Format)
 	}
 
-	return logger
+	if len(options.Level) > 0 {
+		level, err := logrus.ParseLevel(options.Level)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid log level %q", options.Level)
+		}
+		l.SetLevel(level)
+	}
+
+	return l, nil
+}
+
+// setKlogVerbosity lets the verbose logs of client-go through at debug level. Requests are only logged at trace level.
+func setKlogVerbosity(level logrus.Level) {
+	v := 0
+	switch {
+	case level >= logrus.TraceLevel:
+		v = 6
+	case level >= logrus.DebugLevel:
+		v = 2
+	}
+
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	_ = fs.Set("v", strconv.Itoa(v))
 }
 
 func Logger() *logrus.Entry {
 	if logger == nil {
-		NewLogger(true)
+		log = logrus.New()
+		log.SetLevel(logrus.DebugLevel)
+		logger = logrus.NewEntry(log)
 	}
 
 	return logger
@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_NewLogrus(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       LogOptions
+		expectedLevel logrus.Level
+		expectErr     bool
+	}{
+		{
+			name:          "defaults to text and info",
+			options:       LogOptions{},
+			expectedLevel: logrus.InfoLevel,
+		},
+		{
+			name:          "json and debug",
+			options:       LogOptions{Format: LogFormatJSON, Level: "debug"},
+			expectedLevel: logrus.DebugLevel,
+		},
+		{
+			name:      "rejects unknown format",
+			options:   LogOptions{Format: "yaml"},
+			expectErr: true,
+		},
+		{
+			name:      "rejects unknown level",
+			options:   LogOptions{Level: "verbose"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := newLogrus(tc.options)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedLevel, l.GetLevel())
+		})
+	}
+}
+
+func Test_LogSink(t *testing.T) {
+	newLogger := func(level logrus.Level) (logr.Logger, *bytes.Buffer) {
+		buf := &bytes.Buffer{}
+		l, err := newLogrus(LogOptions{Format: LogFormatJSON})
+		require.NoError(t, err)
+		l.SetOutput(buf)
+		l.SetLevel(level)
+
+		return logr.New(newLogSink(logrus.NewEntry(l))), buf
+	}
+
+	decode := func(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+		line := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		return line
+	}
+
+	t.Run("names become the component and keys are renamed", func(t *testing.T) {
+		logger, buf := newLogger(logrus.InfoLevel)
+		logger.WithName("controller-runtime").WithName("controller").
+			WithValues("reconcileID", types.UID("1234"), FieldNamespace, "default").
+			Info("starting workers", "worker count", 10)
+
+		line := decode(t, buf)
+		require.Equal(t, "controller-runtime.controller", line[FieldComponent])
+		require.Equal(t, "1234", line[FieldReconcileID])
+		require.Equal(t, "default", line[FieldNamespace])
+		require.Equal(t, float64(10), line["worker count"])
+		require.Equal(t, "info", line["level"])
+		require.Equal(t, "starting workers", line["msg"])
+	})
+
+	t.Run("errors are logged at error level", func(t *testing.T) {
+		logger, buf := newLogger(logrus.InfoLevel)
+		logger.Error(errors.New("connection refused"), "failed to list gameservers", "cause", errors.New("timeout"))
+
+		line := decode(t, buf)
+		require.Equal(t, "error", line["level"])
+		require.Equal(t, "connection refused", line[logrus.ErrorKey])
+		require.Equal(t, "timeout", line["cause"])
+	})
+
+	t.Run("verbose logs are written at debug and trace levels", func(t *testing.T) {
+		logger, buf := newLogger(logrus.InfoLevel)
+		logger.V(1).Info("not logged")
+		require.Empty(t, buf.String())
+
+		logger, buf = newLogger(logrus.DebugLevel)
+		logger.V(2).Info("logged")
+		require.Equal(t, "debug", decode(t, buf)["level"])
+
+		require.False(t, logger.V(6).Enabled())
+	})
+}
@@ -0,0 +1,95 @@
+package runtime
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// logrKeys renames the keys logged by controller-runtime to the fields used by the controller.
+var logrKeys = map[string]string{
+	"reconcileID": FieldReconcileID,
+}
+
+// logSink writes the logs of controller-runtime and client-go using logrus. The logger name is used as component.
+type logSink struct {
+	entry *logrus.Entry
+	name  string
+}
+
+func newLogSink(entry *logrus.Entry) *logSink {
+	return &logSink{entry: entry}
+}
+
+func (s *logSink) Init(logr.RuntimeInfo) {}
+
+func (s *logSink) Enabled(level int) bool {
+	return s.entry.Logger.IsLevelEnabled(logrusLevel(level))
+}
+
+func (s *logSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.withValues(keysAndValues).Log(logrusLevel(level), msg)
+}
+
+func (s *logSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.withValues(keysAndValues).WithError(err).Error(msg)
+}
+
+func (s *logSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &logSink{entry: s.withValues(keysAndValues), name: s.name}
+}
+
+func (s *logSink) WithName(name string) logr.LogSink {
+	if len(s.name) > 0 {
+		name = s.name + "." + name
+	}
+
+	return &logSink{entry: s.entry.WithField(FieldComponent, name), name: name}
+}
+
+func (s *logSink) withValues(keysAndValues []interface{}) *logrus.Entry {
+	if len(keysAndValues) == 0 {
+		return s.entry
+	}
+
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if renamed, ok := logrKeys[key]; ok {
+			key = renamed
+		}
+
+		var value interface{}
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+		fields[key] = logValue(value)
+	}
+
+	return s.entry.WithFields(fields)
+}
+
+// logValue converts errors and object references to strings, so they are encoded the same way by every formatter.
+func logValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// logrusLevel maps the verbosity of logr to logrus. V(0) is info, V(1) to V(4) are debug and anything above is trace.
+func logrusLevel(level int) logrus.Level {
+	switch {
+	case level <= 0:
+		return logrus.InfoLevel
+	case level < 5:
+		return logrus.DebugLevel
+	default:
+		return logrus.TraceLevel
+	}
+}
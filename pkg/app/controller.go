@@ -8,15 +8,35 @@ import (
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/claims"
 	"github.com/Octops/gameserver-ingress-controller/pkg/controller"
+	"github.com/Octops/gameserver-ingress-controller/pkg/debug"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
 	"github.com/Octops/gameserver-ingress-controller/pkg/handlers"
+	"github.com/Octops/gameserver-ingress-controller/pkg/health"
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
 	"github.com/Octops/gameserver-ingress-controller/pkg/manager"
+	"github.com/Octops/gameserver-ingress-controller/pkg/namespaces"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
+	"github.com/Octops/gameserver-ingress-controller/pkg/policy"
+	"github.com/Octops/gameserver-ingress-controller/pkg/reconcilers"
 	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/sharding"
 	"github.com/Octops/gameserver-ingress-controller/pkg/stores"
+	"github.com/Octops/gameserver-ingress-controller/pkg/sweeper"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 )
 
 type Config struct {
@@ -27,30 +47,122 @@ type Config struct {
 	HealthProbeBindAddress  string
 	MetricsBindAddress      string
 	MaxConcurrentReconciles int
+	// RetryBaseDelay and RetryMaxDelay bound the exponential backoff applied to failed reconciles.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// QueueStallTimeout is the time the work queue can have pending requests without completing a reconcile before
+	// the liveness check fails.
+	QueueStallTimeout time.Duration
+	// LeaderElect enables leader election so multiple replicas can run for availability.
+	LeaderElect             bool
+	LeaderElectionID        string
+	LeaderElectionNamespace string
+	LeaseDuration           time.Duration
+	RenewDeadline           time.Duration
+	RetryPeriod             time.Duration
+	// Sharding splits GameServers across replicas. It can't be combined with LeaderElect.
+	Sharding           bool
+	ShardIdentity      string
+	ShardNamespace     string
+	ShardGroup         string
+	ShardLeaseDuration time.Duration
+	ShardRenewInterval time.Duration
+	// Namespaces and NamespaceSelector restrict the controller to a set of namespaces. Both can be combined.
+	Namespaces        []string
+	NamespaceSelector string
+	// GameServerSelector and ControllerClass choose the GameServers managed by this instance, so multiple instances
+	// can run in the same cluster.
+	GameServerSelector string
+	ControllerClass    string
+	// NamespaceDefaults lets the octops.io annotations of a Namespace act as defaults for its GameServers.
+	NamespaceDefaults bool
+	// DefaultAnnotations are the defaults used when neither the GameServer nor its Namespace set an annotation.
+	DefaultAnnotations map[string]string
+	// DomainPolicy is the path of the policy file mapping domains to the namespaces allowed to use them.
+	DomainPolicy string
+	// DomainPolicyWebhook serves a validating webhook rejecting Fleets and GameServers that violate the policy.
+	DomainPolicyWebhook bool
 	// EnableGatewayAPI controls the Gateway API backend.
 	// "auto" (default): enable if CRDs are present, warn and disable if not.
 	// "true": always enable, fail hard at startup if CRDs are missing.
 	// "false": always disable, no informer or client created.
 	EnableGatewayAPI string
+	// PlaceholderAddress enables the placeholder backend when set.
+	PlaceholderAddress string
+	// PlaceholderService is the namespace/name of the Service exposing the placeholder backend.
+	PlaceholderService     string
+	PlaceholderServicePort int32
+	PlaceholderFormat      string
+	PlaceholderRetryAfter  time.Duration
+	// PlaceholderPod is the namespace/name of the Pod of the replica, labelled while it serves the placeholder backend.
+	PlaceholderPod string
+	// ClusterDomain is the DNS domain of the cluster used to resolve the placeholder Service.
+	ClusterDomain string
+	// OrphanSweeper is the orphan sweeper mode: off, report or delete.
+	OrphanSweeper         string
+	OrphanSweeperInterval time.Duration
+	// TracingEndpoint is the OTLP gRPC collector spans are exported to. Tracing is disabled if it is empty, unless the
+	// OTEL_EXPORTER_OTLP_ENDPOINT environment variable is set.
+	TracingEndpoint    string
+	TracingInsecure    bool
+	TracingSampleRatio float64
+	// DebugAddress enables the debug endpoint serving the routing state of the GameServers when set.
+	DebugAddress string
+	// DryRun sends every write with server-side dry-run and logs it with a diff, so the instance can shadow a live one.
+	DryRun bool
 }
 
+// DryRunSuffix is appended to the leader election Lease and the shard group in dry-run mode, so a shadow instance
+// never takes over the GameServers of the live one.
+const DryRunSuffix = "-dry-run"
+
 func StartController(ctx context.Context, logger *logrus.Entry, config Config) error {
 	duration, err := time.ParseDuration(config.SyncPeriod)
 	if err != nil {
 		withFatal(logger, err, fmt.Sprintf("error parsing sync-period flag: %s", config.SyncPeriod))
 	}
 
-	mgr, err := manager.NewManager(config.Kubeconfig, manager.Options{
-		SyncPeriod:              &duration,
-		Port:                    config.Port,
-		HealthProbeBindAddress:  config.HealthProbeBindAddress,
-		MetricsBindAddress:      config.MetricsBindAddress,
-		MaxConcurrentReconciles: config.MaxConcurrentReconciles,
+	if config.Sharding && config.LeaderElect {
+		withFatal(logger, errors.New("--sharding and --leader-elect are mutually exclusive"), "invalid configuration")
+	}
+
+	// The placeholder is only served by the leader, with sharding every replica would serve its own shard
+	if config.Sharding && len(config.PlaceholderAddress) > 0 {
+		withFatal(logger, errors.New("--sharding and --placeholder-addrs are mutually exclusive"), "invalid configuration")
+	}
+
+	selector, err := labels.Parse(config.NamespaceSelector)
+	if err != nil {
+		withFatal(logger, err, fmt.Sprintf("error parsing namespace-selector flag: %s", config.NamespaceSelector))
+	}
+	scopeOptions := namespaces.Options{Namespaces: config.Namespaces, Selector: selector}
+
+	gsSelector, err := labels.Parse(config.GameServerSelector)
+	if err != nil {
+		withFatal(logger, err, fmt.Sprintf("error parsing gameserver-selector flag: %s", config.GameServerSelector))
+	}
+
+	if err := gameserver.ValidateDefaults(config.DefaultAnnotations); err != nil {
+		withFatal(logger, err, "error parsing default-annotations flag")
+	}
+
+	if config.DryRun {
+		config.LeaderElectionID += DryRunSuffix
+		config.ShardGroup += DryRunSuffix
+		logger.WithField("component", "controller").Warn("dry-run mode enabled, no Service, Ingress, HTTPRoute, GameServer or Event is written")
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Endpoint:    config.TracingEndpoint,
+		Insecure:    config.TracingInsecure,
+		SampleRatio: config.TracingSampleRatio,
 	})
 	if err != nil {
-		withFatal(logger, err, "failed to create controller manager")
+		withFatal(logger, err, "failed to setup tracing")
 	}
+	defer flushTracing(logger, shutdownTracing)
 
+	// The Gateway API setting is resolved first, the manager cache can't be created for kinds that are not installed
 	clusterConfig, err := k8sutil.NewClusterConfig(config.Kubeconfig)
 	if err != nil {
 		withFatal(logger, err, "failed to create cluster config")
@@ -66,24 +178,180 @@ func StartController(ctx context.Context, logger *logrus.Entry, config Config) e
 		withFatal(logger, err, "failed to resolve --enable-gateway-api")
 	}
 
-	store, err := stores.NewStore(ctx, client, clusterConfig, gatewayEnabled)
+	mgr, err := manager.NewManager(config.Kubeconfig, manager.Options{
+		SyncPeriod:              &duration,
+		Port:                    config.Port,
+		HealthProbeBindAddress:  config.HealthProbeBindAddress,
+		MetricsBindAddress:      config.MetricsBindAddress,
+		MaxConcurrentReconciles: config.MaxConcurrentReconciles,
+		LeaderElection:          config.LeaderElect,
+		LeaderElectionID:        config.LeaderElectionID,
+		LeaderElectionNamespace: config.LeaderElectionNamespace,
+		LeaseDuration:           &config.LeaseDuration,
+		RenewDeadline:           &config.RenewDeadline,
+		RetryPeriod:             &config.RetryPeriod,
+		Namespaces:              config.Namespaces,
+		GameServerSelector:      gsSelector,
+		GatewayEnabled:          gatewayEnabled,
+	})
+	if err != nil {
+		withFatal(logger, err, "failed to create controller manager")
+	}
+
+	// Claims are fed by the route informers of the store, before it syncs
+	index := claims.NewIndex()
+	storeOpts := []stores.StoreOption{stores.WithRouteHandlers(index)}
+	agonesOpts := []stores.AgonesStoreOption{stores.WithGameServerSelector(gsSelector)}
+	var reader *namespaces.Reader
+	if scopeOptions.Enabled() {
+		storeOpts = append(storeOpts, stores.Namespaced())
+		agonesOpts = append(agonesOpts, stores.AgonesNamespaced())
+		reader = namespaces.NewReader()
+	}
+	if len(config.PlaceholderAddress) > 0 {
+		namespace, name, err := placeholderService(config)
+		if err != nil {
+			withFatal(logger, err, "failed to setup placeholder backend")
+		}
+		storeOpts = append(storeOpts, stores.WithPlaceholderServices(), stores.WithPlaceholderReferenceGrant(namespace, name))
+	}
+	if config.DryRun {
+		storeOpts = append(storeOpts, stores.WithDryRun())
+		agonesOpts = append(agonesOpts, stores.AgonesDryRun())
+	}
+
+	store, err := stores.NewStore(ctx, client, clusterConfig, gatewayEnabled, storeOpts...)
 	if err != nil {
 		withFatal(logger, err, "failed to create store")
 	}
 
-	agones, err := stores.NewAgonesStore(ctx, clusterConfig, duration)
+	agones, err := stores.NewAgonesStore(ctx, clusterConfig, duration, agonesOpts...)
+	if err != nil {
+		withFatal(logger, err, "failed to create agones store")
+	}
+
+	watchdog := health.NewQueueWatchdog(config.QueueStallTimeout)
+	if err := setupHealthChecks(mgr, config, client, store, agones, gatewayEnabled, watchdog); err != nil {
+		withFatal(logger, err, "failed to setup health checks")
+	}
+
+	var history *record.History
+	var results *debug.Results
+	var recorderOpts []record.EventRecorderOption
+	if len(config.DebugAddress) > 0 {
+		history = record.NewHistory(record.DefaultHistorySize)
+		results = debug.NewResults()
+		recorderOpts = append(recorderOpts, record.WithHistory(history))
+	}
+	if config.DryRun {
+		recorderOpts = append(recorderOpts, record.WithDryRun())
+	}
+
+	recorder := record.NewEventRecorder(mgr.GetEventRecorderFor("octops-gameserver-controller"), recorderOpts...)
 
-	recorder := mgr.GetEventRecorderFor("octops-gameserver-controller")
-	handler := handlers.NewGameSeverEventHandler(store, agones, record.NewEventRecorder(recorder), gatewayEnabled)
+	var sharder *sharding.Sharder
+	if config.Sharding {
+		sharder, err = setupSharding(mgr, client, config, reader)
+		if err != nil {
+			withFatal(logger, err, "failed to setup sharding")
+		}
+	}
+
+	var namespaceReader ctrlclient.Reader
+	if config.NamespaceDefaults {
+		namespaceReader = mgr.GetClient()
+		if reader != nil {
+			namespaceReader = reader
+		}
+	}
+
+	handlerOpts := []handlers.HandlerOption{
+		handlers.WithControllerClass(config.ControllerClass),
+		handlers.WithDefaults(namespaceReader, config.DefaultAnnotations),
+		handlers.WithClaims(index),
+	}
+
+	var enforcer *policy.Enforcer
+	if len(config.DomainPolicy) > 0 {
+		var policyReader ctrlclient.Reader = mgr.GetClient()
+		if reader != nil {
+			policyReader = reader
+		}
+
+		enforcer, err = setupDomainPolicy(config.DomainPolicy, policyReader)
+		if err != nil {
+			withFatal(logger, err, "failed to setup domain policy")
+		}
+		handlerOpts = append(handlerOpts, handlers.WithHostChecks(enforcer.CheckHost))
+	}
+	if len(config.PlaceholderAddress) > 0 {
+		opt, err := setupPlaceholder(ctx, mgr, client, config)
+		if err != nil {
+			withFatal(logger, err, "failed to setup placeholder backend")
+		}
+		handlerOpts = append(handlerOpts, opt)
+	}
+
+	handler := handlers.NewGameSeverEventHandler(store, agones, recorder, gatewayEnabled, handlerOpts...)
+
+	if err := setupOrphanSweeper(mgr, config, store, agones, recorder, gatewayEnabled, sharder); err != nil {
+		withFatal(logger, err, "failed to setup orphan sweeper")
+	}
+
+	if config.DomainPolicyWebhook {
+		if enforcer == nil {
+			withFatal(logger, errors.New("--domain-policy-webhook requires --domain-policy"), "invalid configuration")
+		}
+
+		validator := policy.NewValidator(enforcer, mgr.GetScheme(), handler.ResolveDefaults)
+		mgr.GetWebhookServer().Register(policy.WebhookPath, &webhook.Admission{Handler: validator})
+	}
+
+	if len(config.DebugAddress) > 0 {
+		if err := setupDebug(mgr, config, store, agones, handler, gatewayEnabled, results, history); err != nil {
+			withFatal(logger, err, "failed to setup debug endpoint")
+		}
+	}
+
+	owns := []ctrlclient.Object{&corev1.Service{}, &networkingv1.Ingress{}}
+	if gatewayEnabled {
+		owns = append(owns, &gatewayv1.HTTPRoute{})
+	}
 
 	ctrl, err := controller.NewGameServerController(ctx, mgr, handler, controller.Options{
-		For: &agonesv1.GameServer{},
+		For:                     &agonesv1.GameServer{},
+		Owns:                    owns,
+		MaxConcurrentReconciles: config.MaxConcurrentReconciles,
+		RetryBaseDelay:          config.RetryBaseDelay,
+		RetryMaxDelay:           config.RetryMaxDelay,
+		Sharder:                 sharder,
+		Claims:                  index,
+		Namespaces:              reader,
+		SyncPeriod:              &duration,
+		GameServerSelector:      gsSelector,
+		GatewayEnabled:          gatewayEnabled,
+		Predicates:              []predicate.Predicate{controller.ControllerClassPredicate(config.ControllerClass)},
+		NamespaceDefaults:       config.NamespaceDefaults,
+		Watchdog:                watchdog,
+		Results:                 observer(results),
 	})
 
 	if err != nil {
 		withFatal(logger, err, "failed to create controller")
 	}
 
+	if scopeOptions.Enabled() {
+		// Informers of the stores must be synced before the controller reconciles a namespace
+		scope := namespaces.NewScope(client, scopeOptions)
+		scope.AddHandler(store)
+		scope.AddHandler(index)
+		scope.AddHandler(agones)
+		scope.AddHandler(ctrl)
+		if err := mgr.Add(scope); err != nil {
+			withFatal(logger, err, "failed to add namespace scope to manager")
+		}
+	}
+
 	logger.WithField("component", "controller").Info("starting gameserver controller")
 	if err := ctrl.Start(ctx); err != nil {
 		withFatal(logger, err, "failed to start controller")
@@ -92,6 +360,185 @@ func StartController(ctx context.Context, logger *logrus.Entry, config Config) e
 	return nil
 }
 
+// flushTracing exports the spans still buffered on shutdown.
+func flushTracing(logger *logrus.Entry, shutdown func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if err := shutdown(ctx); err != nil {
+		logger.WithError(err).Warn("failed to flush traces")
+	}
+}
+
+// setupHealthChecks registers named readiness checks for the caches of the stores, the Gateway API CRDs and the
+// webhook server, and a liveness check detecting a stalled work queue.
+func setupHealthChecks(mgr *manager.Manager, config Config, client kubernetes.Interface, store *stores.Store, agones *stores.AgonesStore, gatewayEnabled bool, watchdog *health.QueueWatchdog) error {
+	checks := map[string]healthz.Checker{
+		"agones-cache": health.Synced("Agones", agones.HasSynced),
+		"k8s-cache":    health.Synced("K8S", store.HasSynced),
+	}
+
+	if config.EnableGatewayAPI != "false" {
+		checks["gateway-api"] = health.GatewayAPI(gatewayEnabled, func() error { return checkGatewayAPICRDs(client) }, health.DefaultProbeInterval)
+	}
+
+	if config.DomainPolicyWebhook {
+		checks["webhook"] = mgr.GetWebhookServer().StartedChecker()
+	}
+
+	for name, check := range checks {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			return errors.Wrapf(err, "failed to add readiness check %s", name)
+		}
+	}
+
+	return errors.Wrap(mgr.AddHealthzCheck("workqueue", watchdog.Check), "failed to add liveness check workqueue")
+}
+
+// setupPlaceholder registers the placeholder server with the manager and returns the handler option
+// that points routes of non-routable GameServers to it.
+func setupPlaceholder(ctx context.Context, mgr *manager.Manager, client kubernetes.Interface, config Config) (handlers.HandlerOption, error) {
+	namespace, name, err := placeholderService(config)
+	if err != nil {
+		return nil, err
+	}
+
+	podNamespace, podName, err := cache.SplitMetaNamespaceKey(config.PlaceholderPod)
+	if err != nil || len(podNamespace) == 0 || len(podName) == 0 {
+		return nil, errors.Errorf("placeholder pod %q must be in the format namespace/name", config.PlaceholderPod)
+	}
+
+	// Only the leader keeps the registry up to date, the placeholder Service selects the Pod it labels
+	activator := placeholder.NewActivator(client, podNamespace, podName, config.DryRun)
+	if err := activator.Deactivate(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := mgr.Add(activator); err != nil {
+		return nil, errors.Wrap(err, "failed to add placeholder activator to manager")
+	}
+
+	registry := placeholder.NewRegistry(0)
+	server, err := placeholder.NewServer(registry, placeholder.Options{
+		Addr:       config.PlaceholderAddress,
+		Format:     placeholder.Format(config.PlaceholderFormat),
+		RetryAfter: config.PlaceholderRetryAfter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mgr.Add(server); err != nil {
+		return nil, errors.Wrap(err, "failed to add placeholder server to manager")
+	}
+
+	return handlers.WithPlaceholder(registry, placeholder.Backend{
+		Name:          name,
+		Namespace:     namespace,
+		Port:          config.PlaceholderServicePort,
+		ClusterDomain: config.ClusterDomain,
+	}), nil
+}
+
+// placeholderService returns the namespace and the name of the Service exposing the placeholder backend.
+func placeholderService(config Config) (string, string, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(config.PlaceholderService)
+	if err != nil || len(namespace) == 0 || len(name) == 0 {
+		return "", "", errors.Errorf("placeholder service %q must be in the format namespace/name", config.PlaceholderService)
+	}
+
+	return namespace, name, nil
+}
+
+// setupDebug registers the debug server with the manager.
+func setupDebug(mgr *manager.Manager, config Config, store *stores.Store, agones *stores.AgonesStore, handler *handlers.GameSeverEventHandler, gatewayEnabled bool, results *debug.Results, history *record.History) error {
+	var routes debug.HTTPRouteGetter
+	if gatewayEnabled {
+		routes = store
+	}
+
+	server := debug.NewServer(agones, handler.Planner(), store, store, routes, results, history, debug.Options{Addr: config.DebugAddress})
+
+	return errors.Wrap(mgr.Add(server), "failed to add debug server to manager")
+}
+
+// observer avoids passing a typed nil to the controller when the debug endpoint is disabled.
+func observer(results *debug.Results) reconcilers.ResultObserver {
+	if results == nil {
+		return nil
+	}
+
+	return results
+}
+
+// setupDomainPolicy loads the domain policy. Namespaces are read using the reader when rules use namespace selectors.
+func setupDomainPolicy(path string, reader ctrlclient.Reader) (*policy.Enforcer, error) {
+	p, err := policy.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.NewEnforcer(p, reader)
+}
+
+// setupOrphanSweeper registers the orphan sweeper with the manager unless the mode is off.
+func setupOrphanSweeper(mgr *manager.Manager, config Config, store *stores.Store, agones *stores.AgonesStore, recorder *record.EventRecorder, gatewayEnabled bool, sharder *sharding.Sharder) error {
+	mode := sweeper.Mode(config.OrphanSweeper)
+	switch mode {
+	case "", sweeper.ModeOff:
+		return nil
+	case sweeper.ModeReport, sweeper.ModeDelete:
+	default:
+		return errors.Errorf("invalid orphan sweeper mode %q, must be one of off, report or delete", config.OrphanSweeper)
+	}
+
+	var routes sweeper.HTTPRouteStore
+	if gatewayEnabled {
+		routes = store
+	}
+
+	options := sweeper.Options{
+		Mode:     mode,
+		Interval: config.OrphanSweeperInterval,
+		MinAge:   time.Minute,
+	}
+	if sharder != nil {
+		options.Owns = sharder.Owns
+	}
+	if len(config.PlaceholderAddress) > 0 {
+		options.Placeholders = store
+	}
+
+	s := sweeper.NewSweeper(store, store, routes, agones, recorder, options)
+
+	return errors.Wrap(mgr.Add(s), "failed to add orphan sweeper to manager")
+}
+
+// setupSharding registers the shard membership with the manager.
+func setupSharding(mgr *manager.Manager, client kubernetes.Interface, config Config, namespaced *namespaces.Reader) (*sharding.Sharder, error) {
+	var reader ctrlclient.Reader = mgr.GetClient()
+	if namespaced != nil {
+		reader = namespaced
+	}
+
+	sharder, err := sharding.NewSharder(client, reader, sharding.Options{
+		Identity:      config.ShardIdentity,
+		Namespace:     config.ShardNamespace,
+		Group:         config.ShardGroup,
+		LeaseDuration: config.ShardLeaseDuration,
+		RenewInterval: config.ShardRenewInterval,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mgr.Add(sharder); err != nil {
+		return nil, errors.Wrap(err, "failed to add sharder to manager")
+	}
+
+	return sharder, nil
+}
+
 // resolveGatewayAPIEnabled determines whether the Gateway API backend should be
 // enabled based on the --enable-gateway-api flag value:
 //
@@ -0,0 +1,355 @@
+package claims
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/pkg/errors"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Claim is a host and path pair published by a GameServer, either by an existing Ingress or HTTPRoute or reserved
+// before the route is created.
+type Claim struct {
+	Owner types.NamespacedName
+	// Source is the kind and name of the route, it is empty for reservations.
+	Source string
+	Since  time.Time
+}
+
+// ConflictError is returned when a host and path pair is already claimed by an older GameServer.
+type ConflictError struct {
+	Target gameserver.RouteTarget
+	Owner  types.NamespacedName
+	// Reported is true if the same conflict was already returned for the GameServer, so it is only reported once.
+	Reported bool
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("host %s and path %s are already claimed by gameserver %s", e.Target.Host, e.Target.Path, e.Owner)
+}
+
+// IsConflict checks if the error, or any error it wraps, is a claim conflict.
+func IsConflict(err error) bool {
+	var c *ConflictError
+	return errors.As(err, &c)
+}
+
+// Index keeps the host and path pairs claimed by every managed Ingress and HTTPRoute. Two GameServers with the same
+// name in different namespaces publish the same routes, the ingress controller then silently picks one of them.
+// Conflicts are resolved deterministically: the oldest claim wins, ties are broken by namespace/name.
+//
+// GameServers refused because of a conflict wait for the target. They are sent to Events once the claims that beat
+// them are released, instead of being retried until then.
+type Index struct {
+	mu      sync.Mutex
+	claims  map[gameserver.RouteTarget][]Claim
+	waiting map[types.NamespacedName]gameserver.RouteTarget
+	events  chan event.GenericEvent
+	now     func() time.Time
+}
+
+func NewIndex() *Index {
+	return &Index{
+		claims:  map[gameserver.RouteTarget][]Claim{},
+		waiting: map[types.NamespacedName]gameserver.RouteTarget{},
+		events:  make(chan event.GenericEvent, 1024),
+		now:     time.Now,
+	}
+}
+
+// Events enqueues GameServers whose conflicting claim was released. It is meant to be used as a channel source by
+// the controller.
+func (i *Index) Events() <-chan event.GenericEvent {
+	return i.events
+}
+
+// Claim reserves the targets for the GameServer. It returns a ConflictError for the first target claimed by an older
+// GameServer, in which case nothing is reserved.
+func (i *Index) Claim(gs *agonesv1.GameServer, targets []gameserver.RouteTarget) error {
+	owner := types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	for _, t := range targets {
+		t = normalize(t)
+		if winner, ok := i.winner(t, owner, now); ok && winner.Owner != owner {
+			waiting, reported := i.waiting[owner]
+			i.waiting[owner] = t
+			return &ConflictError{Target: t, Owner: winner.Owner, Reported: reported && waiting == t}
+		}
+	}
+
+	delete(i.waiting, owner)
+
+	// Reservations of targets the GameServer no longer publishes are dropped
+	i.wake(i.remove(func(t gameserver.RouteTarget, c Claim) bool { return c.Owner == owner && len(c.Source) == 0 }))
+	for _, t := range targets {
+		t = normalize(t)
+		if !i.has(t, owner) {
+			i.claims[t] = append(i.claims[t], Claim{Owner: owner, Since: now})
+		}
+	}
+
+	i.observe()
+	return nil
+}
+
+// Release drops every claim of a GameServer that was deleted or is no longer managed.
+func (i *Index) Release(owner types.NamespacedName) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	delete(i.waiting, owner)
+	i.wake(i.remove(func(_ gameserver.RouteTarget, c Claim) bool { return c.Owner == owner }))
+	i.observe()
+}
+
+// Conflicts returns the number of host and path pairs claimed by more than one GameServer.
+func (i *Index) Conflicts() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.conflicts()
+}
+
+// OnAdd, OnUpdate and OnDelete keep the index up to date with the Ingresses and HTTPRoutes in the informer caches.
+func (i *Index) OnAdd(obj interface{}, _ bool) {
+	i.set(obj)
+}
+
+func (i *Index) OnUpdate(_, newObj interface{}) {
+	i.set(newObj)
+}
+
+func (i *Index) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	source, _, _, ok := routeClaims(obj)
+	if !ok {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.wake(i.remove(func(_ gameserver.RouteTarget, c Claim) bool { return c.Source == source }))
+	i.observe()
+}
+
+// AddNamespace does nothing, claims are added by the informers of the namespace.
+func (i *Index) AddNamespace(_ context.Context, _ string) error {
+	return nil
+}
+
+// RemoveNamespace drops the claims of a namespace that is no longer watched.
+func (i *Index) RemoveNamespace(namespace string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for owner := range i.waiting {
+		if owner.Namespace == namespace {
+			delete(i.waiting, owner)
+		}
+	}
+
+	i.wake(i.remove(func(_ gameserver.RouteTarget, c Claim) bool { return c.Owner.Namespace == namespace }))
+	i.observe()
+}
+
+func (i *Index) set(obj interface{}) {
+	source, claim, targets, ok := routeClaims(obj)
+	if !ok {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	released := i.remove(func(_ gameserver.RouteTarget, c Claim) bool { return c.Source == source })
+	for _, t := range targets {
+		t = normalize(t)
+		// A route replaces the reservation made before it was created, keeping the oldest timestamp
+		for j, c := range i.claims[t] {
+			if c.Owner == claim.Owner && len(c.Source) == 0 {
+				if c.Since.Before(claim.Since) {
+					claim.Since = c.Since
+				}
+				i.claims[t] = append(i.claims[t][:j], i.claims[t][j+1:]...)
+				break
+			}
+		}
+		i.claims[t] = append(i.claims[t], claim)
+	}
+
+	i.wake(released)
+	i.observe()
+}
+
+// winner returns the oldest claim of the target. The owner is considered as claiming it at now if it does not yet.
+func (i *Index) winner(t gameserver.RouteTarget, owner types.NamespacedName, now time.Time) (Claim, bool) {
+	candidates := append([]Claim(nil), i.claims[t]...)
+	if !i.has(t, owner) {
+		candidates = append(candidates, Claim{Owner: owner, Since: now})
+	}
+
+	if len(candidates) == 0 {
+		return Claim{}, false
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if !candidates[a].Since.Equal(candidates[b].Since) {
+			return candidates[a].Since.Before(candidates[b].Since)
+		}
+		return candidates[a].Owner.String() < candidates[b].Owner.String()
+	})
+
+	return candidates[0], true
+}
+
+func (i *Index) has(t gameserver.RouteTarget, owner types.NamespacedName) bool {
+	for _, c := range i.claims[t] {
+		if c.Owner == owner {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remove drops the matching claims and returns the targets that lost at least one claim.
+func (i *Index) remove(match func(t gameserver.RouteTarget, c Claim) bool) map[gameserver.RouteTarget]bool {
+	released := map[gameserver.RouteTarget]bool{}
+	for t, claims := range i.claims {
+		kept := claims[:0]
+		for _, c := range claims {
+			if !match(t, c) {
+				kept = append(kept, c)
+				continue
+			}
+			released[t] = true
+		}
+
+		if len(kept) == 0 {
+			delete(i.claims, t)
+			continue
+		}
+		i.claims[t] = kept
+	}
+
+	return released
+}
+
+// wake sends the GameServers waiting for one of the released targets that they would now win. The event is dropped
+// if the channel is full, the GameServer is then published by the next resync.
+func (i *Index) wake(released map[gameserver.RouteTarget]bool) {
+	if len(released) == 0 {
+		return
+	}
+
+	now := i.now()
+	for owner, t := range i.waiting {
+		if !released[t] {
+			continue
+		}
+
+		if winner, ok := i.winner(t, owner, now); !ok || winner.Owner != owner {
+			continue
+		}
+
+		delete(i.waiting, owner)
+		select {
+		case i.events <- event.GenericEvent{Object: &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{Name: owner.Name, Namespace: owner.Namespace}}}:
+		default:
+		}
+	}
+}
+
+func (i *Index) conflicts() int {
+	var count int
+	for _, claims := range i.claims {
+		owners := map[types.NamespacedName]bool{}
+		for _, c := range claims {
+			owners[c.Owner] = true
+		}
+
+		if len(owners) > 1 {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (i *Index) observe() {
+	metrics.HostnameConflicts.Set(float64(i.conflicts()))
+}
+
+// routeClaims returns the targets of an Ingress or HTTPRoute created for a GameServer.
+func routeClaims(obj interface{}) (string, Claim, []gameserver.RouteTarget, bool) {
+	var meta metav1.Object
+	var kind string
+	var targets []gameserver.RouteTarget
+
+	switch route := obj.(type) {
+	case *networkingv1.Ingress:
+		meta, kind = route, record.IngressKind
+		for _, rule := range route.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+
+			for _, p := range rule.HTTP.Paths {
+				targets = append(targets, gameserver.RouteTarget{Host: rule.Host, Path: p.Path})
+			}
+		}
+	case *gatewayv1.HTTPRoute:
+		meta, kind = route, record.HTTPRouteKind
+		for _, hostname := range route.Spec.Hostnames {
+			for _, rule := range route.Spec.Rules {
+				for _, match := range rule.Matches {
+					if match.Path != nil && match.Path.Value != nil {
+						targets = append(targets, gameserver.RouteTarget{Host: string(hostname), Path: *match.Path.Value})
+					}
+				}
+			}
+		}
+	default:
+		return "", Claim{}, nil, false
+	}
+
+	name, ok := meta.GetLabels()[gameserver.AgonesGameServerNameLabel]
+	if !ok || len(name) == 0 {
+		return "", Claim{}, nil, false
+	}
+
+	source := fmt.Sprintf("%s/%s/%s", kind, meta.GetNamespace(), meta.GetName())
+	claim := Claim{
+		Owner:  types.NamespacedName{Namespace: meta.GetNamespace(), Name: name},
+		Source: source,
+		Since:  meta.GetCreationTimestamp().Time,
+	}
+
+	return source, claim, targets, true
+}
+
+func normalize(t gameserver.RouteTarget) gameserver.RouteTarget {
+	return gameserver.RouteTarget{Host: strings.ToLower(strings.TrimSpace(t.Host)), Path: t.Path}
+}
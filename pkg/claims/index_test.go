@@ -0,0 +1,218 @@
+package claims
+
+import (
+	"testing"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+var base = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func Test_Index_Claim(t *testing.T) {
+	target := gameserver.RouteTarget{Host: "servers.example.com", Path: "/game-1"}
+
+	testCases := []struct {
+		name     string
+		setup    func(i *Index)
+		gs       *agonesv1.GameServer
+		conflict *types.NamespacedName
+	}{
+		{
+			name: "claims a free target",
+			gs:   newGameServer("game-1", "team-a"),
+		},
+		{
+			name: "claims again a target it already owns",
+			setup: func(i *Index) {
+				require.NoError(t, i.Claim(newGameServer("game-1", "team-a"), []gameserver.RouteTarget{target}))
+			},
+			gs: newGameServer("game-1", "team-a"),
+		},
+		{
+			name: "refuses a target reserved by another gameserver",
+			setup: func(i *Index) {
+				require.NoError(t, i.Claim(newGameServer("game-1", "team-a"), []gameserver.RouteTarget{target}))
+			},
+			gs:       newGameServer("game-1", "team-b"),
+			conflict: &types.NamespacedName{Namespace: "team-a", Name: "game-1"},
+		},
+		{
+			name: "refuses a target published by an existing route",
+			setup: func(i *Index) {
+				i.OnAdd(newIngress("game-1", "team-a", base, target), true)
+			},
+			gs:       newGameServer("game-1", "team-b"),
+			conflict: &types.NamespacedName{Namespace: "team-a", Name: "game-1"},
+		},
+		{
+			name: "matches hosts regardless of case",
+			setup: func(i *Index) {
+				i.OnAdd(newIngress("game-1", "team-a", base, gameserver.RouteTarget{Host: "Servers.Example.com", Path: "/game-1"}), true)
+			},
+			gs:       newGameServer("game-1", "team-b"),
+			conflict: &types.NamespacedName{Namespace: "team-a", Name: "game-1"},
+		},
+		{
+			name: "the older route wins over a newer route",
+			setup: func(i *Index) {
+				i.OnAdd(newIngress("game-1", "team-b", base, target), true)
+				i.OnAdd(newHTTPRoute("game-1", "team-a", base.Add(time.Minute), target), true)
+			},
+			gs:       newGameServer("game-1", "team-a"),
+			conflict: &types.NamespacedName{Namespace: "team-b", Name: "game-1"},
+		},
+		{
+			name: "ties are broken by namespace and name",
+			setup: func(i *Index) {
+				i.OnAdd(newIngress("game-1", "team-b", base, target), true)
+				i.OnAdd(newIngress("game-1", "team-a", base, target), true)
+			},
+			gs: newGameServer("game-1", "team-a"),
+		},
+		{
+			name: "claims a target released by a deleted gameserver",
+			setup: func(i *Index) {
+				require.NoError(t, i.Claim(newGameServer("game-1", "team-a"), []gameserver.RouteTarget{target}))
+				i.Release(types.NamespacedName{Namespace: "team-a", Name: "game-1"})
+			},
+			gs: newGameServer("game-1", "team-b"),
+		},
+		{
+			name: "claims a target of a deleted route",
+			setup: func(i *Index) {
+				ingress := newIngress("game-1", "team-a", base, target)
+				i.OnAdd(ingress, true)
+				i.OnDelete(cache.DeletedFinalStateUnknown{Key: "team-a/game-1", Obj: ingress})
+			},
+			gs: newGameServer("game-1", "team-b"),
+		},
+		{
+			name: "claims a target of a namespace no longer watched",
+			setup: func(i *Index) {
+				i.OnAdd(newIngress("game-1", "team-a", base, target), true)
+				i.RemoveNamespace("team-a")
+			},
+			gs: newGameServer("game-1", "team-b"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			index := NewIndex()
+			index.now = func() time.Time { return base.Add(time.Hour) }
+			if tc.setup != nil {
+				tc.setup(index)
+			}
+
+			err := index.Claim(tc.gs, []gameserver.RouteTarget{target})
+			if tc.conflict == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.True(t, IsConflict(err))
+			require.Equal(t, *tc.conflict, err.(*ConflictError).Owner)
+		})
+	}
+}
+
+func Test_Index_Conflicts(t *testing.T) {
+	target := gameserver.RouteTarget{Host: "game-1.example.com", Path: "/"}
+
+	index := NewIndex()
+	index.OnAdd(newIngress("game-1", "team-a", base, target), true)
+	require.Equal(t, 0, index.Conflicts())
+
+	// A newer route for the same host is still counted until it is removed
+	route := newHTTPRoute("game-1", "team-b", base.Add(time.Minute), target)
+	index.OnAdd(route, true)
+	require.Equal(t, 1, index.Conflicts())
+
+	// The reservation is replaced by the route of the same gameserver
+	require.NoError(t, index.Claim(newGameServer("game-1", "team-a"), []gameserver.RouteTarget{target}))
+	index.OnUpdate(nil, newIngress("game-1", "team-a", base, target))
+	require.Equal(t, 1, index.Conflicts())
+
+	index.OnDelete(route)
+	require.Equal(t, 0, index.Conflicts())
+}
+
+func Test_Index_Waiting(t *testing.T) {
+	target := gameserver.RouteTarget{Host: "servers.example.com", Path: "/game-1"}
+	winner := types.NamespacedName{Namespace: "team-a", Name: "game-1"}
+
+	index := NewIndex()
+	index.OnAdd(newIngress("game-1", "team-a", base, target), true)
+
+	err := index.Claim(newGameServer("game-1", "team-b"), []gameserver.RouteTarget{target})
+	require.True(t, IsConflict(err))
+	require.False(t, err.(*ConflictError).Reported)
+
+	err = index.Claim(newGameServer("game-1", "team-b"), []gameserver.RouteTarget{target})
+	require.True(t, IsConflict(err))
+	require.True(t, err.(*ConflictError).Reported, "the same conflict is only reported once")
+
+	// Updates of the winning route do not wake the refused gameserver
+	index.OnUpdate(nil, newIngress("game-1", "team-a", base, target))
+	require.Empty(t, index.Events())
+
+	index.Release(winner)
+	require.Len(t, index.Events(), 1)
+	e := <-index.Events()
+	require.Equal(t, "team-b/game-1", e.Object.GetNamespace()+"/"+e.Object.GetName())
+
+	require.NoError(t, index.Claim(newGameServer("game-1", "team-b"), []gameserver.RouteTarget{target}))
+	require.Empty(t, index.Events())
+}
+
+func newGameServer(name, namespace string) *agonesv1.GameServer {
+	return &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+}
+
+func routeMeta(name, namespace string, created time.Time) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:              name,
+		Namespace:         namespace,
+		Labels:            map[string]string{gameserver.AgonesGameServerNameLabel: name},
+		CreationTimestamp: metav1.NewTime(created),
+	}
+}
+
+func newIngress(name, namespace string, created time.Time, target gameserver.RouteTarget) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: routeMeta(name, namespace, created),
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: target.Host,
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{{Path: target.Path}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newHTTPRoute(name, namespace string, created time.Time, target gameserver.RouteTarget) *gatewayv1.HTTPRoute {
+	path := target.Path
+	return &gatewayv1.HTTPRoute{
+		ObjectMeta: routeMeta(name, namespace, created),
+		Spec: gatewayv1.HTTPRouteSpec{
+			Hostnames: []gatewayv1.Hostname{gatewayv1.Hostname(target.Host)},
+			Rules: []gatewayv1.HTTPRouteRule{
+				{Matches: []gatewayv1.HTTPRouteMatch{{Path: &gatewayv1.HTTPPathMatch{Value: &path}}}},
+			},
+		},
+	}
+}
@@ -3,36 +3,89 @@ package controller
 import (
 	"context"
 	"reflect"
+	"sync"
+	"time"
 
-	"sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/controller-runtime/pkg/log/zap"
-
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/claims"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
 	"github.com/Octops/gameserver-ingress-controller/pkg/handlers"
+	"github.com/Octops/gameserver-ingress-controller/pkg/health"
+	octopsmanager "github.com/Octops/gameserver-ingress-controller/pkg/manager"
+	"github.com/Octops/gameserver-ingress-controller/pkg/namespaces"
 	"github.com/Octops/gameserver-ingress-controller/pkg/reconcilers"
+	"github.com/Octops/gameserver-ingress-controller/pkg/sharding"
 )
 
 type Options struct {
-	For  client.Object
-	Owns client.Object
+	For client.Object
+	// Owns lists the kinds created for each GameServer. Events on them enqueue the owning GameServer so manual
+	// deletions and edits are repaired without waiting for the next resync.
+	Owns []client.Object
+	// MaxConcurrentReconciles is the number of workers processing the queue. Requests for the same key are never
+	// processed concurrently.
+	MaxConcurrentReconciles int
+	// RetryBaseDelay and RetryMaxDelay bound the exponential backoff applied to failed requests.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// Sharder restricts the controller to the GameServers of its shard. Sharding is disabled if nil.
+	Sharder *sharding.Sharder
+	// Claims enqueues GameServers refused because of a hostname conflict once the conflicting claim is released.
+	Claims *claims.Index
+	// Namespaces restricts the controller to the namespaces added with AddNamespace. The manager cache is
+	// not used and each namespace gets its own cache registered with the reader. The controller is cluster wide if nil.
+	Namespaces *namespaces.Reader
+	SyncPeriod *time.Duration
+	// GameServerSelector restricts the GameServers cached by the per namespace caches.
+	GameServerSelector labels.Selector
+	// GatewayEnabled caches HTTPRoutes in the per namespace caches.
+	GatewayEnabled bool
+	// Predicates filter the GameServer events before they are enqueued.
+	Predicates []predicate.Predicate
+	// NamespaceDefaults watches Namespaces and enqueues their GameServers when the octops.io annotations
+	// inherited by GameServers change.
+	NamespaceDefaults bool
+	// Watchdog is notified of every processed request and observes the work queue to detect stalls.
+	Watchdog *health.QueueWatchdog
+	// Results is notified of the outcome of every reconcile, e.g. to serve the last error of each GameServer.
+	Results reconcilers.ResultObserver
 }
 
 // GameServerController watches for events associated to a particular resource type like GameServers or Fleets.
-// It uses the passed EventHandler argument to send back the current state of the world.
+// Events are turned into requests keyed by namespace/name and processed by the passed EventHandler from a rate limited
+// work queue.
 type GameServerController struct {
 	logger *logrus.Entry
 	manager.Manager
+	controller   ctrlcontroller.Controller
+	reader       client.Reader
+	eventHandler handlers.EventHandler
+	options      Options
+	owns         func(key types.NamespacedName) bool
+	mu           sync.Mutex
+	cancels      map[string]context.CancelFunc
 }
 
 func NewGameServerController(ctx context.Context, mgr manager.Manager, eventHandler handlers.EventHandler, options Options) (*GameServerController, error) {
@@ -42,98 +95,312 @@ func NewGameServerController(ctx context.Context, mgr manager.Manager, eventHand
 		"resource":  optFor,
 	})
 
-	err := ctrl.NewControllerManagedBy(mgr).
-		For(options.For).
-		WithEventFilter(predicate.Funcs{
-			CreateFunc: func(event event.CreateEvent) bool {
-				// Implement some logic here and if returns true if you think that
-				// this event should be sent to the reconciler or false otherwise
-				return true
-			},
-			DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
-				return true
-			},
-			UpdateFunc: func(updateEvent event.UpdateEvent) bool {
-				return true
-			},
-			GenericFunc: func(genericEvent event.GenericEvent) bool {
-				return true
-			},
-		}).
-		Watches(options.For, &handler.Funcs{
-			CreateFunc: func(ctx context.Context, createEvent event.CreateEvent, limitingInterface workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-				// OnAdd is triggered only when the controller is syncing its cache.
-				// It does not map ot the resource creation event triggered by Kubernetes
-				request := reconcile.Request{
-					NamespacedName: types.NamespacedName{
-						Namespace: createEvent.Object.GetNamespace(),
-						Name:      createEvent.Object.GetName(),
-					},
-				}
-
-				//TODO: Investigate if controller require this Done. Keeping doubles the reconcile calls
-				//defer limitingInterface.Done(request)
-
-				if err := eventHandler.OnAdd(ctx, createEvent.Object); err != nil {
-					limitingInterface.AddRateLimited(request)
-					return
-				}
-
-				limitingInterface.Forget(request)
-				limitingInterface.Done(request)
-			},
-			UpdateFunc: func(ctx context.Context, updateEvent event.UpdateEvent, limitingInterface workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-				request := reconcile.Request{
-					NamespacedName: types.NamespacedName{
-						Namespace: updateEvent.ObjectNew.GetNamespace(),
-						Name:      updateEvent.ObjectNew.GetName(),
-					},
-				}
-
-				//TODO: Investigate if controller require this Done. Keeping doubles the reconcile calls
-				//defer limitingInterface.Done(request)
-
-				if err := eventHandler.OnUpdate(ctx, updateEvent.ObjectOld, updateEvent.ObjectNew); err != nil {
-					limitingInterface.AddRateLimited(request)
-					return
-				}
-
-				limitingInterface.Forget(request)
-				limitingInterface.Done(request)
-			},
-			DeleteFunc: func(ctx context.Context, deleteEvent event.DeleteEvent, limitingInterface workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-
-				request := reconcile.Request{
-					NamespacedName: types.NamespacedName{
-						Namespace: deleteEvent.Object.GetNamespace(),
-						Name:      deleteEvent.Object.GetName(),
-					},
-				}
-
-				if err := eventHandler.OnDelete(ctx, deleteEvent.Object); err != nil {
-					limitingInterface.AddRateLimited(request)
-					return
-				}
-
-				limitingInterface.Forget(request)
-				limitingInterface.Done(request)
-			},
-		}).Complete(&reconcilers.Reconciler{
-		Obj:    options.For,
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	})
+	c := &GameServerController{
+		logger:       logger,
+		Manager:      mgr,
+		eventHandler: eventHandler,
+		options:      options,
+		owns:         func(types.NamespacedName) bool { return true },
+		cancels:      map[string]context.CancelFunc{},
+	}
+
+	var reader client.Reader = mgr.GetClient()
+	if options.Namespaces != nil {
+		reader = options.Namespaces
+	}
+	c.reader = reader
+
+	var reconcilerOpts []reconcilers.ReconcilerOption
+	ctrlOptions := ctrlcontroller.Options{
+		MaxConcurrentReconciles: options.MaxConcurrentReconciles,
+		RateLimiter:             NewRateLimiter(options.RetryBaseDelay, options.RetryMaxDelay),
+	}
+
+	if options.Sharder != nil {
+		c.owns = options.Sharder.Owns
+		reconcilerOpts = append(reconcilerOpts, reconcilers.WithOwnership(c.owns))
+	}
+
+	if options.Watchdog != nil {
+		reconcilerOpts = append(reconcilerOpts, reconcilers.WithProgress(options.Watchdog.Processed))
+	}
+
+	if options.Results != nil {
+		reconcilerOpts = append(reconcilerOpts, reconcilers.WithResults(options.Results))
+	}
+
+	if options.Sharder != nil || options.Watchdog != nil {
+		ctrlOptions.NewQueue = func(name string, rateLimiter workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+			queue := workqueue.NewTypedRateLimitingQueueWithConfig(rateLimiter, workqueue.TypedRateLimitingQueueConfig[reconcile.Request]{
+				Name: name,
+			})
+			if options.Sharder != nil {
+				options.Sharder.ObserveQueue(queue)
+			}
+			if options.Watchdog != nil {
+				options.Watchdog.ObserveQueue(queue)
+			}
+			return queue
+		}
+	}
+
+	ctrlOptions.Reconciler = reconcilers.NewReconciler(reader, eventHandler, reconcilerOpts...)
+
+	gsController, err := ctrlcontroller.New("gameserver", mgr, ctrlOptions)
 	if err != nil {
 		return nil, err
 	}
+	c.controller = gsController
+
+	if options.Namespaces == nil {
+		if err := c.Watch(mgr.GetCache()); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Sharder != nil {
+		// GameServers gained on a rebalance are enqueued without waiting for an event or resync
+		if err := gsController.Watch(source.Channel(options.Sharder.Events(), &handler.EnqueueRequestForObject{})); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Claims != nil {
+		if err := gsController.Watch(source.Channel(options.Claims.Events(), &handler.EnqueueRequestForObject{})); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// sourceFunc returns the source of events of a kind, e.g. from the manager cache or from the cache of a namespace.
+type sourceFunc func(obj client.Object, h handler.EventHandler, predicates ...predicate.Predicate) (source.Source, error)
+
+// Watch enqueues GameServers and the kinds they own from the manager cache.
+func (c *GameServerController) Watch(cache ctrlcache.Cache) error {
+	return c.watch(func(obj client.Object, h handler.EventHandler, predicates ...predicate.Predicate) (source.Source, error) {
+		return source.Kind(cache, obj, h, predicates...), nil
+	})
+}
+
+// watchNamespace enqueues GameServers and the kinds they own from the synced cache of a namespace. The handlers are
+// registered on the informers of the cache, they are removed along with the namespace.
+func (c *GameServerController) watchNamespace(ctx context.Context, cache ctrlcache.Cache, informers *namespaceInformers) error {
+	return c.watch(func(obj client.Object, h handler.EventHandler, predicates ...predicate.Predicate) (source.Source, error) {
+		informer, err := cache.GetInformer(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if !toolscache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+			return nil, errors.Errorf("timed out waiting for %T informer to sync", obj)
+		}
+
+		return &source.Informer{Informer: informers.track(informer), Handler: h, Predicates: predicates}, nil
+	})
+}
+
+func (c *GameServerController) watch(kind sourceFunc) error {
+	src, err := kind(c.options.For, &handler.EnqueueRequestForObject{}, c.options.Predicates...)
+	if err != nil {
+		return errors.Wrap(err, "failed to watch gameservers")
+	}
+	if err := c.controller.Watch(src); err != nil {
+		return errors.Wrap(err, "failed to watch gameservers")
+	}
+
+	// Deleted objects are no longer available when the request is processed. This watch only notifies
+	// the handler and does not enqueue anything.
+	src, err = kind(c.options.For, handler.EventHandler(&handler.Funcs{
+		DeleteFunc: func(ctx context.Context, deleteEvent event.DeleteEvent, _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if !c.owns(client.ObjectKeyFromObject(deleteEvent.Object)) {
+				return
+			}
+
+			if err := c.eventHandler.OnDelete(ctx, deleteEvent.Object); err != nil {
+				c.logger.WithError(err).Error("failed to handle delete event")
+			}
+		},
+	}), c.options.Predicates...)
+	if err == nil {
+		err = c.controller.Watch(src)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to watch gameserver deletions")
+	}
+
+	for _, owned := range c.options.Owns {
+		src, err := kind(owned, handler.EnqueueRequestsFromMapFunc(OwnerRequests))
+		if err == nil {
+			err = c.controller.Watch(src)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to watch %T", owned)
+		}
+	}
+
+	if c.options.NamespaceDefaults {
+		src, err := kind(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(c.namespaceRequests), NamespaceDefaultsPredicate())
+		if err == nil {
+			err = c.controller.Watch(src)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to watch namespaces")
+		}
+	}
+
+	return nil
+}
+
+// namespaceRequests enqueues every GameServer of the Namespace.
+func (c *GameServerController) namespaceRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	list := &agonesv1.GameServerList{}
+	if err := c.reader.List(ctx, list, client.InNamespace(obj.GetName())); err != nil {
+		c.logger.WithError(err).Errorf("failed to list gameservers of namespace %s", obj.GetName())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, gs := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}})
+	}
+
+	return requests
+}
+
+// NamespaceDefaultsPredicate only lets through updates of Namespaces that change the annotations inherited by
+// GameServers. GameServers are reconciled anyway when the controller starts or a Namespace is added.
+func NamespaceDefaultsPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			previous := gameserver.DefaultableAnnotations(e.ObjectOld.GetAnnotations())
+			current := gameserver.DefaultableAnnotations(e.ObjectNew.GetAnnotations())
+			return !reflect.DeepEqual(previous, current)
+		},
+	}
+}
+
+// AddNamespace starts a cache restricted to the namespace and watches it.
+func (c *GameServerController) AddNamespace(ctx context.Context, namespace string) error {
+	if c.options.Namespaces == nil {
+		return errors.New("controller is not restricted to namespaces")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.cancels[namespace]; ok {
+		return nil
+	}
+
+	byObject := octopsmanager.CacheByObject(c.options.GameServerSelector, c.options.GatewayEnabled)
+	// Namespaces are cluster scoped, the cache only keeps the one it is restricted to
+	byObject[&corev1.Namespace{}] = ctrlcache.ByObject{Field: fields.OneTermEqualSelector("metadata.name", namespace)}
+
+	cache, err := ctrlcache.New(c.GetConfig(), ctrlcache.Options{
+		HTTPClient:        c.GetHTTPClient(),
+		Scheme:            c.GetScheme(),
+		Mapper:            c.GetRESTMapper(),
+		SyncPeriod:        c.options.SyncPeriod,
+		DefaultNamespaces: map[string]ctrlcache.Config{namespace: {}},
+		DefaultTransform:  ctrlcache.TransformStripManagedFields(),
+		ByObject:          byObject,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create cache for namespace %s", namespace)
+	}
+
+	nsCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		if err := cache.Start(nsCtx); err != nil {
+			c.logger.WithError(err).Errorf("cache for namespace %s stopped", namespace)
+		}
+	}()
+
+	// The handlers are removed with the namespace, so the controller doesn't keep the stopped cache alive
+	informers := &namespaceInformers{}
+	stop := func() {
+		informers.remove()
+		cancel()
+	}
+
+	// The handlers are only added to the informers once they have synced
+	if err := c.watchNamespace(nsCtx, cache, informers); err != nil {
+		stop()
+		return errors.Wrapf(err, "failed to watch namespace %s", namespace)
+	}
+
+	if !cache.WaitForCacheSync(nsCtx) {
+		stop()
+		return errors.Errorf("timed out waiting for cache of namespace %s to sync", namespace)
+	}
+
+	c.options.Namespaces.Set(namespace, cache)
+	c.cancels[namespace] = stop
+
+	return nil
+}
+
+// RemoveNamespace removes the handlers of the namespace and stops its cache. Requests still queued for it are dropped by the reconciler.
+func (c *GameServerController) RemoveNamespace(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.cancels[namespace]; ok {
+		c.options.Namespaces.Delete(namespace)
+		cancel()
+		delete(c.cancels, namespace)
+	}
+}
+
+// OwnerRequests maps an object created by the controller back to the GameServer it belongs to. The owner reference
+// is preferred and the agones.dev/gameserver label is used for objects that lost it.
+func OwnerRequests(_ context.Context, obj client.Object) []reconcile.Request {
+	for _, ref := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+
+		if ref.Kind == "GameServer" && gv.Group == agonesv1.SchemeGroupVersion.Group {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: ref.Name}}}
+		}
+	}
+
+	if name, ok := obj.GetLabels()[gameserver.AgonesGameServerNameLabel]; ok && len(name) > 0 {
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+	}
+
+	return nil
+}
+
+// ControllerClassPredicate filters out events of GameServers managed by a controller of another class.
+func ControllerClassPredicate(class string) predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		gs, ok := obj.(*agonesv1.GameServer)
+		return ok && gameserver.MatchesControllerClass(gs, class)
+	})
+}
+
+// NewRateLimiter returns the controller-runtime default rate limiter using the given per item exponential backoff.
+// Zero values fall back to the controller-runtime defaults.
+func NewRateLimiter(baseDelay, maxDelay time.Duration) workqueue.TypedRateLimiter[reconcile.Request] {
+	if baseDelay <= 0 {
+		baseDelay = time.Millisecond * 5
+	}
 
-	log.SetLogger(zap.New())
-	controller := &GameServerController{
-		logger:  logger,
-		Manager: mgr,
+	if maxDelay <= 0 {
+		maxDelay = time.Second * 1000
 	}
 
-	return controller, nil
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](baseDelay, maxDelay),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
 }
 
 func (c *GameServerController) Start(ctx context.Context) error {
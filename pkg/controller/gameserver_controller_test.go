@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/reconcilers"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type flakyHandler struct {
+	failures int
+	calls    int
+}
+
+func (h *flakyHandler) Reconcile(_ context.Context, _ *logrus.Entry, _ *agonesv1.GameServer) error {
+	h.calls++
+	if h.calls <= h.failures {
+		return errors.New("transient error")
+	}
+
+	return nil
+}
+
+// Test_Reconciler_Retries replays the work queue contract used by controller-runtime: failed requests are
+// requeued using the rate limiter and forgotten once they succeed.
+func Test_Reconciler_Retries(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+	require.NoError(t, agonesv1.AddToScheme(scheme))
+
+	gs := &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{Name: "game-1", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).Build()
+
+	handler := &flakyHandler{failures: 3}
+	r := reconcilers.NewReconciler(c, handler)
+	limiter := NewRateLimiter(time.Millisecond*10, time.Millisecond*25)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "game-1"}}
+
+	var delays []time.Duration
+	for {
+		_, err := r.Reconcile(context.Background(), req)
+		if err == nil {
+			limiter.Forget(req)
+			break
+		}
+
+		delays = append(delays, limiter.When(req))
+	}
+
+	require.Equal(t, 4, handler.calls)
+	require.Equal(t, []time.Duration{time.Millisecond * 10, time.Millisecond * 20, time.Millisecond * 25}, delays)
+	require.Equal(t, 0, limiter.NumRequeues(req))
+}
+
+func Test_NewRateLimiter_Defaults(t *testing.T) {
+	limiter := NewRateLimiter(0, 0)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "game-1"}}
+
+	require.Equal(t, time.Millisecond*5, limiter.When(req))
+	require.Equal(t, time.Millisecond*10, limiter.When(req))
+}
+
+func Test_OwnerRequests(t *testing.T) {
+	owner := &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{Name: "game-1", Namespace: "default", UID: "gs-uid"}}
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "default", Name: "game-1"}}}
+
+	testCases := []struct {
+		name     string
+		meta     metav1.ObjectMeta
+		expected []reconcile.Request
+	}{
+		{
+			name: "maps owner reference",
+			meta: metav1.ObjectMeta{
+				Name:            "game-1",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(owner, agonesv1.SchemeGroupVersion.WithKind("GameServer"))},
+			},
+			expected: expected,
+		},
+		{
+			name: "maps label when owner reference is missing",
+			meta: metav1.ObjectMeta{
+				Name:      "renamed",
+				Namespace: "default",
+				Labels:    map[string]string{gameserver.AgonesGameServerNameLabel: "game-1"},
+			},
+			expected: expected,
+		},
+		{
+			name: "ignores other owners",
+			meta: metav1.ObjectMeta{
+				Name:      "game-1",
+				Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       "game-1",
+				}},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, OwnerRequests(context.Background(), &corev1.Service{ObjectMeta: tc.meta}))
+		})
+	}
+}
+
+func Test_ControllerClassPredicate(t *testing.T) {
+	public := &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{
+		Name:        "game-1",
+		Annotations: map[string]string{gameserver.OctopsAnnotationControllerClass: "public"},
+	}}
+	unclassified := &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{Name: "game-2"}}
+
+	p := ControllerClassPredicate("public")
+	require.True(t, p.Create(event.CreateEvent{Object: public}))
+	require.False(t, p.Create(event.CreateEvent{Object: unclassified}))
+	require.True(t, p.Update(event.UpdateEvent{ObjectOld: unclassified, ObjectNew: public}))
+	require.False(t, p.Delete(event.DeleteEvent{Object: unclassified}))
+	require.False(t, p.Generic(event.GenericEvent{Object: &corev1.Service{}}))
+}
+
+func Test_NamespaceDefaultsPredicate(t *testing.T) {
+	namespace := func(annotations map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "games", Annotations: annotations}}
+	}
+
+	p := NamespaceDefaultsPredicate()
+	require.False(t, p.Create(event.CreateEvent{Object: namespace(nil)}))
+	require.True(t, p.Update(event.UpdateEvent{
+		ObjectOld: namespace(nil),
+		ObjectNew: namespace(map[string]string{gameserver.OctopsAnnotationIngressDomain: "example.com"}),
+	}))
+	require.False(t, p.Update(event.UpdateEvent{
+		ObjectOld: namespace(map[string]string{gameserver.OctopsAnnotationIngressDomain: "example.com"}),
+		ObjectNew: namespace(map[string]string{gameserver.OctopsAnnotationIngressDomain: "example.com", "example.com/team": "blue"}),
+	}))
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"sync"
+
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrlcache "sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+// namespaceInformers keeps the handlers the controller registered on the informers of a namespace cache, so they
+// can be removed along with the namespace.
+type namespaceInformers struct {
+	mu            sync.Mutex
+	removed       bool
+	registrations []registration
+}
+
+type registration struct {
+	informer ctrlcache.Informer
+	handle   toolscache.ResourceEventHandlerRegistration
+}
+
+// track returns the informer recording the handlers added to it.
+func (n *namespaceInformers) track(informer ctrlcache.Informer) ctrlcache.Informer {
+	return &trackedInformer{Informer: informer, namespace: n}
+}
+
+// remove removes every handler. Handlers added afterwards, e.g. by a controller starting late, are not registered.
+func (n *namespaceInformers) remove() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.removed = true
+	for _, r := range n.registrations {
+		_ = r.informer.RemoveEventHandler(r.handle)
+	}
+	n.registrations = nil
+}
+
+type trackedInformer struct {
+	ctrlcache.Informer
+	namespace *namespaceInformers
+}
+
+func (i *trackedInformer) AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	return i.AddEventHandlerWithOptions(handler, toolscache.HandlerOptions{})
+}
+
+func (i *trackedInformer) AddEventHandlerWithOptions(handler toolscache.ResourceEventHandler, options toolscache.HandlerOptions) (toolscache.ResourceEventHandlerRegistration, error) {
+	i.namespace.mu.Lock()
+	defer i.namespace.mu.Unlock()
+
+	if i.namespace.removed {
+		return nil, nil
+	}
+
+	handle, err := i.Informer.AddEventHandlerWithOptions(handler, options)
+	if err != nil {
+		return nil, err
+	}
+
+	i.namespace.registrations = append(i.namespace.registrations, registration{informer: i.Informer, handle: handle})
+	return handle, nil
+}
@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+)
+
+type countingInformer struct {
+	controllertest.FakeInformer
+	added   int
+	removed int
+}
+
+func (i *countingInformer) AddEventHandlerWithOptions(handler toolscache.ResourceEventHandler, options toolscache.HandlerOptions) (toolscache.ResourceEventHandlerRegistration, error) {
+	i.added++
+	return i.FakeInformer.AddEventHandlerWithOptions(handler, options)
+}
+
+func (i *countingInformer) RemoveEventHandler(toolscache.ResourceEventHandlerRegistration) error {
+	i.removed++
+	return nil
+}
+
+func Test_NamespaceInformers(t *testing.T) {
+	gameservers := &countingInformer{}
+	services := &countingInformer{}
+	informers := &namespaceInformers{}
+
+	_, err := informers.track(gameservers).AddEventHandler(toolscache.ResourceEventHandlerFuncs{})
+	require.NoError(t, err)
+	_, err = informers.track(gameservers).AddEventHandlerWithOptions(toolscache.ResourceEventHandlerFuncs{}, toolscache.HandlerOptions{})
+	require.NoError(t, err)
+	_, err = informers.track(services).AddEventHandler(toolscache.ResourceEventHandlerFuncs{})
+	require.NoError(t, err)
+
+	informers.remove()
+	require.Equal(t, 2, gameservers.removed)
+	require.Equal(t, 1, services.removed)
+
+	// Handlers added once the namespace is removed are never registered
+	_, err = informers.track(services).AddEventHandler(toolscache.ResourceEventHandlerFuncs{})
+	require.NoError(t, err)
+	require.Equal(t, 1, services.added)
+}
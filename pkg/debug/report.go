@@ -0,0 +1,205 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Report is the routing state of a GameServer.
+type Report struct {
+	Namespace string                   `json:"namespace"`
+	Name      string                   `json:"name"`
+	UID       types.UID                `json:"uid"`
+	State     agonesv1.GameServerState `json:"state"`
+	// Managed is false if the controller skips the GameServer, Reason explains why.
+	Managed       bool           `json:"managed"`
+	Reason        string         `json:"reason,omitempty"`
+	Config        *Config        `json:"config,omitempty"`
+	Readiness     Readiness      `json:"readiness"`
+	LastReconcile *Result        `json:"lastReconcile,omitempty"`
+	Resources     []Resource     `json:"resources,omitempty"`
+	Events        []record.Event `json:"events,omitempty"`
+	// Errors are the reasons the desired resources can't be built or the cache can't be read.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Config is the Octops configuration of a GameServer, after the Namespace and controller defaults are applied.
+type Config struct {
+	Mode        string            `json:"mode"`
+	Backends    []string          `json:"backends"`
+	Routes      []Route           `json:"routes,omitempty"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type Route struct {
+	Host string `json:"host"`
+	Path string `json:"path"`
+}
+
+// Readiness is published by the controller on the GameServer annotations.
+type Readiness struct {
+	IngressReady bool              `json:"ingressReady"`
+	Backends     map[string]string `json:"backends,omitempty"`
+}
+
+// Resource compares a resource the GameServer must have once it is routable with the one in the cache. Desired is
+// empty for resources the controller deletes, Observed is empty for resources not created yet.
+type Resource struct {
+	Kind     string      `json:"kind"`
+	Desired  interface{} `json:"desired,omitempty"`
+	Observed interface{} `json:"observed,omitempty"`
+	InSync   bool        `json:"inSync"`
+}
+
+func (s *Server) report(ctx context.Context, gs *agonesv1.GameServer) (Report, error) {
+	key := types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}
+	report := Report{
+		Namespace: gs.Namespace,
+		Name:      gs.Name,
+		UID:       gs.UID,
+		State:     gs.Status.State,
+		Readiness: readiness(gs),
+	}
+
+	if result, ok := s.results.Get(key); ok {
+		report.LastReconcile = &result
+	}
+	report.Events = s.history.Events(key)
+
+	plan, err := s.planner.Plan(ctx, gs)
+	if err != nil {
+		return report, err
+	}
+
+	report.Managed = plan.Managed
+	report.Reason = plan.Reason
+	if !plan.Managed {
+		return report, nil
+	}
+
+	report.Config = config(plan.GameServer)
+	for _, err := range plan.Errors {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	service, err := s.services.GetService(gs.Name, gs.Namespace)
+	report.addResource(record.ServiceKind, plan.Service, service, err)
+
+	ingress, err := s.ingresses.GetIngress(gs.Name, gs.Namespace)
+	report.addResource(record.IngressKind, plan.Ingress, ingress, err)
+
+	if s.routes != nil {
+		route, err := s.routes.GetHTTPRoute(gs.Name, gs.Namespace)
+		report.addResource(record.HTTPRouteKind, plan.HTTPRoute, route, err)
+	}
+
+	return report, nil
+}
+
+// addResource adds a resource unless it is neither desired nor observed. Typed nil pointers are treated as missing.
+func (r *Report) addResource(kind string, desired, observed metav1.Object, err error) {
+	if err != nil && !k8serrors.IsNotFound(err) {
+		r.Errors = append(r.Errors, err.Error())
+	}
+
+	desired = orNil(desired)
+	observed = orNil(observed)
+	if err != nil {
+		observed = nil
+	}
+
+	if desired == nil && observed == nil {
+		return
+	}
+
+	resource := Resource{Kind: kind, InSync: inSync(desired, observed)}
+	if desired != nil {
+		resource.Desired = desired
+	}
+	if observed != nil {
+		resource.Observed = withoutManagedFields(observed)
+	}
+
+	r.Resources = append(r.Resources, resource)
+}
+
+// inSync checks that every label, annotation and spec field of the desired resource is set on the observed one.
+// Fields defaulted by the API server are ignored.
+func inSync(desired, observed metav1.Object) bool {
+	if desired == nil || observed == nil {
+		return false
+	}
+
+	if !equality.Semantic.DeepDerivative(desired.GetLabels(), observed.GetLabels()) ||
+		!equality.Semantic.DeepDerivative(desired.GetAnnotations(), observed.GetAnnotations()) {
+		return false
+	}
+
+	return equality.Semantic.DeepDerivative(spec(desired), spec(observed))
+}
+
+// spec returns the spec of a resource using its JSON encoding, so every kind is compared the same way.
+func spec(obj metav1.Object) map[string]interface{} {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil
+	}
+
+	var fields struct {
+		Spec map[string]interface{} `json:"spec"`
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil
+	}
+
+	return fields.Spec
+}
+
+func config(gs *agonesv1.GameServer) *Config {
+	c := &Config{
+		Mode:        string(gameserver.GetIngressRoutingMode(gs)),
+		Annotations: map[string]string{},
+	}
+
+	for _, backend := range gameserver.GetRouterBackends(gs) {
+		c.Backends = append(c.Backends, string(backend))
+	}
+
+	// Invalid routing annotations are reported by the errors of the plan
+	if targets, err := gameserver.GetRouteTargets(gs); err == nil {
+		for _, t := range targets {
+			c.Routes = append(c.Routes, Route{Host: t.Host, Path: t.Path})
+		}
+	}
+
+	for k, v := range gs.Annotations {
+		if strings.HasPrefix(k, gameserver.OctopsAnnotationPrefix) ||
+			strings.HasPrefix(k, gameserver.OctopsAnnotationCustomPrefix) ||
+			strings.HasPrefix(k, gameserver.OctopsAnnotationCustomServicePrefix) {
+			c.Annotations[k] = v
+		}
+	}
+
+	return c
+}
+
+func readiness(gs *agonesv1.GameServer) Readiness {
+	r := Readiness{
+		IngressReady: gs.Annotations[gameserver.OctopsAnnotationGameServerIngressReady] == "true",
+	}
+
+	if status, ok := gs.Annotations[gameserver.OctopsAnnotationRouterBackendStatus]; ok {
+		_ = json.Unmarshal([]byte(status), &r.Backends)
+	}
+
+	return r
+}
@@ -0,0 +1,57 @@
+package debug
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Result is the outcome of the last reconcile of a GameServer.
+type Result struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Results keeps the outcome of the last reconcile of each GameServer. It is fed by the reconciler.
+type Results struct {
+	mu      sync.RWMutex
+	now     func() time.Time
+	results map[types.NamespacedName]Result
+}
+
+func NewResults() *Results {
+	return &Results{
+		now:     time.Now,
+		results: map[types.NamespacedName]Result{},
+	}
+}
+
+// Observe records the outcome of a reconcile, err is nil if the GameServer was reconciled.
+func (r *Results) Observe(key types.NamespacedName, err error) {
+	result := Result{Time: r.now()}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.results[key] = result
+}
+
+// Forget drops the result of a deleted GameServer.
+func (r *Results) Forget(key types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.results, key)
+}
+
+func (r *Results) Get(key types.NamespacedName) (Result, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result, ok := r.results[key]
+	return result, ok
+}
@@ -0,0 +1,219 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/handlers"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Path is where the routing state of the GameServers is served.
+const Path = "/debug/gameservers"
+
+type GameServerLister interface {
+	ListGameServers(namespace string) ([]*agonesv1.GameServer, error)
+}
+
+type Planner interface {
+	Plan(ctx context.Context, gs *agonesv1.GameServer) (*handlers.Plan, error)
+}
+
+type ServiceGetter interface {
+	GetService(name, namespace string) (*corev1.Service, error)
+}
+
+type IngressGetter interface {
+	GetIngress(name, namespace string) (*networkingv1.Ingress, error)
+}
+
+type HTTPRouteGetter interface {
+	GetHTTPRoute(name, namespace string) (*gatewayv1.HTTPRoute, error)
+}
+
+type Options struct {
+	// Addr is the TCP address the debug server binds to.
+	Addr string
+}
+
+type response struct {
+	GameServers []Report `json:"gameservers"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the routing state of the GameServers from the informer caches. It never calls the API server, so it
+// is safe to poll while troubleshooting.
+type Server struct {
+	logger      *logrus.Entry
+	gameservers GameServerLister
+	planner     Planner
+	services    ServiceGetter
+	ingresses   IngressGetter
+	routes      HTTPRouteGetter
+	results     *Results
+	history     *record.History
+	options     Options
+}
+
+// NewServer returns the debug server. routes is nil if the Gateway API backend is disabled.
+func NewServer(gameservers GameServerLister, planner Planner, services ServiceGetter, ingresses IngressGetter,
+	routes HTTPRouteGetter, results *Results, history *record.History, options Options) *Server {
+	if results == nil {
+		results = NewResults()
+	}
+
+	if history == nil {
+		history = record.NewHistory(record.DefaultHistorySize)
+	}
+
+	return &Server{
+		logger:      runtime.Logger().WithField(runtime.FieldComponent, "debug"),
+		gameservers: gameservers,
+		planner:     planner,
+		services:    services,
+		ingresses:   ingresses,
+		routes:      routes,
+		results:     results,
+		history:     history,
+		options:     options,
+	}
+}
+
+// ServeHTTP lists the GameServers managed by the controller. The namespace and name query parameters filter the list,
+// a GameServer requested by name is returned even if it is not managed.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		s.write(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	name := r.URL.Query().Get("name")
+
+	list, err := s.gameservers.ListGameServers(namespace)
+	if err != nil {
+		s.write(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	resp := response{GameServers: []Report{}}
+	for _, gs := range list {
+		if len(name) > 0 && gs.Name != name {
+			continue
+		}
+
+		report, err := s.report(r.Context(), gs)
+		if err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{
+				runtime.FieldGameServer: gs.Name,
+				runtime.FieldNamespace:  gs.Namespace,
+			}).Warn("failed to build the debug report")
+			report.Errors = append(report.Errors, err.Error())
+		}
+
+		if err == nil && !report.Managed && len(name) == 0 {
+			continue
+		}
+
+		resp.GameServers = append(resp.GameServers, report)
+	}
+
+	sort.Slice(resp.GameServers, func(i, j int) bool {
+		a, b := resp.GameServers[i], resp.GameServers[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	s.write(w, http.StatusOK, resp)
+}
+
+func (s *Server) write(w http.ResponseWriter, status int, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(resp)
+}
+
+// Start runs the debug server until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, s)
+
+	srv := &http.Server{
+		Addr:              s.options.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 5,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Infof("debug server listening on %s%s", s.options.Addr, Path)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(err, "debug server failed")
+	}
+
+	return nil
+}
+
+// NeedLeaderElection allows every replica to serve its own caches. Results and events are only known by the replica
+// that reconciles the GameServers.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// orNil turns a typed nil pointer into a nil interface.
+func orNil(obj metav1.Object) metav1.Object {
+	if obj == nil {
+		return nil
+	}
+
+	if v := reflect.ValueOf(obj); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+
+	return obj
+}
+
+// withoutManagedFields returns a copy of the cached object without the managed fields, which are noise when comparing
+// resources. Cached objects must never be modified.
+func withoutManagedFields(obj metav1.Object) metav1.Object {
+	object, ok := obj.(k8sruntime.Object)
+	if !ok {
+		return obj
+	}
+
+	copied, ok := object.DeepCopyObject().(metav1.Object)
+	if !ok {
+		return obj
+	}
+	copied.SetManagedFields(nil)
+
+	return copied
+}
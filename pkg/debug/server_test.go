@@ -0,0 +1,201 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/handlers"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type fakeGameServers []*agonesv1.GameServer
+
+func (f fakeGameServers) ListGameServers(namespace string) ([]*agonesv1.GameServer, error) {
+	var result []*agonesv1.GameServer
+	for _, gs := range f {
+		if len(namespace) == 0 || gs.Namespace == namespace {
+			result = append(result, gs)
+		}
+	}
+
+	return result, nil
+}
+
+// fakePlanner manages GameServers with the ingress mode annotation and desires a Service exposing port 7777.
+type fakePlanner struct{}
+
+func (fakePlanner) Plan(_ context.Context, gs *agonesv1.GameServer) (*handlers.Plan, error) {
+	plan := &handlers.Plan{GameServer: gs}
+	if _, ok := gs.Annotations[gameserver.OctopsAnnotationIngressMode]; !ok {
+		plan.Reason = "annotation not present"
+		return plan, nil
+	}
+
+	plan.Managed = true
+	plan.Service = newService(gs, 7777)
+	plan.Errors = append(plan.Errors, errors.New("ingress is not valid"))
+
+	return plan, nil
+}
+
+type fakeStore struct {
+	services map[string]*corev1.Service
+}
+
+func (f fakeStore) GetService(name, _ string) (*corev1.Service, error) {
+	if svc, ok := f.services[name]; ok {
+		return svc, nil
+	}
+
+	return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "services"}, name)
+}
+
+func (f fakeStore) GetIngress(name, _ string) (*networkingv1.Ingress, error) {
+	return nil, k8serrors.NewNotFound(schema.GroupResource{Resource: "ingresses"}, name)
+}
+
+func Test_ServeHTTP(t *testing.T) {
+	annotations := map[string]string{
+		gameserver.OctopsAnnotationIngressMode:         string(gameserver.IngressRoutingModeDomain),
+		gameserver.OctopsAnnotationIngressDomain:       "example.com",
+		gameserver.OctopsAnnotationRouterBackendStatus: `{"ingress":"ready"}`,
+		"agones.dev/sdk-version":                       "1.56.0",
+	}
+	inSyncGS := newGameServer("default", "game-2", annotations)
+	driftedGS := newGameServer("default", "game-1", annotations)
+	missingGS := newGameServer("other", "game-3", annotations)
+	unmanagedGS := newGameServer("default", "game-4", nil)
+
+	// The API server defaults fields of the Service that are not desired, it is still in sync
+	inSync := newService(inSyncGS, 7777)
+	inSync.Spec.Type = corev1.ServiceTypeClusterIP
+	inSync.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: "octops"}}
+
+	results := NewResults()
+	results.Observe(types.NamespacedName{Namespace: "default", Name: "game-1"}, errors.New("conflict"))
+
+	server := NewServer(
+		fakeGameServers{inSyncGS, driftedGS, missingGS, unmanagedGS},
+		fakePlanner{},
+		fakeStore{services: map[string]*corev1.Service{
+			"game-1": newService(driftedGS, 8888),
+			"game-2": inSync,
+		}},
+		fakeStore{},
+		nil,
+		results,
+		record.NewHistory(0),
+		Options{},
+	)
+
+	get := func(t *testing.T, query string) []Report {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, Path+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var resp response
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.GameServers
+	}
+
+	t.Run("lists managed gameservers sorted by namespace and name", func(t *testing.T) {
+		reports := get(t, "")
+		require.Len(t, reports, 3)
+		require.Equal(t, "game-1", reports[0].Name)
+		require.Equal(t, "game-2", reports[1].Name)
+		require.Equal(t, "game-3", reports[2].Name)
+	})
+
+	t.Run("reports the configuration and readiness", func(t *testing.T) {
+		reports := get(t, "?namespace=default&name=game-1")
+		require.Len(t, reports, 1)
+
+		report := reports[0]
+		require.True(t, report.Managed)
+		require.Equal(t, string(gameserver.IngressRoutingModeDomain), report.Config.Mode)
+		require.Equal(t, []Route{{Host: "game-1.example.com", Path: "/"}}, report.Config.Routes)
+		require.NotContains(t, report.Config.Annotations, "agones.dev/sdk-version")
+		require.Equal(t, map[string]string{"ingress": "ready"}, report.Readiness.Backends)
+		require.Equal(t, "conflict", report.LastReconcile.Error)
+		require.Equal(t, []string{"ingress is not valid"}, report.Errors)
+	})
+
+	t.Run("compares desired and observed resources", func(t *testing.T) {
+		testCases := []struct {
+			name     string
+			observed bool
+			inSync   bool
+		}{
+			{name: "game-1", observed: true, inSync: false},
+			{name: "game-2", observed: true, inSync: true},
+			{name: "game-3", observed: false, inSync: false},
+		}
+
+		for _, tc := range testCases {
+			reports := get(t, "?name="+tc.name)
+			require.Len(t, reports, 1)
+			require.Len(t, reports[0].Resources, 1, tc.name)
+
+			resource := reports[0].Resources[0]
+			require.Equal(t, record.ServiceKind, resource.Kind)
+			require.NotNil(t, resource.Desired, tc.name)
+			require.Equal(t, tc.observed, resource.Observed != nil, tc.name)
+			require.Equal(t, tc.inSync, resource.InSync, tc.name)
+		}
+
+		// The cache must not be modified when managed fields are stripped
+		require.Len(t, inSync.ManagedFields, 1)
+	})
+
+	t.Run("returns unmanaged gameservers requested by name", func(t *testing.T) {
+		reports := get(t, "?name=game-4")
+		require.Len(t, reports, 1)
+		require.False(t, reports[0].Managed)
+		require.Equal(t, "annotation not present", reports[0].Reason)
+		require.Empty(t, reports[0].Resources)
+	})
+
+	t.Run("rejects other methods", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, Path, nil))
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+}
+
+func newGameServer(namespace, name string, annotations map[string]string) *agonesv1.GameServer {
+	return &agonesv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: annotations,
+		},
+		Status: agonesv1.GameServerStatus{State: agonesv1.GameServerStateReady},
+	}
+}
+
+func newService(gs *agonesv1.GameServer, port int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      gs.Name,
+			Namespace: gs.Namespace,
+			Labels:    map[string]string{"agones.dev/gameserver": gs.Name},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: port}},
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package gameserver
+
+import (
+	"strings"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/pkg/errors"
+)
+
+// OctopsAnnotationPrefix is the prefix of the annotations that can be set as defaults on a Namespace or in the
+// controller config.
+const OctopsAnnotationPrefix = "octops.io/"
+
+// IsDefaultable checks if the annotation can be inherited by a GameServer. Annotations written by the controller and
+// the controller class, that decides which controller instance reads the GameServer, are never inherited.
+func IsDefaultable(annotation string) bool {
+	if !strings.HasPrefix(annotation, OctopsAnnotationPrefix) {
+		return false
+	}
+
+	switch annotation {
+	case OctopsAnnotationGameServerIngressReady,
+		OctopsAnnotationRouterBackendStatus,
+		OctopsAnnotationPlaceholderState,
+		OctopsAnnotationHostnameLabel,
+		OctopsAnnotationHostnameOriginal,
+		OctopsAnnotationTimeToRoute,
+		OctopsAnnotationControllerClass:
+		return false
+	}
+
+	return true
+}
+
+// DefaultableAnnotations returns the annotations that can be inherited by a GameServer.
+func DefaultableAnnotations(annotations map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range annotations {
+		if IsDefaultable(k) {
+			result[k] = v
+		}
+	}
+
+	return result
+}
+
+// ValidateDefaults checks that every annotation of the controller config can be inherited by GameServers.
+func ValidateDefaults(defaults map[string]string) error {
+	for k := range defaults {
+		if !IsDefaultable(k) {
+			return errors.Errorf("annotation %s can't be used as a default, it must start with %s and not be set by the controller", k, OctopsAnnotationPrefix)
+		}
+	}
+
+	return nil
+}
+
+// WithDefaults returns a copy of the GameServer with the annotations it does not set resolved from the layers, in
+// order. The layers are usually the annotations of the Namespace followed by the controller config, so every Get*
+// helper resolves a value from the GameServer, then the Namespace, then the controller config.
+// The GameServer is returned as is if no annotation is inherited.
+func WithDefaults(gs *agonesv1.GameServer, layers ...map[string]string) *agonesv1.GameServer {
+	var inherited map[string]string
+	for _, layer := range layers {
+		for k, v := range layer {
+			if !IsDefaultable(k) {
+				continue
+			}
+
+			if _, ok := gs.Annotations[k]; ok {
+				continue
+			}
+
+			if _, ok := inherited[k]; ok {
+				continue
+			}
+
+			if inherited == nil {
+				inherited = map[string]string{}
+			}
+			inherited[k] = v
+		}
+	}
+
+	if len(inherited) == 0 {
+		return gs
+	}
+
+	result := gs.DeepCopy()
+	if result.Annotations == nil {
+		result.Annotations = map[string]string{}
+	}
+	for k, v := range inherited {
+		result.Annotations[k] = v
+	}
+
+	return result
+}
@@ -0,0 +1,81 @@
+package gameserver
+
+import (
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_WithDefaults(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		namespace   map[string]string
+		controller  map[string]string
+		expected    map[string]string
+	}{
+		{
+			name:        "gameserver annotations take precedence",
+			annotations: map[string]string{OctopsAnnotationIngressDomain: "team.example.com"},
+			namespace:   map[string]string{OctopsAnnotationIngressDomain: "namespace.example.com"},
+			controller:  map[string]string{OctopsAnnotationIngressDomain: "example.com"},
+			expected:    map[string]string{OctopsAnnotationIngressDomain: "team.example.com"},
+		},
+		{
+			name:        "namespace annotations take precedence over the controller",
+			annotations: map[string]string{OctopsAnnotationIngressMode: "domain"},
+			namespace:   map[string]string{OctopsAnnotationIngressDomain: "namespace.example.com"},
+			controller:  map[string]string{OctopsAnnotationIngressDomain: "example.com", OctopsAnnotationIssuerName: "letsencrypt"},
+			expected: map[string]string{
+				OctopsAnnotationIngressMode:   "domain",
+				OctopsAnnotationIngressDomain: "namespace.example.com",
+				OctopsAnnotationIssuerName:    "letsencrypt",
+			},
+		},
+		{
+			name:        "annotations set by the controller are not inherited",
+			annotations: nil,
+			namespace: map[string]string{
+				OctopsAnnotationGameServerIngressReady: "true",
+				OctopsAnnotationControllerClass:        "internal",
+				"example.com/team":                     "blue",
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gs := &agonesv1.GameServer{}
+			gs.Annotations = tc.annotations
+
+			result := WithDefaults(gs, tc.namespace, tc.controller)
+			require.Equal(t, tc.expected, result.Annotations)
+			require.Equal(t, tc.annotations, gs.Annotations, "the original gameserver must not be modified")
+
+			if len(tc.expected) == len(tc.annotations) {
+				require.Same(t, gs, result)
+			}
+		})
+	}
+}
+
+func Test_WithDefaults_Helpers(t *testing.T) {
+	gs := &agonesv1.GameServer{}
+	gs.Name = "game-1"
+	gs.Annotations = map[string]string{OctopsAnnotationIngressMode: "domain"}
+
+	result := WithDefaults(gs, map[string]string{OctopsAnnotationIngressDomain: "team.example.com"}, map[string]string{OctopsAnnotationIssuerName: "letsencrypt"})
+
+	targets, err := GetRouteTargets(result)
+	require.NoError(t, err)
+	require.Equal(t, []RouteTarget{{Host: "game-1.team.example.com", Path: "/"}}, targets)
+	require.Equal(t, "letsencrypt", GetTLSCertIssuer(result))
+}
+
+func Test_ValidateDefaults(t *testing.T) {
+	require.NoError(t, ValidateDefaults(map[string]string{OctopsAnnotationTerminateTLS: "true"}))
+	require.Error(t, ValidateDefaults(map[string]string{OctopsAnnotationRouterBackendStatus: "ready"}))
+	require.Error(t, ValidateDefaults(map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"}))
+}
@@ -1,13 +1,19 @@
 package gameserver
 
 import (
+	"strings"
+
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/pkg/errors"
 )
 
 type IngressRoutingMode string
 
 type RouterBackend string
 
+// BackendStatus is the state of the route created for a single router backend.
+type BackendStatus string
+
 const (
 	IngressRoutingModeDomain IngressRoutingMode = "domain"
 	IngressRoutingModePath   IngressRoutingMode = "path"
@@ -15,6 +21,9 @@ const (
 	RouterBackendIngress RouterBackend = "ingress"
 	RouterBackendGateway RouterBackend = "gateway"
 
+	BackendStatusReady  BackendStatus = "ready"
+	BackendStatusFailed BackendStatus = "failed"
+
 	OctopsAnnotationIngressMode            = "octops.io/gameserver-ingress-mode"
 	OctopsAnnotationIngressDomain          = "octops.io/gameserver-ingress-domain"
 	OctopsAnnotationIngressFQDN            = "octops.io/gameserver-ingress-fqdn"
@@ -27,19 +36,35 @@ const (
 	OctopsAnnotationIngressClassName       = "octops.io/ingress-class-name"
 	OctopsAnnotationIngressClassNameLegacy = "octops-kubernetes.io/ingress.class"
 
-	OctopsAnnotationRouterBackend      = "octops.io/router-backend"
-	OctopsAnnotationGatewayName        = "octops.io/gateway-name"
-	OctopsAnnotationGatewayNamespace   = "octops.io/gateway-namespace"
-	OctopsAnnotationGatewaySectionName = "octops.io/gateway-section-name"
+	OctopsAnnotationRouterBackend       = "octops.io/router-backend"
+	OctopsAnnotationRouterBackendStatus = "octops.io/router-backend-status"
+	OctopsAnnotationGatewayName         = "octops.io/gateway-name"
+	OctopsAnnotationGatewayNamespace    = "octops.io/gateway-namespace"
+	OctopsAnnotationGatewaySectionName  = "octops.io/gateway-section-name"
+
+	OctopsAnnotationPlaceholderState = "octops.io/placeholder-state"
 
-	CertManagerAnnotationIssuer = "cert-manager.io/cluster-issuer"
-	AgonesGameServerNameLabel   = "agones.dev/gameserver"
+	OctopsAnnotationControllerClass = "octops.io/controller-class"
+
+	// OctopsAnnotationTimeToRoute is set by the controller with the time it took to publish the GameServer.
+	OctopsAnnotationTimeToRoute = "octops.io/time-to-route"
+
+	CertManagerAnnotationIssuer  = "cert-manager.io/cluster-issuer"
+	AgonesGameServerNameLabel    = "agones.dev/gameserver"
+	AgonesFleetNameLabel         = "agones.dev/fleet"
+	AgonesGameServerSetNameLabel = "agones.dev/gameserverset"
 
 	ErrGameServerAnnotationMissing = "gameserver %s/%s is missing annotation %s"
 	ErrGameServerAnnotationEmpty   = "gameserver %s/%s has annotation %s but it is empty"
 	ErrIngressRoutingModeEmpty     = "ingress routing mode %s requires the annotation %s to be set on gameserver %s/%s"
 )
 
+// RouteTarget is a host and path pair a GameServer is reachable on.
+type RouteTarget struct {
+	Host string
+	Path string
+}
+
 func (m IngressRoutingMode) String() string {
 	return string(m)
 }
@@ -76,6 +101,14 @@ func HasAnnotation(gs *agonesv1.GameServer, annotation string) (string, bool) {
 	return "", false
 }
 
+// MatchesControllerClass checks if the GameServer is managed by a controller of the given class. Like IngressClass,
+// a controller without a class only manages GameServers without the annotation.
+func MatchesControllerClass(gs *agonesv1.GameServer, class string) bool {
+	value, _ := HasAnnotation(gs, OctopsAnnotationControllerClass)
+
+	return strings.TrimSpace(value) == class
+}
+
 func IsShutdown(gs *agonesv1.GameServer) bool {
 	if gs == nil {
 		return false
@@ -127,10 +160,99 @@ func GetIngressClassName(gs *agonesv1.GameServer) string {
 	return ""
 }
 
-func GetRouterBackend(gs *agonesv1.GameServer) RouterBackend {
-	if backend, ok := HasAnnotation(gs, OctopsAnnotationRouterBackend); ok {
-		return RouterBackend(backend)
+// GetRouterBackends returns the router backends requested by the GameServer.
+// The annotation accepts a comma separated list, i.e. "ingress,gateway", to run both backends side by side.
+// Unknown values fall back to the ingress backend.
+func GetRouterBackends(gs *agonesv1.GameServer) []RouterBackend {
+	value, ok := HasAnnotation(gs, OctopsAnnotationRouterBackend)
+	if !ok {
+		return []RouterBackend{RouterBackendIngress}
+	}
+
+	var backends []RouterBackend
+	seen := map[RouterBackend]bool{}
+	for _, b := range strings.Split(value, ",") {
+		backend := RouterBackend(strings.ToLower(strings.TrimSpace(b)))
+		if len(backend) == 0 {
+			continue
+		}
+
+		if backend != RouterBackendGateway {
+			backend = RouterBackendIngress
+		}
+
+		if seen[backend] {
+			continue
+		}
+
+		seen[backend] = true
+		backends = append(backends, backend)
+	}
+
+	if len(backends) == 0 {
+		return []RouterBackend{RouterBackendIngress}
+	}
+
+	return backends
+}
+
+// HasRouterBackend checks if the backend is one of the router backends requested by the GameServer.
+func HasRouterBackend(gs *agonesv1.GameServer, backend RouterBackend) bool {
+	for _, b := range GetRouterBackends(gs) {
+		if b == backend {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RouterBackendsString returns the requested router backends as they are set on the annotation.
+func RouterBackendsString(gs *agonesv1.GameServer) string {
+	backends := GetRouterBackends(gs)
+	values := make([]string, len(backends))
+	for i, b := range backends {
+		values[i] = string(b)
+	}
+
+	return strings.Join(values, ",")
+}
+
+// GetRouteTargets returns the hosts and paths the GameServer is published on for its routing mode.
+func GetRouteTargets(gs *agonesv1.GameServer) ([]RouteTarget, error) {
+	var targets []RouteTarget
+
+	mode := GetIngressRoutingMode(gs)
+	switch mode {
+	case IngressRoutingModePath:
+		fqdns, ok := HasAnnotation(gs, OctopsAnnotationIngressFQDN)
+		if !ok || len(fqdns) == 0 {
+			return nil, errors.Errorf(ErrIngressRoutingModeEmpty, mode, OctopsAnnotationIngressFQDN, gs.Namespace, gs.Name)
+		}
+
+		hosts, err := FQDNs(gs, fqdns)
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range hosts {
+			targets = append(targets, RouteTarget{Host: host, Path: "/" + gs.Name})
+		}
+	case IngressRoutingModeDomain:
+		domains, ok := HasAnnotation(gs, OctopsAnnotationIngressDomain)
+		if !ok || len(domains) == 0 {
+			return nil, errors.Errorf(ErrIngressRoutingModeEmpty, mode, OctopsAnnotationIngressDomain, gs.Namespace, gs.Name)
+		}
+
+		for _, d := range strings.Split(domains, ",") {
+			host, err := Hostname(gs, d)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, RouteTarget{Host: host, Path: "/"})
+		}
+	default:
+		return nil, errors.Errorf("routing mode '%s' from gameserver %s/%s is not recognised", mode, gs.Namespace, gs.Name)
 	}
 
-	return RouterBackendIngress
+	return targets, nil
 }
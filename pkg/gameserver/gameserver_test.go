@@ -0,0 +1,99 @@
+package gameserver
+
+import (
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetRouterBackends(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    []RouterBackend
+	}{
+		{
+			name:        "defaults to ingress",
+			annotations: map[string]string{},
+			expected:    []RouterBackend{RouterBackendIngress},
+		},
+		{
+			name:        "single backend",
+			annotations: map[string]string{OctopsAnnotationRouterBackend: "gateway"},
+			expected:    []RouterBackend{RouterBackendGateway},
+		},
+		{
+			name:        "dual backend",
+			annotations: map[string]string{OctopsAnnotationRouterBackend: "ingress, gateway"},
+			expected:    []RouterBackend{RouterBackendIngress, RouterBackendGateway},
+		},
+		{
+			name:        "duplicated backends",
+			annotations: map[string]string{OctopsAnnotationRouterBackend: "gateway,Gateway,"},
+			expected:    []RouterBackend{RouterBackendGateway},
+		},
+		{
+			name:        "unknown backend falls back to ingress",
+			annotations: map[string]string{OctopsAnnotationRouterBackend: "nginx"},
+			expected:    []RouterBackend{RouterBackendIngress},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gs := &agonesv1.GameServer{}
+			gs.Annotations = tc.annotations
+
+			require.Equal(t, tc.expected, GetRouterBackends(gs))
+		})
+	}
+}
+
+func Test_MatchesControllerClass(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		class       string
+		expected    bool
+	}{
+		{
+			name:        "default controller manages gameservers without class",
+			annotations: map[string]string{},
+			class:       "",
+			expected:    true,
+		},
+		{
+			name:        "default controller ignores gameservers with class",
+			annotations: map[string]string{OctopsAnnotationControllerClass: "internal"},
+			class:       "",
+			expected:    false,
+		},
+		{
+			name:        "controller manages gameservers of its class",
+			annotations: map[string]string{OctopsAnnotationControllerClass: " internal "},
+			class:       "internal",
+			expected:    true,
+		},
+		{
+			name:        "controller ignores gameservers of another class",
+			annotations: map[string]string{OctopsAnnotationControllerClass: "public"},
+			class:       "internal",
+			expected:    false,
+		},
+		{
+			name:        "controller with class ignores gameservers without class",
+			annotations: map[string]string{},
+			class:       "internal",
+			expected:    false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gs := &agonesv1.GameServer{}
+			gs.Annotations = tc.annotations
+			require.Equal(t, tc.expected, MatchesControllerClass(gs, tc.class))
+		})
+	}
+}
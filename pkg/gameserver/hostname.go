@@ -0,0 +1,151 @@
+package gameserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// OctopsAnnotationHostnameShorten shortens GameServer names that don't fit a DNS label to a stable hash based label.
+	OctopsAnnotationHostnameShorten = "octops.io/hostname-shorten"
+	// OctopsAnnotationHostnameLabel and OctopsAnnotationHostnameOriginal are set by the controller on GameServers
+	// published using a shortened label.
+	OctopsAnnotationHostnameLabel    = "octops.io/hostname-label"
+	OctopsAnnotationHostnameOriginal = "octops.io/hostname-original"
+
+	// hashLength is the number of hex characters of the hash appended to shortened labels.
+	hashLength = 10
+)
+
+// HostLabel returns the DNS label of the GameServer used in domain mode, that is the GameServer name. Names longer
+// than 63 characters are rejected, unless octops.io/hostname-shorten=true, in which case they are shortened using
+// ShortenLabel. The second value is true if the label was shortened.
+func HostLabel(gs *agonesv1.GameServer) (string, bool, error) {
+	label := gs.Name
+	shortened := false
+	if len(label) > validation.DNS1123LabelMaxLength && shortenHostname(gs) {
+		label = ShortenLabel(label)
+		shortened = true
+	}
+
+	if errs := validation.IsDNS1123Label(label); len(errs) > 0 {
+		msg := strings.Join(errs, ", ")
+		if len(label) > validation.DNS1123LabelMaxLength {
+			msg = fmt.Sprintf("%s, set %s=true to use a shortened label", msg, OctopsAnnotationHostnameShorten)
+		}
+
+		return "", false, errors.Errorf("gameserver %s/%s can't be used as a hostname label: %s", gs.Namespace, gs.Name, msg)
+	}
+
+	return label, shortened, nil
+}
+
+// Hostname returns the host of the GameServer for a domain used in domain mode, i.e. [gameserver_name].[domain].
+func Hostname(gs *agonesv1.GameServer, domain string) (string, error) {
+	label, _, err := HostLabel(gs)
+	if err != nil {
+		return "", err
+	}
+
+	host := fmt.Sprintf("%s.%s", label, strings.TrimSpace(domain))
+	if err := ValidateHost(host); err != nil {
+		return "", errors.Wrapf(err, "gameserver %s/%s", gs.Namespace, gs.Name)
+	}
+
+	return host, nil
+}
+
+// FQDNs returns the trimmed hosts of a comma separated list of FQDNs used in path mode, validating each of them.
+func FQDNs(gs *agonesv1.GameServer, fqdns string) ([]string, error) {
+	var hosts []string
+	for _, f := range strings.Split(fqdns, ",") {
+		host := strings.TrimSpace(f)
+		if err := ValidateHost(host); err != nil {
+			return nil, errors.Wrapf(err, "gameserver %s/%s", gs.Namespace, gs.Name)
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+// ValidateHost checks that every label of the host and its total length are valid for DNS.
+func ValidateHost(host string) error {
+	if errs := validation.IsDNS1123Subdomain(host); len(errs) > 0 {
+		return errors.Errorf("invalid hostname %q: %s", host, strings.Join(errs, ", "))
+	}
+
+	for _, label := range strings.Split(host, ".") {
+		if len(label) > validation.DNS1123LabelMaxLength {
+			return errors.Errorf("invalid hostname %q: label %q must be no more than %d characters", host, label, validation.DNS1123LabelMaxLength)
+		}
+	}
+
+	return nil
+}
+
+// ShortenLabel truncates the name and appends a hash of the full name, so the label is stable and different names
+// sharing the same prefix don't collide.
+func ShortenLabel(name string) string {
+	if len(name) <= validation.DNS1123LabelMaxLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:hashLength]
+	prefix := strings.TrimRight(name[:validation.DNS1123LabelMaxLength-hashLength-1], "-.")
+
+	return prefix + "-" + hash
+}
+
+// HostnameAnnotations returns the annotations recording the original and the shortened label of a GameServer
+// published using a shortened label, or nil if the label was not shortened.
+func HostnameAnnotations(gs *agonesv1.GameServer) map[string]string {
+	if GetIngressRoutingMode(gs) != IngressRoutingModeDomain {
+		return nil
+	}
+
+	label, shortened, err := HostLabel(gs)
+	if err != nil || !shortened {
+		return nil
+	}
+
+	return map[string]string{
+		OctopsAnnotationHostnameLabel:    label,
+		OctopsAnnotationHostnameOriginal: gs.Name,
+	}
+}
+
+// StaleHostnameAnnotations returns the hostname annotations left on a GameServer that is no longer published using a
+// shortened label, e.g. after the routing mode changed or octops.io/hostname-shorten was removed.
+func StaleHostnameAnnotations(gs *agonesv1.GameServer) []string {
+	if HostnameAnnotations(gs) != nil {
+		return nil
+	}
+
+	var stale []string
+	for _, k := range []string{OctopsAnnotationHostnameLabel, OctopsAnnotationHostnameOriginal} {
+		if _, ok := gs.Annotations[k]; ok {
+			stale = append(stale, k)
+		}
+	}
+
+	return stale
+}
+
+func shortenHostname(gs *agonesv1.GameServer) bool {
+	value, ok := HasAnnotation(gs, OctopsAnnotationHostnameShorten)
+	if !ok {
+		return false
+	}
+
+	shorten, err := strconv.ParseBool(strings.TrimSpace(value))
+	return err == nil && shorten
+}
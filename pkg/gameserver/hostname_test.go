@@ -0,0 +1,136 @@
+package gameserver
+
+import (
+	"strings"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_Hostname(t *testing.T) {
+	long := strings.Repeat("a", 50) + "-fleet-x7k2p-9qzvw"
+
+	testCases := []struct {
+		name        string
+		gsName      string
+		annotations map[string]string
+		domain      string
+		expected    string
+		wantErr     string
+	}{
+		{
+			name:     "uses the gameserver name as label",
+			gsName:   "octops-domain-x7k2p-9qzvw",
+			domain:   " example.com",
+			expected: "octops-domain-x7k2p-9qzvw.example.com",
+		},
+		{
+			name:    "rejects labels longer than 63 characters",
+			gsName:  long,
+			domain:  "example.com",
+			wantErr: OctopsAnnotationHostnameShorten + "=true",
+		},
+		{
+			name:        "shortens labels longer than 63 characters",
+			gsName:      long,
+			annotations: map[string]string{OctopsAnnotationHostnameShorten: "true"},
+			domain:      "example.com",
+			expected:    ShortenLabel(long) + ".example.com",
+		},
+		{
+			name:        "does not shorten valid labels",
+			gsName:      "game-1",
+			annotations: map[string]string{OctopsAnnotationHostnameShorten: "true"},
+			domain:      "example.com",
+			expected:    "game-1.example.com",
+		},
+		{
+			name:    "rejects invalid domains",
+			gsName:  "game-1",
+			domain:  "Example_com",
+			wantErr: "invalid hostname",
+		},
+		{
+			name:    "rejects hostnames longer than 253 characters",
+			gsName:  "game-1",
+			domain:  strings.TrimSuffix(strings.Repeat(strings.Repeat("b", 60)+".", 5), "."),
+			wantErr: "invalid hostname",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gs := &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{Name: tc.gsName, Namespace: "default", Annotations: tc.annotations}}
+
+			host, err := Hostname(gs, tc.domain)
+			if len(tc.wantErr) > 0 {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, host)
+		})
+	}
+}
+
+func Test_ShortenLabel(t *testing.T) {
+	prefix := strings.Repeat("a", 60)
+
+	first := ShortenLabel(prefix + "-x7k2p")
+	second := ShortenLabel(prefix + "-9qzvw")
+
+	require.Len(t, first, 63)
+	require.Equal(t, first, ShortenLabel(prefix+"-x7k2p"), "labels must be stable")
+	require.NotEqual(t, first, second, "names sharing a prefix must not collide")
+	require.Equal(t, "game-1", ShortenLabel("game-1"))
+
+	// The prefix never ends with a dash before the hash
+	label := ShortenLabel(strings.Repeat("a", 51) + "-" + strings.Repeat("b", 20))
+	require.NotContains(t, label, "--")
+}
+
+func Test_HostnameAnnotations(t *testing.T) {
+	long := strings.Repeat("a", 70)
+	gs := &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{
+		Name: long,
+		Annotations: map[string]string{
+			OctopsAnnotationIngressMode:     string(IngressRoutingModeDomain),
+			OctopsAnnotationHostnameShorten: "true",
+		},
+	}}
+
+	require.Equal(t, map[string]string{
+		OctopsAnnotationHostnameLabel:    ShortenLabel(long),
+		OctopsAnnotationHostnameOriginal: long,
+	}, HostnameAnnotations(gs))
+
+	gs.Annotations[OctopsAnnotationIngressMode] = string(IngressRoutingModePath)
+	require.Nil(t, HostnameAnnotations(gs))
+}
+
+func Test_StaleHostnameAnnotations(t *testing.T) {
+	long := strings.Repeat("a", 70)
+	gs := &agonesv1.GameServer{ObjectMeta: metav1.ObjectMeta{
+		Name: long,
+		Annotations: map[string]string{
+			OctopsAnnotationIngressMode:      string(IngressRoutingModeDomain),
+			OctopsAnnotationHostnameShorten:  "true",
+			OctopsAnnotationHostnameLabel:    ShortenLabel(long),
+			OctopsAnnotationHostnameOriginal: long,
+		},
+	}}
+
+	require.Nil(t, StaleHostnameAnnotations(gs))
+
+	delete(gs.Annotations, OctopsAnnotationHostnameShorten)
+	require.Equal(t, []string{OctopsAnnotationHostnameLabel, OctopsAnnotationHostnameOriginal}, StaleHostnameAnnotations(gs))
+
+	gs.Annotations[OctopsAnnotationHostnameShorten] = "true"
+	gs.Annotations[OctopsAnnotationIngressMode] = string(IngressRoutingModePath)
+	delete(gs.Annotations, OctopsAnnotationHostnameLabel)
+	require.Equal(t, []string{OctopsAnnotationHostnameOriginal}, StaleHostnameAnnotations(gs))
+}
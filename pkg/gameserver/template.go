@@ -0,0 +1,42 @@
+package gameserver
+
+import (
+	"strings"
+	"text/template"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/pkg/errors"
+)
+
+// TemplateData is the data the templates of custom annotations are executed with.
+type TemplateData struct {
+	Name string
+	Port int32
+}
+
+func NewTemplateData(gs *agonesv1.GameServer) TemplateData {
+	return TemplateData{
+		Name: gs.Name,
+		Port: GetGameServerPort(gs).Port,
+	}
+}
+
+// IsTemplate checks if the value of a custom annotation is a template.
+func IsTemplate(value string) bool {
+	return strings.Contains(value, "{{") && strings.Contains(value, "}}")
+}
+
+// ExecuteTemplate parses and executes the value of a custom annotation for the GameServer.
+func ExecuteTemplate(gs *agonesv1.GameServer, value string) (string, error) {
+	t, err := template.New("gs").Parse(value)
+	if err != nil {
+		return "", errors.Wrap(err, "template can't be parsed")
+	}
+
+	b := new(strings.Builder)
+	if err := t.Execute(b, NewTemplateData(gs)); err != nil {
+		return "", errors.Wrap(err, "template can't be executed")
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,43 @@
+package gameserver
+
+import (
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// ManagedSelector selects the Services, Ingresses and HTTPRoutes created for GameServers.
+func ManagedSelector() labels.Selector {
+	requirement, err := labels.NewRequirement(AgonesGameServerNameLabel, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return labels.NewSelector().Add(*requirement)
+}
+
+// Transform keeps only the GameServer fields read by the controller before objects are stored in informer caches.
+// The pod template and managed fields are by far the largest part of a GameServer. Objects returned by an informer
+// using this transform are incomplete and must never be used to update the GameServer.
+func Transform(obj interface{}) (interface{}, error) {
+	gs, ok := obj.(*agonesv1.GameServer)
+	if !ok {
+		return obj, nil
+	}
+
+	meta := *gs.ObjectMeta.DeepCopy()
+	meta.ManagedFields = nil
+
+	return &agonesv1.GameServer{
+		TypeMeta:   gs.TypeMeta,
+		ObjectMeta: meta,
+		Spec: agonesv1.GameServerSpec{
+			Ports: gs.Spec.Ports,
+		},
+		Status: agonesv1.GameServerStatus{
+			State:   gs.Status.State,
+			Ports:   gs.Status.Ports,
+			Address: gs.Status.Address,
+		},
+	}, nil
+}
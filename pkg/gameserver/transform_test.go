@@ -0,0 +1,143 @@
+package gameserver
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func Test_Transform(t *testing.T) {
+	gs := newTransformGameServer(0)
+
+	obj, err := Transform(gs)
+	require.NoError(t, err)
+
+	transformed := obj.(*agonesv1.GameServer)
+	require.Equal(t, gs.Name, transformed.Name)
+	require.Equal(t, gs.Annotations, transformed.Annotations)
+	require.Equal(t, gs.Labels, transformed.Labels)
+	require.Equal(t, gs.OwnerReferences, transformed.OwnerReferences)
+	require.Equal(t, gs.Spec.Ports, transformed.Spec.Ports)
+	require.Equal(t, gs.Status.State, transformed.Status.State)
+	require.Equal(t, gs.Status.Ports, transformed.Status.Ports)
+	require.Equal(t, gs.Status.Address, transformed.Status.Address)
+	require.Nil(t, transformed.ManagedFields)
+	require.Empty(t, transformed.Spec.Template.Spec.Containers)
+
+	require.NotEmpty(t, gs.ManagedFields, "the original object must not be modified")
+
+	service := &corev1.Service{}
+	obj, err = Transform(service)
+	require.NoError(t, err)
+	require.Same(t, service, obj)
+}
+
+// BenchmarkInformerCache reports the heap used by an informer cache holding 10k GameServers.
+func BenchmarkInformerCache(b *testing.B) {
+	b.Run("without transform", func(b *testing.B) {
+		benchmarkInformerCache(b, nil)
+	})
+
+	b.Run("with transform", func(b *testing.B) {
+		benchmarkInformerCache(b, Transform)
+	})
+}
+
+func benchmarkInformerCache(b *testing.B, transform cache.TransformFunc) {
+	const count = 10000
+
+	for i := 0; i < b.N; i++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		store := cache.NewStore(cache.MetaNamespaceKeyFunc)
+		for j := 0; j < count; j++ {
+			var obj interface{} = newTransformGameServer(j)
+			if transform != nil {
+				obj, _ = transform(obj)
+			}
+			require.NoError(b, store.Add(obj))
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/(1<<20), "MiB/10k")
+		runtime.KeepAlive(store)
+	}
+}
+
+// newTransformGameServer returns a GameServer similar to the ones created by a Fleet, including the pod template
+// and the managed fields written by Agones.
+func newTransformGameServer(i int) *agonesv1.GameServer {
+	fields := `{"f:metadata":{"f:annotations":{".":{},"f:agones.dev/sdk-version":{}},"f:labels":{".":{},"f:agones.dev/fleet":{},"f:agones.dev/gameserverset":{}},"f:ownerReferences":{".":{},"k:{\"uid\":\"0\"}":{}}},"f:spec":{".":{},"f:container":{},"f:health":{".":{},"f:failureThreshold":{},"f:initialDelaySeconds":{},"f:periodSeconds":{}},"f:ports":{},"f:scheduling":{},"f:sdkServer":{".":{},"f:grpcPort":{},"f:httpPort":{},"f:logLevel":{}},"f:template":{".":{},"f:metadata":{".":{},"f:creationTimestamp":{}},"f:spec":{".":{},"f:containers":{}}}}}`
+
+	return &agonesv1.GameServer{
+		TypeMeta: metav1.TypeMeta{Kind: "GameServer", APIVersion: "agones.dev/v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("fleet-abcde-%05d", i),
+			Namespace: "default",
+			Labels: map[string]string{
+				"agones.dev/fleet":         "fleet",
+				"agones.dev/gameserverset": "fleet-abcde",
+			},
+			Annotations: map[string]string{
+				"agones.dev/sdk-version":      "1.56.0",
+				OctopsAnnotationIngressMode:   string(IngressRoutingModeDomain),
+				OctopsAnnotationIngressDomain: "example.com",
+			},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{Manager: "agones-controller", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{Raw: []byte(fields)}},
+				{Manager: "agones-controller", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1", FieldsV1: &metav1.FieldsV1{Raw: []byte(fields)}, Subresource: "status"},
+			},
+		},
+		Spec: agonesv1.GameServerSpec{
+			Container: "gameserver",
+			Ports: []agonesv1.GameServerPort{
+				{Name: "default", PortPolicy: "Dynamic", ContainerPort: 7654, Protocol: corev1.ProtocolTCP},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "gameserver"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "gameserver",
+							Image: "us-docker.pkg.dev/agones-images/examples/simple-game-server:0.35",
+							Args:  []string{"-udp=false", "-tcp=true", "-port=7654"},
+							Env: []corev1.EnvVar{
+								{Name: "LOG_LEVEL", Value: "info"},
+								{Name: "REGION", Value: "us-east-1"},
+								{Name: "POD_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"}}},
+							},
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("20m"),
+									corev1.ResourceMemory: resource.MustParse("64Mi"),
+								},
+								Limits: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("20m"),
+									corev1.ResourceMemory: resource.MustParse("64Mi"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		Status: agonesv1.GameServerStatus{
+			State:    agonesv1.GameServerStateReady,
+			Address:  "10.0.0.1",
+			NodeName: "node-1",
+			Ports:    []agonesv1.GameServerStatusPort{{Name: "default", Port: 7000 + int32(i%1000)}},
+		},
+	}
+}
@@ -3,17 +3,26 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/claims"
 	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
 	"github.com/Octops/gameserver-ingress-controller/pkg/reconcilers"
 	"github.com/Octops/gameserver-ingress-controller/pkg/record"
 	"github.com/Octops/gameserver-ingress-controller/pkg/stores"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type GameSeverEventHandler struct {
@@ -23,106 +32,363 @@ type GameSeverEventHandler struct {
 	ingressReconciler    *reconcilers.IngressReconciler
 	gatewayReconciler    *reconcilers.GatewayReconciler
 	gameserverReconciler *reconcilers.GameServerReconciler
+	cleanupReconciler    *reconcilers.CleanupReconciler
+	grantReconciler      *reconcilers.ReferenceGrantReconciler
+	placeholders         *placeholder.Registry
+	placeholderBackend   placeholder.Backend
+	controllerClass      string
+	namespaces           client.Reader
+	defaults             map[string]string
+	hostChecks           []reconcilers.HostCheck
+	claims               *claims.Index
+	agones               *stores.AgonesStore
+	recorder             *record.EventRecorder
+	managed              *metrics.ManagedSet
+	timer                *metrics.RouteTimer
 }
 
-func NewGameSeverEventHandler(store *stores.Store, agones *stores.AgonesStore, recorder *record.EventRecorder, gatewayEnabled bool) *GameSeverEventHandler {
-	h := &GameSeverEventHandler{
-		logger:               runtime.Logger().WithField("component", "event_handler"),
-		serviceReconciler:    reconcilers.NewServiceReconciler(store, recorder),
-		ingressReconciler:    reconcilers.NewIngressReconciler(store, recorder),
-		gameserverReconciler: reconcilers.NewGameServerReconciler(agones, recorder),
+type HandlerOption func(h *GameSeverEventHandler)
+
+// WithPlaceholder routes GameServers that are not routable to the placeholder backend
+// and keeps the registry used by the placeholder server up to date.
+func WithPlaceholder(registry *placeholder.Registry, backend placeholder.Backend) HandlerOption {
+	return func(h *GameSeverEventHandler) {
+		h.placeholders = registry
+		h.placeholderBackend = backend
 	}
-	if gatewayEnabled {
-		h.gatewayReconciler = reconcilers.NewGatewayReconciler(store, recorder)
+}
+
+// WithControllerClass only reconciles GameServers annotated with octops.io/controller-class set to the class.
+func WithControllerClass(class string) HandlerOption {
+	return func(h *GameSeverEventHandler) {
+		h.controllerClass = class
 	}
-	return h
 }
 
-func (h *GameSeverEventHandler) OnAdd(ctx context.Context, obj interface{}) error {
-	gs := gameserver.FromObject(obj)
+// WithDefaults resolves the octops.io annotations a GameServer does not set from the annotations of its Namespace,
+// read using the reader, and then from the controller defaults. The Namespace layer is skipped if reader is nil.
+func WithDefaults(reader client.Reader, defaults map[string]string) HandlerOption {
+	return func(h *GameSeverEventHandler) {
+		h.namespaces = reader
+		h.defaults = defaults
+	}
+}
 
-	if err := h.Reconcile(ctx, h.logger.WithField("event", "added"), gs); err != nil {
-		h.logger.Error(err)
+// WithHostChecks refuses to publish GameServers on hosts rejected by any of the checks, e.g. by the domain policy.
+func WithHostChecks(checks ...reconcilers.HostCheck) HandlerOption {
+	return func(h *GameSeverEventHandler) {
+		h.hostChecks = append(h.hostChecks, checks...)
 	}
+}
 
-	return nil
+// WithClaims refuses to publish GameServers on a host and path pair already claimed by an older GameServer.
+func WithClaims(index *claims.Index) HandlerOption {
+	return func(h *GameSeverEventHandler) {
+		h.claims = index
+	}
 }
 
-func (h *GameSeverEventHandler) OnUpdate(ctx context.Context, _ interface{}, newObj interface{}) error {
-	gs := gameserver.FromObject(newObj)
+func NewGameSeverEventHandler(store *stores.Store, agones *stores.AgonesStore, recorder *record.EventRecorder, gatewayEnabled bool, opts ...HandlerOption) *GameSeverEventHandler {
+	h := &GameSeverEventHandler{
+		logger:   runtime.Logger().WithField("component", "event_handler"),
+		agones:   agones,
+		recorder: recorder,
+		managed:  metrics.NewManagedSet(),
+		timer:    metrics.NewRouteTimer(),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
 
-	if err := h.Reconcile(ctx, h.logger.WithField("event", "updated"), gs); err != nil {
-		h.logger.Error(err)
+	h.serviceReconciler = reconcilers.NewServiceReconciler(store, recorder)
+	h.ingressReconciler = reconcilers.NewIngressReconciler(store, recorder, h.hostChecks...)
+	h.gameserverReconciler = reconcilers.NewGameServerReconciler(agones, recorder, reconcilers.WithRouteTimer(h.timer))
+	var routes reconcilers.HTTPRouteCleanupStore
+	if gatewayEnabled {
+		h.gatewayReconciler = reconcilers.NewGatewayReconciler(store, recorder, h.hostChecks...)
+		h.grantReconciler = reconcilers.NewReferenceGrantReconciler(store, recorder)
+		if err := store.AddReferenceGrantHandler(h.grantReconciler); err != nil {
+			h.logger.WithError(err).Error("failed to watch the placeholder reference grant")
+		}
+		routes = store
 	}
+	h.cleanupReconciler = reconcilers.NewCleanupReconciler(store, store, routes, recorder)
 
-	return nil
+	return h
 }
 
-func (h *GameSeverEventHandler) OnDelete(_ context.Context, obj interface{}) error {
+func (h *GameSeverEventHandler) OnDelete(ctx context.Context, obj interface{}) error {
 	gs := obj.(*agonesv1.GameServer)
-	h.logger.WithField("event", "deleted").Infof("%s/%s", gs.Namespace, gs.Name)
+	if !gameserver.MatchesControllerClass(gs, h.controllerClass) {
+		return nil
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"event":                 "deleted",
+		runtime.FieldGameServer: gs.Name,
+		runtime.FieldNamespace:  gs.Namespace,
+	}).Infof("%s/%s", gs.Namespace, gs.Name)
+
+	key := types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}
+	h.managed.Delete(key)
+	h.timer.Forget(gs)
+	h.recorder.Forget(gs)
+	if h.claims != nil {
+		h.claims.Release(key)
+	}
+
+	if h.placeholders != nil {
+		resolved, err := h.ResolveDefaults(ctx, gs)
+		if err != nil {
+			return err
+		}
+
+		h.placeholders.Set(resolved, placeholder.StateGone)
+	}
 
 	return nil
 }
 
+// unmanage stops counting a GameServer the controller no longer manages and releases the host and path pairs it
+// claimed, so they don't block other GameServers until it is deleted. Claims are only taken by managed GameServers.
+func (h *GameSeverEventHandler) unmanage(key types.NamespacedName) {
+	if h.managed.Delete(key) && h.claims != nil {
+		h.claims.Release(key)
+	}
+}
+
 func (h *GameSeverEventHandler) Reconcile(ctx context.Context, logger *logrus.Entry, gs *agonesv1.GameServer) error {
+	// Resources of GameServers managed by another controller instance must not be cleaned up
+	if !gameserver.MatchesControllerClass(gs, h.controllerClass) {
+		logger.Debugf("skipping %s/%s, managed by controller class %q", gs.Namespace, gs.Name, gs.Annotations[gameserver.OctopsAnnotationControllerClass])
+		metrics.SkippedGameServers.WithLabelValues(metrics.SkipReasonControllerClass).Inc()
+		h.unmanage(types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name})
+		return nil
+	}
+
+	gs, err := h.ResolveDefaults(ctx, gs)
+	if err != nil {
+		return err
+	}
+
+	// Resources that no longer belong to the GameServer are removed before anything else is reconciled
+	deleted, err := h.cleanupReconciler.Reconcile(ctx, gs)
+	if err != nil {
+		return errors.Wrapf(err, "failed to cleanup resources for %s", k8sutil.Namespaced(gs))
+	}
+	if len(deleted) > 0 {
+		logger.WithField("deleted", deleted).Infof("%s cleaned up", k8sutil.Namespaced(gs))
+	}
+
 	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationIngressMode); !ok {
 		logger.Infof("skipping %s/%s, annotation %s not present", gs.Namespace, gs.Name, gameserver.OctopsAnnotationIngressMode)
+		metrics.SkippedGameServers.WithLabelValues(metrics.SkipReasonNoAnnotation).Inc()
+		h.unmanage(types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name})
 		return nil
 	}
 
+	h.managed.Set(types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, strings.Split(gameserver.RouterBackendsString(gs), ","))
+	logger = logger.WithField(runtime.FieldBackend, gameserver.RouterBackendsString(gs))
+
+	if claimed, err := h.claim(ctx, logger, gs); err != nil || !claimed {
+		return err
+	}
+
+	h.timer.Scheduled(gs)
+
 	//If a game server is in a Shutdown state it will not trigger reconcile
 	if gameserver.IsShutdown(gs) {
 		logger.WithField("event", "shutdown").Infof("%s/%s", gs.Namespace, gs.Name)
+		metrics.SkippedGameServers.WithLabelValues(metrics.SkipReasonShutdown).Inc()
 
-		return nil
+		return h.reconcilePlaceholder(ctx, gs)
 	}
 
 	//Only Scheduled, ReadyState and Ready game server states will trigger reconcile
 	if gameserver.MustReconcile(gs) == false {
 		msg := fmt.Sprintf("%s/%s/%s not reconciled, requires Scheduled, ReadyState or Ready state", gs.Namespace, gs.Name, gs.Status.State)
 		logger.Info(msg)
+		metrics.SkippedGameServers.WithLabelValues(metrics.SkipReasonNotReady).Inc()
 
-		return nil
+		return h.reconcilePlaceholder(ctx, gs)
 	}
 
-	_, err := h.serviceReconciler.Reconcile(ctx, gs)
+	_, err = h.serviceReconciler.Reconcile(ctx, gs)
 	if err != nil {
 		return errors.Wrapf(err, "failed to reconcile service %s", k8sutil.Namespaced(gs))
 	}
+	h.timer.ServiceCreated(gs)
 
+	// Every requested backend is reconciled even if one of them fails, so the status of each one can be published
 	var routeReconciled bool
-	switch gameserver.GetRouterBackend(gs) {
+	var errs []error
+	statuses := map[gameserver.RouterBackend]gameserver.BackendStatus{}
+	for _, backend := range gameserver.GetRouterBackends(gs) {
+		reconciled, err := h.reconcileRoute(ctx, gs, backend)
+		if err != nil {
+			statuses[backend] = gameserver.BackendStatusFailed
+			errs = append(errs, err)
+			continue
+		}
+
+		statuses[backend] = gameserver.BackendStatusReady
+		routeReconciled = routeReconciled || reconciled
+	}
+
+	if len(errs) == 0 {
+		h.timer.RouteCreated(gs)
+	}
+
+	if h.placeholders != nil && len(errs) == 0 {
+		h.placeholders.Remove(gs)
+	}
+
+	result, err := h.gameserverReconciler.Reconcile(ctx, gs, statuses)
+	if err != nil {
+		errs = append(errs, errors.Wrapf(err, "failed to reconcile gameserver %s", k8sutil.Namespaced(gs)))
+	}
+
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	if routeReconciled {
+		msg := fmt.Sprintf("%s/%s", k8sutil.Namespaced(result), result.Status.State)
+		logger.WithField("reconciled", true).Info(msg)
+	}
+
+	return nil
+}
+
+// ResolveDefaults returns the GameServer with the annotations inherited from its Namespace and the controller defaults.
+func (h *GameSeverEventHandler) ResolveDefaults(ctx context.Context, gs *agonesv1.GameServer) (*agonesv1.GameServer, error) {
+	return resolveDefaults(ctx, h.namespaces, h.defaults, gs)
+}
+
+// resolveDefaults skips the Namespace layer if reader is nil.
+func resolveDefaults(ctx context.Context, reader client.Reader, defaults map[string]string, gs *agonesv1.GameServer) (*agonesv1.GameServer, error) {
+	var namespace map[string]string
+	if reader != nil {
+		ns := &corev1.Namespace{}
+		err := reader.Get(ctx, client.ObjectKey{Name: gs.Namespace}, ns)
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "failed to get namespace %s", gs.Namespace)
+		}
+		namespace = ns.Annotations
+	}
+
+	return gameserver.WithDefaults(gs, namespace, defaults), nil
+}
+
+// claim reserves the routes of the GameServer. A conflicting claim is reported once on both GameServers and refused,
+// so the ingress controller never has to pick one of two identical routes. Routes the refused GameServer already
+// published are withdrawn. It is not retried, the index enqueues it again once the conflicting claim is released.
+func (h *GameSeverEventHandler) claim(ctx context.Context, logger *logrus.Entry, gs *agonesv1.GameServer) (bool, error) {
+	if h.claims == nil {
+		return true, nil
+	}
+
+	// Invalid routing annotations are reported by the route reconcilers
+	targets, err := gameserver.GetRouteTargets(gs)
+	if err != nil {
+		return true, nil
+	}
+
+	err = h.claims.Claim(gs, targets)
+	if err == nil {
+		return true, nil
+	}
+
+	var conflict *claims.ConflictError
+	if !errors.As(err, &conflict) {
+		return false, err
+	}
+
+	if !conflict.Reported {
+		h.recorder.RecordConflict(gs, fmt.Sprintf("Route not published, %s", conflict))
+		winner, getErr := h.agones.GetGameServer(ctx, conflict.Owner.Name, conflict.Owner.Namespace)
+		if getErr == nil {
+			h.recorder.RecordConflict(winner, fmt.Sprintf("Host %s and path %s are also requested by gameserver %s, the newer claim was refused",
+				conflict.Target.Host, conflict.Target.Path, k8sutil.Namespaced(gs)))
+		}
+	}
+
+	deleted, err := h.cleanupReconciler.Withdraw(ctx, gs, conflict.Error())
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to withdraw routes for %s", k8sutil.Namespaced(gs))
+	}
+	if len(deleted) > 0 {
+		logger.WithField("deleted", deleted).Infof("%s routes withdrawn, %s", k8sutil.Namespaced(gs), conflict)
+	}
+
+	return false, nil
+}
+
+// reconcileRoute creates the Ingress or HTTPRoute for a single router backend.
+func (h *GameSeverEventHandler) reconcileRoute(ctx context.Context, gs *agonesv1.GameServer, backend gameserver.RouterBackend) (bool, error) {
+	switch backend {
 	case gameserver.RouterBackendGateway:
 		if h.gatewayReconciler == nil {
-			return errors.Errorf(
+			return false, errors.Errorf(
 				"gameserver %s requests router-backend=gateway but the Gateway API backend is disabled; "+
 					"restart the controller with --enable-gateway-api=true or install Gateway API CRDs",
 				k8sutil.Namespaced(gs),
 			)
 		}
-		_, routeReconciled, err = h.gatewayReconciler.Reconcile(ctx, gs)
+
+		_, reconciled, err := h.gatewayReconciler.Reconcile(ctx, gs)
 		if err != nil {
-			return errors.Wrapf(err, "failed to reconcile HTTPRoute %s", k8sutil.Namespaced(gs))
+			return false, errors.Wrapf(err, "failed to reconcile HTTPRoute %s", k8sutil.Namespaced(gs))
 		}
+
+		return reconciled, nil
 	default:
-		_, routeReconciled, err = h.ingressReconciler.Reconcile(ctx, gs)
+		_, reconciled, err := h.ingressReconciler.Reconcile(ctx, gs)
 		if err != nil {
-			return errors.Wrapf(err, "failed to reconcile ingress %s", k8sutil.Namespaced(gs))
+			return false, errors.Wrapf(err, "failed to reconcile ingress %s", k8sutil.Namespaced(gs))
 		}
+
+		return reconciled, nil
 	}
+}
 
-	result, err := h.gameserverReconciler.Reconcile(ctx, gs)
-	if err != nil {
-		return errors.Wrapf(err, "failed to reconcile gameserver %s", k8sutil.Namespaced(gs))
+// reconcilePlaceholder points the routes of starting and draining GameServers to the placeholder backend.
+// It is a no-op unless the handler was created using WithPlaceholder.
+func (h *GameSeverEventHandler) reconcilePlaceholder(ctx context.Context, gs *agonesv1.GameServer) error {
+	if h.placeholders == nil {
+		return nil
 	}
 
-	if routeReconciled {
-		msg := fmt.Sprintf("%s/%s", k8sutil.Namespaced(result), result.Status.State)
-		logger.WithFields(logrus.Fields{
-			"reconciled": true,
-			"backend":    string(gameserver.GetRouterBackend(gs)),
-		}).Info(msg)
+	state, ok := placeholder.StateFor(gs)
+	if !ok {
+		return nil
+	}
+
+	h.placeholders.Set(gs, state)
+
+	for _, backend := range gameserver.GetRouterBackends(gs) {
+		switch backend {
+		case gameserver.RouterBackendGateway:
+			if h.gatewayReconciler == nil {
+				continue
+			}
+
+			if err := h.grantReconciler.Reconcile(ctx, gs, h.placeholderBackend); err != nil {
+				return errors.Wrapf(err, "failed to reconcile placeholder reference grant for %s", k8sutil.Namespaced(gs))
+			}
+
+			if _, err := h.gatewayReconciler.ReconcilePlaceholder(ctx, gs, h.placeholderBackend, state); err != nil {
+				return errors.Wrapf(err, "failed to reconcile placeholder HTTPRoute %s", k8sutil.Namespaced(gs))
+			}
+		default:
+			if _, err := h.serviceReconciler.ReconcilePlaceholder(ctx, gs, h.placeholderBackend); err != nil {
+				return errors.Wrapf(err, "failed to reconcile placeholder service for %s", k8sutil.Namespaced(gs))
+			}
+
+			if _, err := h.ingressReconciler.ReconcilePlaceholder(ctx, gs, h.placeholderBackend, state); err != nil {
+				return errors.Wrapf(err, "failed to reconcile placeholder ingress %s", k8sutil.Namespaced(gs))
+			}
+		}
 	}
 
 	return nil
@@ -1,9 +1,13 @@
 package handlers
 
-import "context"
+import (
+	"context"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/sirupsen/logrus"
+)
 
 type EventHandler interface {
-	OnAdd(ctx context.Context, obj interface{}) error
-	OnUpdate(ctx context.Context, oldObj interface{}, newObj interface{}) error
+	Reconcile(ctx context.Context, logger *logrus.Entry, gs *agonesv1.GameServer) error
 	OnDelete(ctx context.Context, obj interface{}) error
 }
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/reconcilers"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// Plan describes how the handler publishes a GameServer. Building a plan never writes any resource.
+type Plan struct {
+	// GameServer has the annotations inherited from its Namespace and the controller defaults.
+	GameServer *agonesv1.GameServer
+	// Managed is false if the GameServer is skipped, Reason explains why.
+	Managed bool
+	Reason  string
+	// Service, Ingress and HTTPRoute are the resources the GameServer must have once it is routable. The routes are
+	// nil if their router backend is not requested.
+	Service   *corev1.Service
+	Ingress   *networkingv1.Ingress
+	HTTPRoute *gatewayv1.HTTPRoute
+	// Errors are the reasons the resources can't be created, e.g. a missing annotation.
+	Errors []error
+}
+
+// Planner builds plans. Unlike the handler it has no store, so it never reconciles anything.
+type Planner struct {
+	controllerClass   string
+	namespaces        client.Reader
+	defaults          map[string]string
+	serviceReconciler *reconcilers.ServiceReconciler
+	ingressReconciler *reconcilers.IngressReconciler
+	gatewayReconciler *reconcilers.GatewayReconciler
+}
+
+// NewPlanner returns a Planner configured with the handler options, e.g. to render the resources of a manifest
+// without a cluster. Every router backend is enabled and options that only matter to reconciles are ignored.
+func NewPlanner(opts ...HandlerOption) *Planner {
+	h := &GameSeverEventHandler{}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return &Planner{
+		controllerClass:   h.controllerClass,
+		namespaces:        h.namespaces,
+		defaults:          h.defaults,
+		serviceReconciler: reconcilers.NewServiceReconciler(nil, nil),
+		ingressReconciler: reconcilers.NewIngressReconciler(nil, nil, h.hostChecks...),
+		gatewayReconciler: reconcilers.NewGatewayReconciler(nil, nil, h.hostChecks...),
+	}
+}
+
+// Planner returns a Planner with the configuration of the handler, the Gateway API backend is only enabled if the
+// handler has it.
+func (h *GameSeverEventHandler) Planner() *Planner {
+	return &Planner{
+		controllerClass:   h.controllerClass,
+		namespaces:        h.namespaces,
+		defaults:          h.defaults,
+		serviceReconciler: h.serviceReconciler,
+		ingressReconciler: h.ingressReconciler,
+		gatewayReconciler: h.gatewayReconciler,
+	}
+}
+
+// Plan resolves the configuration of the GameServer and builds the resources the handler creates for it.
+func (p *Planner) Plan(ctx context.Context, gs *agonesv1.GameServer) (*Plan, error) {
+	plan := &Plan{GameServer: gs}
+
+	if !gameserver.MatchesControllerClass(gs, p.controllerClass) {
+		plan.Reason = fmt.Sprintf("managed by controller class %q", gs.Annotations[gameserver.OctopsAnnotationControllerClass])
+		return plan, nil
+	}
+
+	resolved, err := resolveDefaults(ctx, p.namespaces, p.defaults, gs)
+	if err != nil {
+		return nil, err
+	}
+	plan.GameServer = resolved
+
+	if _, ok := gameserver.HasAnnotation(resolved, gameserver.OctopsAnnotationIngressMode); !ok {
+		plan.Reason = fmt.Sprintf("annotation %s not present", gameserver.OctopsAnnotationIngressMode)
+		return plan, nil
+	}
+	plan.Managed = true
+
+	if plan.Service, err = p.serviceReconciler.Desired(resolved); err != nil {
+		plan.Errors = append(plan.Errors, err)
+	}
+
+	for _, backend := range gameserver.GetRouterBackends(resolved) {
+		switch backend {
+		case gameserver.RouterBackendGateway:
+			if p.gatewayReconciler == nil {
+				plan.Errors = append(plan.Errors, errors.New("router-backend=gateway is requested but the Gateway API backend is disabled"))
+				continue
+			}
+
+			if plan.HTTPRoute, err = p.gatewayReconciler.Desired(resolved); err != nil {
+				plan.Errors = append(plan.Errors, err)
+			}
+		default:
+			if plan.Ingress, err = p.ingressReconciler.Desired(resolved); err != nil {
+				plan.Errors = append(plan.Errors, err)
+			}
+		}
+	}
+
+	return plan, nil
+}
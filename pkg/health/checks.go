@@ -0,0 +1,58 @@
+package health
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// DefaultProbeInterval is how often GatewayAPI queries the API server, probes are usually sent every few seconds.
+const DefaultProbeInterval = time.Second * 30
+
+// Synced returns a check that fails until the cache reports it has synced.
+func Synced(name string, hasSynced func() bool) healthz.Checker {
+	return func(_ *http.Request) error {
+		if !hasSynced() {
+			return errors.Errorf("%s cache has not synced", name)
+		}
+
+		return nil
+	}
+}
+
+// GatewayAPI returns a check that fails when the Gateway API CRDs no longer match the backend resolved at startup
+// from --enable-gateway-api. The probe returns nil if the HTTPRoute CRD is served. Its result is kept for interval.
+func GatewayAPI(enabled bool, probe func() error, interval time.Duration) healthz.Checker {
+	if interval <= 0 {
+		interval = DefaultProbeInterval
+	}
+
+	var mu sync.Mutex
+	var checked time.Time
+	var last error
+
+	return func(_ *http.Request) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !checked.IsZero() && time.Since(checked) < interval {
+			return last
+		}
+
+		err := probe()
+		switch {
+		case enabled && err != nil:
+			last = errors.Wrap(err, "gateway API backend is enabled but HTTPRoutes are not served")
+		case !enabled && err == nil:
+			last = errors.New("HTTPRoutes are served but the gateway API backend was disabled at startup, restart the controller to enable it")
+		default:
+			last = nil
+		}
+		checked = time.Now()
+
+		return last
+	}
+}
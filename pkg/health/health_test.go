@@ -0,0 +1,110 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Synced(t *testing.T) {
+	synced := false
+	check := Synced("Agones", func() bool { return synced })
+
+	require.EqualError(t, check(nil), "Agones cache has not synced")
+
+	synced = true
+	require.NoError(t, check(nil))
+}
+
+func Test_GatewayAPI(t *testing.T) {
+	missing := errors.New("httproutes not found")
+
+	testCases := []struct {
+		name    string
+		enabled bool
+		probe   error
+		wantErr string
+	}{
+		{
+			name:    "enabled and served",
+			enabled: true,
+		},
+		{
+			name:    "enabled but no longer served",
+			enabled: true,
+			probe:   missing,
+			wantErr: "gateway API backend is enabled but HTTPRoutes are not served: httproutes not found",
+		},
+		{
+			name:    "disabled and not served",
+			enabled: false,
+			probe:   missing,
+		},
+		{
+			name:    "disabled but installed after startup",
+			enabled: false,
+			wantErr: "HTTPRoutes are served but the gateway API backend was disabled at startup, restart the controller to enable it",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := GatewayAPI(tc.enabled, func() error { return tc.probe }, time.Minute)
+
+			err := check(nil)
+			if len(tc.wantErr) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func Test_GatewayAPI_CachesProbe(t *testing.T) {
+	var calls int
+	check := GatewayAPI(true, func() error {
+		calls++
+		return nil
+	}, time.Minute)
+
+	require.NoError(t, check(nil))
+	require.NoError(t, check(nil))
+	require.Equal(t, 1, calls)
+}
+
+type fakeQueue int
+
+func (q *fakeQueue) Len() int {
+	return int(*q)
+}
+
+func Test_QueueWatchdog(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	watchdog := NewQueueWatchdog(time.Minute)
+	watchdog.now = func() time.Time { return now }
+
+	// Standby replicas never create the queue
+	require.NoError(t, watchdog.Check(nil))
+
+	queue := fakeQueue(0)
+	watchdog.ObserveQueue(&queue)
+
+	// An idle queue is never stalled
+	now = now.Add(time.Hour)
+	require.NoError(t, watchdog.Check(nil))
+
+	queue = 3
+	now = now.Add(time.Second * 30)
+	require.NoError(t, watchdog.Check(nil))
+
+	watchdog.Processed()
+	now = now.Add(time.Second * 50)
+	require.NoError(t, watchdog.Check(nil))
+
+	now = now.Add(time.Second * 20)
+	require.EqualError(t, watchdog.Check(nil), "work queue stalled, 3 requests pending and no reconcile completed for 1m10s")
+}
@@ -0,0 +1,79 @@
+package health
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultStallTimeout is the time a work queue with pending requests can go without completing a reconcile.
+const DefaultStallTimeout = time.Minute * 5
+
+// Queue is the subset of the work queue used to check for pending requests.
+type Queue interface {
+	Len() int
+}
+
+// QueueWatchdog detects a stalled work queue, i.e. requests are waiting but no reconcile completed for the timeout,
+// for example because every worker is blocked on a call that never returns.
+type QueueWatchdog struct {
+	mu      sync.Mutex
+	queue   Queue
+	last    time.Time
+	timeout time.Duration
+	now     func() time.Time
+}
+
+func NewQueueWatchdog(timeout time.Duration) *QueueWatchdog {
+	if timeout <= 0 {
+		timeout = DefaultStallTimeout
+	}
+
+	return &QueueWatchdog{
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// ObserveQueue sets the watched queue. The queue is only created once the controller starts, a standby replica
+// has none and is always live.
+func (w *QueueWatchdog) ObserveQueue(queue Queue) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.queue = queue
+	w.last = w.now()
+}
+
+// Processed is called every time a reconcile completes, successful or not.
+func (w *QueueWatchdog) Processed() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.last = w.now()
+}
+
+// Check is a liveness check failing if the queue is stalled.
+func (w *QueueWatchdog) Check(_ *http.Request) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.queue == nil {
+		return nil
+	}
+
+	// An empty queue is up to date, the timeout only starts once requests are waiting
+	pending := w.queue.Len()
+	if pending == 0 {
+		w.last = w.now()
+		return nil
+	}
+
+	if idle := w.now().Sub(w.last); idle > w.timeout {
+		return errors.Errorf("work queue stalled, %d requests pending and no reconcile completed for %s", pending, idle.Round(time.Second))
+	}
+
+	return nil
+}
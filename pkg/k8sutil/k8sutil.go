@@ -50,3 +50,15 @@ func NewClusterConfig(kubeconfig string) (*rest.Config, error) {
 func Namespaced(obj v1.Object) string {
 	return fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
 }
+
+// DeleteOptions guards against deleting an object that was replaced after it was read from the cache.
+func DeleteOptions(obj v1.Object) v1.DeleteOptions {
+	uid := obj.GetUID()
+	if len(uid) == 0 {
+		return v1.DeleteOptions{}
+	}
+
+	return v1.DeleteOptions{
+		Preconditions: &v1.Preconditions{UID: &uid},
+	}
+}
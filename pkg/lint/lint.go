@@ -0,0 +1,414 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/handlers"
+	"github.com/Octops/gameserver-ingress-controller/pkg/render"
+	"github.com/pkg/errors"
+	"go.yaml.in/yaml/v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is an issue found in the Octops configuration of a manifest.
+type Problem struct {
+	File       string   `json:"file"`
+	Line       int      `json:"line"`
+	Severity   Severity `json:"severity"`
+	Kind       string   `json:"kind"`
+	Name       string   `json:"name"`
+	Annotation string   `json:"annotation,omitempty"`
+	Message    string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	prefix := fmt.Sprintf("%s:%d: %s: %s %s", p.File, p.Line, p.Severity, p.Kind, p.Name)
+	if len(p.Annotation) > 0 {
+		return fmt.Sprintf("%s: %s: %s", prefix, p.Annotation, p.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", prefix, p.Message)
+}
+
+// HasErrors checks if any of the problems is an error.
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Options struct {
+	// Defaults are the annotations the controller config sets on every GameServer, see --default-annotations.
+	Defaults map[string]string
+}
+
+// Linter checks the Octops annotations of Fleets, GameServerSets and GameServers.
+type Linter struct {
+	planner *handlers.Planner
+	options Options
+}
+
+func NewLinter(options Options) *Linter {
+	return &Linter{
+		planner: handlers.NewPlanner(handlers.WithDefaults(nil, options.Defaults)),
+		options: options,
+	}
+}
+
+// Lint reads a YAML or JSON manifest, possibly with multiple documents, and returns the problems sorted by line. An
+// error is returned if the manifest can't be parsed.
+func (l *Linter) Lint(ctx context.Context, file string, r io.Reader) ([]Problem, error) {
+	var problems []Problem
+
+	decoder := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", file)
+		}
+
+		found, err := l.lintDocument(ctx, file, &doc)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, found...)
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+
+	return problems, nil
+}
+
+// annotationsPath is where the annotations of the GameServers are set for each kind.
+var annotationsPath = map[string][]string{
+	"Fleet":         {"spec", "template", "metadata", "annotations"},
+	"GameServerSet": {"spec", "template", "metadata", "annotations"},
+	"GameServer":    {"metadata", "annotations"},
+}
+
+func (l *Linter) lintDocument(ctx context.Context, file string, doc *yaml.Node) ([]Problem, error) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+
+	kind := value(lookup(root, "kind"))
+	gv, err := schema.ParseGroupVersion(value(lookup(root, "apiVersion")))
+	if err != nil || gv.Group != agonesv1.SchemeGroupVersion.Group {
+		return nil, nil
+	}
+
+	path, ok := annotationsPath[kind]
+	if !ok {
+		return nil, nil
+	}
+
+	b, err := yaml.Marshal(root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode %s at line %d", kind, root.Line)
+	}
+
+	source, ok, err := render.FromManifest(b, render.Options{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s:%d", file, root.Line)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	d := &document{
+		file:        file,
+		kind:        kind,
+		name:        fmt.Sprintf("%s/%s", source.Namespace, source.Name),
+		line:        root.Line,
+		annotations: map[string]*yaml.Node{},
+	}
+
+	node := root
+	for _, key := range path {
+		var keyNode *yaml.Node
+		if keyNode, node = lookupPair(node, key); node == nil {
+			break
+		}
+		d.line = keyNode.Line
+	}
+	if node != nil && node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			d.annotations[node.Content[i].Value] = node.Content[i]
+		}
+	}
+
+	gs := gameserver.WithDefaults(source.GameServer, l.options.Defaults)
+	d.check(gs)
+
+	// The resources are only built once the annotations are valid, so every problem is reported once
+	if HasErrors(d.problems) {
+		return d.problems, nil
+	}
+
+	plan, err := l.planner.Plan(ctx, gs)
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range plan.Errors {
+		d.errorf("", "%s", err)
+	}
+
+	return d.problems, nil
+}
+
+// document collects the problems of a single manifest.
+type document struct {
+	file string
+	kind string
+	name string
+	// line is the line of the annotations, or of their closest parent if the manifest has none.
+	line        int
+	annotations map[string]*yaml.Node
+	problems    []Problem
+}
+
+func (d *document) add(severity Severity, annotation, format string, args ...interface{}) {
+	line := d.line
+	if node, ok := d.annotations[annotation]; ok {
+		line = node.Line
+	}
+
+	d.problems = append(d.problems, Problem{
+		File:       d.file,
+		Line:       line,
+		Severity:   severity,
+		Kind:       d.kind,
+		Name:       d.name,
+		Annotation: annotation,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *document) errorf(annotation, format string, args ...interface{}) {
+	d.add(SeverityError, annotation, format, args...)
+}
+
+func (d *document) warnf(annotation, format string, args ...interface{}) {
+	d.add(SeverityWarning, annotation, format, args...)
+}
+
+func (d *document) check(gs *agonesv1.GameServer) {
+	d.checkKeys(gs)
+	d.checkTemplates(gs)
+
+	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationIngressMode); !ok {
+		for k := range gs.Annotations {
+			if userAnnotations[k] && k != gameserver.OctopsAnnotationControllerClass {
+				d.warnf("", "octops.io annotations have no effect without %s", gameserver.OctopsAnnotationIngressMode)
+				break
+			}
+		}
+		return
+	}
+
+	d.checkBooleans(gs)
+	d.checkRoutingMode(gs)
+	d.checkBackends(gs)
+}
+
+// userAnnotations are the octops.io annotations set on Fleets and GameServers.
+var userAnnotations = map[string]bool{
+	gameserver.OctopsAnnotationIngressMode:        true,
+	gameserver.OctopsAnnotationIngressDomain:      true,
+	gameserver.OctopsAnnotationIngressFQDN:        true,
+	gameserver.OctopsAnnotationTerminateTLS:       true,
+	gameserver.OctopsAnnotationsTLSSecretName:     true,
+	gameserver.OctopsAnnotationIssuerName:         true,
+	gameserver.OctopsAnnotationIngressClassName:   true,
+	gameserver.OctopsAnnotationRouterBackend:      true,
+	gameserver.OctopsAnnotationGatewayName:        true,
+	gameserver.OctopsAnnotationGatewayNamespace:   true,
+	gameserver.OctopsAnnotationGatewaySectionName: true,
+	gameserver.OctopsAnnotationControllerClass:    true,
+	gameserver.OctopsAnnotationHostnameShorten:    true,
+}
+
+// controllerAnnotations are the octops.io annotations written by the controller.
+var controllerAnnotations = map[string]bool{
+	gameserver.OctopsAnnotationGameServerIngressReady: true,
+	gameserver.OctopsAnnotationRouterBackendStatus:    true,
+	gameserver.OctopsAnnotationPlaceholderState:       true,
+	gameserver.OctopsAnnotationHostnameLabel:          true,
+	gameserver.OctopsAnnotationHostnameOriginal:       true,
+	gameserver.OctopsAnnotationTimeToRoute:            true,
+}
+
+// ingressAnnotations and gatewayAnnotations only apply to a single router backend.
+var (
+	ingressAnnotations = []string{
+		gameserver.OctopsAnnotationIngressClassName,
+		gameserver.OctopsAnnotationIngressClassNameLegacy,
+		gameserver.OctopsAnnotationTerminateTLS,
+		gameserver.OctopsAnnotationsTLSSecretName,
+		gameserver.OctopsAnnotationIssuerName,
+	}
+	gatewayAnnotations = []string{
+		gameserver.OctopsAnnotationGatewayName,
+		gameserver.OctopsAnnotationGatewayNamespace,
+		gameserver.OctopsAnnotationGatewaySectionName,
+	}
+)
+
+func (d *document) checkKeys(gs *agonesv1.GameServer) {
+	for _, k := range sortedKeys(gs.Annotations) {
+		switch {
+		case k == gameserver.OctopsAnnotationIngressClassNameLegacy:
+			d.warnf(k, "deprecated, use %s", gameserver.OctopsAnnotationIngressClassName)
+		case controllerAnnotations[k]:
+			d.warnf(k, "set by the controller, the value is overwritten")
+		case strings.HasPrefix(k, gameserver.OctopsAnnotationPrefix) && !userAnnotations[k]:
+			d.errorf(k, "unknown annotation")
+		case k == gameserver.OctopsAnnotationCustomPrefix || k == gameserver.OctopsAnnotationCustomServicePrefix:
+			d.errorf(k, "custom annotation does not contain a suffix")
+		}
+	}
+}
+
+func (d *document) checkTemplates(gs *agonesv1.GameServer) {
+	for _, k := range sortedKeys(gs.Annotations) {
+		if !strings.HasPrefix(k, gameserver.OctopsAnnotationCustomPrefix) &&
+			!strings.HasPrefix(k, gameserver.OctopsAnnotationCustomServicePrefix) {
+			continue
+		}
+
+		v := gs.Annotations[k]
+		if !gameserver.IsTemplate(v) {
+			continue
+		}
+
+		if _, err := gameserver.ExecuteTemplate(gs, v); err != nil {
+			d.errorf(k, "%s", err)
+		}
+	}
+}
+
+func (d *document) checkBooleans(gs *agonesv1.GameServer) {
+	for _, k := range []string{gameserver.OctopsAnnotationTerminateTLS, gameserver.OctopsAnnotationHostnameShorten} {
+		v, ok := gameserver.HasAnnotation(gs, k)
+		if !ok {
+			continue
+		}
+
+		if _, err := strconv.ParseBool(strings.TrimSpace(v)); err != nil {
+			d.errorf(k, "must be \"true\" or \"false\", got %q", v)
+		}
+	}
+}
+
+func (d *document) checkRoutingMode(gs *agonesv1.GameServer) {
+	mode := gameserver.GetIngressRoutingMode(gs)
+
+	var required, ignored string
+	switch mode {
+	case gameserver.IngressRoutingModeDomain:
+		required, ignored = gameserver.OctopsAnnotationIngressDomain, gameserver.OctopsAnnotationIngressFQDN
+	case gameserver.IngressRoutingModePath:
+		required, ignored = gameserver.OctopsAnnotationIngressFQDN, gameserver.OctopsAnnotationIngressDomain
+	default:
+		d.errorf(gameserver.OctopsAnnotationIngressMode, "routing mode %q is not valid, use %s or %s", mode,
+			gameserver.IngressRoutingModeDomain, gameserver.IngressRoutingModePath)
+		return
+	}
+
+	if v, ok := gameserver.HasAnnotation(gs, required); !ok || len(strings.TrimSpace(v)) == 0 {
+		d.errorf(gameserver.OctopsAnnotationIngressMode, "routing mode %s requires the annotation %s", mode, required)
+	}
+
+	if _, ok := gameserver.HasAnnotation(gs, ignored); ok {
+		d.warnf(ignored, "has no effect in routing mode %s", mode)
+	}
+}
+
+func (d *document) checkBackends(gs *agonesv1.GameServer) {
+	if v, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationRouterBackend); ok {
+		for _, b := range strings.Split(v, ",") {
+			backend := gameserver.RouterBackend(strings.ToLower(strings.TrimSpace(b)))
+			if backend != gameserver.RouterBackendIngress && backend != gameserver.RouterBackendGateway {
+				d.errorf(gameserver.OctopsAnnotationRouterBackend, "router backend %q is not valid, use %s or %s", b,
+					gameserver.RouterBackendIngress, gameserver.RouterBackendGateway)
+			}
+		}
+	}
+
+	noEffect := func(backend gameserver.RouterBackend, annotations []string) {
+		if gameserver.HasRouterBackend(gs, backend) {
+			return
+		}
+
+		for _, k := range annotations {
+			if _, ok := gameserver.HasAnnotation(gs, k); ok {
+				d.warnf(k, "has no effect, router backend %s is not requested by %s", backend, gameserver.OctopsAnnotationRouterBackend)
+			}
+		}
+	}
+	noEffect(gameserver.RouterBackendIngress, ingressAnnotations)
+	noEffect(gameserver.RouterBackendGateway, gatewayAnnotations)
+}
+
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	_, v := lookupPair(node, key)
+	return v
+}
+
+// lookupPair returns the key and value nodes of a key of a mapping.
+func lookupPair(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+func value(node *yaml.Node) string {
+	if node == nil {
+		return ""
+	}
+
+	return node.Value
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,205 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/stretchr/testify/require"
+)
+
+// fleet returns a Fleet manifest. The annotations start at line 10.
+func fleet(annotations ...string) string {
+	return fmt.Sprintf(`# Source: chart/templates/fleet.yaml
+apiVersion: agones.dev/v1
+kind: Fleet
+metadata:
+  name: octops
+spec:
+  template:
+    metadata:
+      annotations:
+        %s
+    spec:
+      ports:
+      - containerPort: 7654
+`, strings.Join(annotations, "\n        "))
+}
+
+func Test_Lint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		manifest string
+		expected []Problem
+	}{
+		{
+			name: "valid domain mode",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-mode: domain",
+				"octops.io/gameserver-ingress-domain: example.com",
+				"octops.io/terminate-tls: \"true\"",
+				"octops.io/issuer-tls-name: selfsigned-issuer",
+				"octops.io/ingress-class-name: contour",
+				"octops-projectcontour.io/websocket-routes: /",
+			),
+		},
+		{
+			name: "unknown and controller annotations",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-mode: path",
+				"octops.io/gameserver-ingress-fqdn: servers.example.com",
+				"octops.io/ingress-mode: path",
+				"octops.io/ingress-ready: \"true\"",
+				"octops.io/ingress-class-name: contour",
+			),
+			expected: []Problem{
+				{Line: 12, Severity: SeverityError, Annotation: "octops.io/ingress-mode", Message: "unknown annotation"},
+				{Line: 13, Severity: SeverityWarning, Annotation: gameserver.OctopsAnnotationGameServerIngressReady, Message: "set by the controller, the value is overwritten"},
+			},
+		},
+		{
+			name: "invalid values",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-mode: domains",
+				"octops.io/terminate-tls: \"yes\"",
+				"octops.io/router-backend: ingress,gateways",
+			),
+			expected: []Problem{
+				{Line: 10, Severity: SeverityError, Annotation: gameserver.OctopsAnnotationIngressMode, Message: `routing mode "domains" is not valid, use domain or path`},
+				{Line: 11, Severity: SeverityError, Annotation: gameserver.OctopsAnnotationTerminateTLS, Message: `must be "true" or "false", got "yes"`},
+				{Line: 12, Severity: SeverityError, Annotation: gameserver.OctopsAnnotationRouterBackend, Message: `router backend "gateways" is not valid, use ingress or gateway`},
+			},
+		},
+		{
+			name: "missing domain and ignored fqdn",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-mode: domain",
+				"octops.io/gameserver-ingress-fqdn: servers.example.com",
+			),
+			expected: []Problem{
+				{Line: 10, Severity: SeverityError, Annotation: gameserver.OctopsAnnotationIngressMode, Message: "routing mode domain requires the annotation octops.io/gameserver-ingress-domain"},
+				{Line: 11, Severity: SeverityWarning, Annotation: gameserver.OctopsAnnotationIngressFQDN, Message: "has no effect in routing mode domain"},
+			},
+		},
+		{
+			name: "templates and deprecated annotations",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-mode: domain",
+				"octops.io/gameserver-ingress-domain: example.com",
+				"octops-kubernetes.io/ingress.class: contour",
+				"octops-example.com/upstream: \"{{ .Name }:{{ .Port }}\"",
+				"octops.service-example.com/owner: \"{{ .Owner }}\"",
+			),
+			expected: []Problem{
+				{Line: 12, Severity: SeverityWarning, Annotation: gameserver.OctopsAnnotationIngressClassNameLegacy, Message: "deprecated, use octops.io/ingress-class-name"},
+				{Line: 13, Severity: SeverityError, Annotation: "octops-example.com/upstream", Message: `template can't be parsed: template: gs:1: unexpected "}" in operand`},
+				{Line: 14, Severity: SeverityError, Annotation: "octops.service-example.com/owner", Message: `template can't be executed: template: gs:1:3: executing "gs" at <.Owner>: can't evaluate field Owner in type gameserver.TemplateData`},
+			},
+		},
+		{
+			name: "annotations of another router backend",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-mode: domain",
+				"octops.io/gameserver-ingress-domain: example.com",
+				"octops.io/router-backend: gateway",
+				"octops.io/gateway-name: gateway",
+				"octops.io/ingress-class-name: contour",
+			),
+			expected: []Problem{
+				{Line: 14, Severity: SeverityWarning, Annotation: gameserver.OctopsAnnotationIngressClassName, Message: "has no effect, router backend ingress is not requested by octops.io/router-backend"},
+			},
+		},
+		{
+			name: "gateway annotations without gateway backend",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-mode: domain",
+				"octops.io/gameserver-ingress-domain: example.com",
+				"octops.io/gateway-name: gateway",
+				"octops.io/ingress-class-name: contour",
+			),
+			expected: []Problem{
+				{Line: 12, Severity: SeverityWarning, Annotation: gameserver.OctopsAnnotationGatewayName, Message: "has no effect, router backend gateway is not requested by octops.io/router-backend"},
+			},
+		},
+		{
+			name: "annotations without routing mode",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-domain: example.com",
+			),
+			expected: []Problem{
+				{Line: 9, Severity: SeverityWarning, Message: "octops.io annotations have no effect without octops.io/gameserver-ingress-mode"},
+			},
+		},
+		{
+			name: "resources that can't be built",
+			manifest: fleet(
+				"octops.io/gameserver-ingress-mode: domain",
+				"octops.io/gameserver-ingress-domain: example.com",
+				"octops.io/router-backend: gateway",
+			),
+			expected: []Problem{
+				{Line: 9, Severity: SeverityError, Message: "gameserver default/octops-xxxxx-xxxxx is missing annotation octops.io/gateway-name"},
+			},
+		},
+	}
+
+	linter := NewLinter(Options{})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems, err := linter.Lint(context.Background(), "fleet.yaml", strings.NewReader(tc.manifest))
+			require.NoError(t, err)
+
+			for i := range tc.expected {
+				tc.expected[i].File = "fleet.yaml"
+				tc.expected[i].Kind = "Fleet"
+				tc.expected[i].Name = "default/octops"
+			}
+
+			if len(tc.expected) == 0 {
+				require.Empty(t, problems)
+				return
+			}
+			require.Equal(t, tc.expected, problems)
+		})
+	}
+}
+
+func Test_Lint_Documents(t *testing.T) {
+	manifest := fleet("octops.io/gameserver-ingress-mode: domain") + `---
+apiVersion: v1
+kind: Service
+metadata:
+  name: octops
+---
+apiVersion: agones.dev/v1
+kind: GameServer
+metadata:
+  name: game-1
+  namespace: games
+spec:
+  ports:
+  - containerPort: 7654
+`
+
+	linter := NewLinter(Options{Defaults: map[string]string{gameserver.OctopsAnnotationIngressMode: "path"}})
+	problems, err := linter.Lint(context.Background(), "<stdin>", strings.NewReader(manifest))
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+
+	require.Equal(t, 10, problems[0].Line)
+	require.Equal(t, "Fleet", problems[0].Kind)
+
+	// The routing mode is inherited from the defaults, the problem is reported on the metadata
+	require.Equal(t, 22, problems[1].Line)
+	require.Equal(t, "GameServer", problems[1].Kind)
+	require.Equal(t, "games/game-1", problems[1].Name)
+	require.Equal(t, "<stdin>:22: error: GameServer games/game-1: octops.io/gameserver-ingress-mode: routing mode path requires the annotation octops.io/gameserver-ingress-fqdn", problems[1].String())
+	require.True(t, HasErrors(problems))
+}
+
+func Test_Lint_InvalidYAML(t *testing.T) {
+	_, err := NewLinter(Options{}).Lint(context.Background(), "fleet.yaml", strings.NewReader("kind: Fleet\n  name: [\n"))
+	require.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+)
+
+const (
+	LeaderStateLeader  = "leader"
+	LeaderStateStandby = "standby"
+)
+
+// LeaderStatus reports whether the replica holds the leader election lease. Standbys are ready as well so rolling
+// updates are not blocked, the state is exposed by the readiness check and the octops_leader_election_leader metric.
+// The manager closes the elected channel right away when leader election is disabled.
+type LeaderStatus struct {
+	elected <-chan struct{}
+}
+
+func NewLeaderStatus(elected <-chan struct{}) *LeaderStatus {
+	metrics.LeaderElectionLeader.Set(0)
+
+	return &LeaderStatus{elected: elected}
+}
+
+func (s *LeaderStatus) IsLeader() bool {
+	select {
+	case <-s.elected:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *LeaderStatus) State() string {
+	if s.IsLeader() {
+		return LeaderStateLeader
+	}
+
+	return LeaderStateStandby
+}
+
+// Check is registered as the "leader" readiness check. It fails on standbys, so /readyz/leader identifies the leader.
+// Pod readiness probes must use /readyz?exclude=leader or standbys would never become ready.
+func (s *LeaderStatus) Check(_ *http.Request) error {
+	if !s.IsLeader() {
+		return errors.Errorf("replica is %s", LeaderStateStandby)
+	}
+
+	return nil
+}
+
+// Start is only called by the manager once the lease is acquired.
+func (s *LeaderStatus) Start(ctx context.Context) error {
+	runtime.Logger().WithField("component", "leader_election").Info("leader election lease acquired, replica is the leader")
+	metrics.LeaderElectionLeader.Set(1)
+
+	<-ctx.Done()
+	metrics.LeaderElectionLeader.Set(0)
+
+	return nil
+}
+
+func (s *LeaderStatus) NeedLeaderElection() bool {
+	return true
+}
@@ -0,0 +1,22 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LeaderStatus(t *testing.T) {
+	elected := make(chan struct{})
+	status := NewLeaderStatus(elected)
+
+	require.False(t, status.IsLeader())
+	require.Equal(t, LeaderStateStandby, status.State())
+	require.EqualError(t, status.Check(nil), "replica is standby")
+
+	close(elected)
+
+	require.True(t, status.IsLeader())
+	require.Equal(t, LeaderStateLeader, status.State())
+	require.NoError(t, status.Check(nil))
+}
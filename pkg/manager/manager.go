@@ -3,14 +3,23 @@ package manager
 import (
 	"time"
 
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
 )
 
@@ -20,6 +29,21 @@ type Options struct {
 	HealthProbeBindAddress  string
 	MetricsBindAddress      string
 	MaxConcurrentReconciles int
+	// LeaderElection makes only one replica reconcile at a time. Standbys keep their caches warm and take over
+	// once the lease expires.
+	LeaderElection          bool
+	LeaderElectionID        string
+	LeaderElectionNamespace string
+	LeaseDuration           *time.Duration
+	RenewDeadline           *time.Duration
+	RetryPeriod             *time.Duration
+	// Namespaces restricts the manager cache. The cache is cluster wide if empty.
+	Namespaces []string
+	// GameServerSelector restricts the cached GameServers. Every GameServer is cached if nil.
+	GameServerSelector labels.Selector
+	// GatewayEnabled caches HTTPRoutes. It must be false if the Gateway API CRDs are not installed, the cache fails to
+	// start otherwise.
+	GatewayEnabled bool
 }
 
 type Manager struct {
@@ -32,13 +56,30 @@ func NewManager(kubeconfig string, options Options) (*Manager, error) {
 		return nil, withError(errors.Wrap(err, "failed to create cluster config"))
 	}
 
+	scheme, err := NewScheme()
+	if err != nil {
+		return nil, withError(err)
+	}
+
 	mgr, err := manager.New(config, manager.Options{
+		Scheme: scheme,
 		Cache: cache.Options{
-			SyncPeriod: options.SyncPeriod,
+			SyncPeriod:        options.SyncPeriod,
+			DefaultNamespaces: defaultNamespaces(options.Namespaces),
+			DefaultTransform:  cache.TransformStripManagedFields(),
+			ByObject:          CacheByObject(options.GameServerSelector, options.GatewayEnabled),
 		},
-		WebhookServer:          webhook.NewServer(webhook.Options{Port: options.Port}),
-		Metrics:                metricsserver.Options{BindAddress: options.MetricsBindAddress},
-		HealthProbeBindAddress: options.HealthProbeBindAddress,
+		WebhookServer:           webhook.NewServer(webhook.Options{Port: options.Port}),
+		Metrics:                 metricsserver.Options{BindAddress: options.MetricsBindAddress},
+		HealthProbeBindAddress:  options.HealthProbeBindAddress,
+		LeaderElection:          options.LeaderElection,
+		LeaderElectionID:        options.LeaderElectionID,
+		LeaderElectionNamespace: options.LeaderElectionNamespace,
+		LeaseDuration:           options.LeaseDuration,
+		RenewDeadline:           options.RenewDeadline,
+		RetryPeriod:             options.RetryPeriod,
+		// The lease is released on shutdown so a standby takes over without waiting for it to expire
+		LeaderElectionReleaseOnCancel: options.LeaderElection,
 		Controller: ctrlconfig.Controller{
 			MaxConcurrentReconciles: options.MaxConcurrentReconciles,
 			CacheSyncTimeout:        time.Second * 30,
@@ -48,17 +89,70 @@ func NewManager(kubeconfig string, options Options) (*Manager, error) {
 		return nil, withError(err)
 	}
 
-	if err := mgr.AddHealthzCheck("/", healthz.Ping); err != nil {
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return nil, withError(err)
+	}
+
+	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
 		return nil, withError(err)
 	}
 
-	if err := mgr.AddReadyzCheck("/", healthz.Ping); err != nil {
+	status := NewLeaderStatus(mgr.Elected())
+	if err := mgr.AddReadyzCheck("leader", status.Check); err != nil {
+		return nil, withError(err)
+	}
+
+	if err := mgr.Add(status); err != nil {
 		return nil, withError(err)
 	}
 
 	return &Manager{mgr}, nil
 }
 
+// CacheByObject restricts the cached Services, Ingresses and HTTPRoutes to the ones created for GameServers and
+// strips the GameServer fields the controller never reads. Only GameServers matching gsSelector are cached.
+// HTTPRoutes are only configured if the Gateway API backend is enabled, the cache looks up the REST mapping of every
+// kind and fails if the CRDs are not installed.
+func CacheByObject(gsSelector labels.Selector, gatewayEnabled bool) map[client.Object]cache.ByObject {
+	selector := gameserver.ManagedSelector()
+
+	byObject := map[client.Object]cache.ByObject{
+		&corev1.Service{}:       {Label: selector},
+		&networkingv1.Ingress{}: {Label: selector},
+		&agonesv1.GameServer{}:  {Label: gsSelector, Transform: gameserver.Transform},
+	}
+	if gatewayEnabled {
+		byObject[&gatewayv1.HTTPRoute{}] = cache.ByObject{Label: selector}
+	}
+
+	return byObject
+}
+
+func defaultNamespaces(namespaces []string) map[string]cache.Config {
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	result := make(map[string]cache.Config, len(namespaces))
+	for _, ns := range namespaces {
+		result[ns] = cache.Config{}
+	}
+
+	return result
+}
+
+// NewScheme returns a scheme with the built-in kinds, Agones and Gateway API kinds registered.
+func NewScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, agonesv1.AddToScheme, gatewayv1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			return nil, errors.Wrap(err, "failed to register scheme")
+		}
+	}
+
+	return scheme, nil
+}
+
 func withError(err error) error {
 	return errors.Wrap(err, "failed to create manager")
 }
@@ -0,0 +1,24 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func Test_CacheByObject(t *testing.T) {
+	hasHTTPRoute := func(gatewayEnabled bool) bool {
+		for obj := range CacheByObject(labels.Everything(), gatewayEnabled) {
+			if _, ok := obj.(*gatewayv1.HTTPRoute); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	require.False(t, hasHTTPRoute(false))
+	require.True(t, hasHTTPRoute(true))
+	require.Len(t, CacheByObject(labels.Everything(), false), 3)
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ManagedSet keeps ManagedGameServers up to date. A GameServer is counted once for each of its router backends.
+type ManagedSet struct {
+	mu          sync.Mutex
+	gameservers map[types.NamespacedName][]string
+}
+
+func NewManagedSet() *ManagedSet {
+	return &ManagedSet{gameservers: map[types.NamespacedName][]string{}}
+}
+
+// Set counts the GameServer as managed for the backends, replacing the backends it was counted for before.
+func (m *ManagedSet) Set(key types.NamespacedName, backends []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.remove(key)
+	m.gameservers[key] = backends
+	for _, backend := range backends {
+		ManagedGameServers.WithLabelValues(key.Namespace, backend).Inc()
+	}
+}
+
+// Delete stops counting the GameServer. It returns false if the GameServer was not counted.
+func (m *ManagedSet) Delete(key types.NamespacedName) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.remove(key)
+}
+
+func (m *ManagedSet) remove(key types.NamespacedName) bool {
+	backends, ok := m.gameservers[key]
+	if !ok {
+		return false
+	}
+
+	for _, backend := range backends {
+		ManagedGameServers.WithLabelValues(key.Namespace, backend).Dec()
+	}
+	delete(m.gameservers, key)
+	return true
+}
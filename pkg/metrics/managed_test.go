@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_ManagedSet(t *testing.T) {
+	ManagedGameServers.Reset()
+	set := NewManagedSet()
+
+	game1 := types.NamespacedName{Namespace: "team-a", Name: "game-1"}
+	game2 := types.NamespacedName{Namespace: "team-a", Name: "game-2"}
+
+	set.Set(game1, []string{"ingress"})
+	set.Set(game1, []string{"ingress"})
+	set.Set(game2, []string{"ingress", "gateway"})
+	require.Equal(t, float64(2), testutil.ToFloat64(ManagedGameServers.WithLabelValues("team-a", "ingress")))
+	require.Equal(t, float64(1), testutil.ToFloat64(ManagedGameServers.WithLabelValues("team-a", "gateway")))
+
+	// Backends replace the ones the gameserver was counted for
+	set.Set(game1, []string{"gateway"})
+	require.Equal(t, float64(1), testutil.ToFloat64(ManagedGameServers.WithLabelValues("team-a", "ingress")))
+	require.Equal(t, float64(2), testutil.ToFloat64(ManagedGameServers.WithLabelValues("team-a", "gateway")))
+
+	require.True(t, set.Delete(game1))
+	require.False(t, set.Delete(game1))
+	require.True(t, set.Delete(game2))
+	require.Equal(t, float64(0), testutil.ToFloat64(ManagedGameServers.WithLabelValues("team-a", "ingress")))
+	require.Equal(t, float64(0), testutil.ToFloat64(ManagedGameServers.WithLabelValues("team-a", "gateway")))
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "octops"
+
+// Results of a reconcile, used as the result label of ReconcileTotal.
+const (
+	ResultCreated   = "created"
+	ResultUpdated   = "updated"
+	ResultUnchanged = "unchanged"
+	ResultFailed    = "failed"
+)
+
+// Verbs of the requests sent to the API server, used as the verb label of APIWriteDuration and DryRunWrites.
+const (
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbPatch  = "patch"
+	VerbDelete = "delete"
+)
+
+// Reasons a GameServer is skipped, used as the reason label of SkippedGameServers.
+const (
+	SkipReasonControllerClass = "controller_class"
+	SkipReasonNoAnnotation    = "no_annotation"
+	SkipReasonNotReady        = "not_ready"
+	SkipReasonShutdown        = "shutdown"
+)
+
+var (
+	OrphanSweeps = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_sweeper",
+		Name:      "runs_total",
+		Help:      "Number of orphan sweeps executed",
+	})
+
+	OrphanSweepErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_sweeper",
+		Name:      "errors_total",
+		Help:      "Number of orphan sweeps that failed",
+	})
+
+	OrphanResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_sweeper",
+		Name:      "orphans",
+		Help:      "Number of orphaned resources found on the last sweep",
+	}, []string{"kind"})
+
+	OrphanResourcesDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_sweeper",
+		Name:      "deleted_total",
+		Help:      "Number of orphaned resources deleted",
+	}, []string{"kind"})
+
+	OrphanSweepLastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "orphan_sweeper",
+		Name:      "last_run_timestamp_seconds",
+		Help:      "Unix timestamp of the last orphan sweep",
+	})
+
+	LeaderElectionLeader = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "leader_election",
+		Name:      "leader",
+		Help:      "Set to 1 if the replica is the leader and 0 if it is a standby",
+	})
+
+	ShardMembers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sharding",
+		Name:      "members",
+		Help:      "Number of shards in the active ring",
+	})
+
+	ShardRebalances = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sharding",
+		Name:      "rebalances_total",
+		Help:      "Number of times a new ring was activated",
+	})
+
+	ShardQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sharding",
+		Name:      "queue_depth",
+		Help:      "Number of GameServers waiting to be reconciled by the shard",
+	}, []string{"shard"})
+
+	ShardOwnedGameServers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sharding",
+		Name:      "owned_gameservers",
+		Help:      "Number of GameServers owned by the shard after the last rebalance",
+	}, []string{"shard"})
+
+	ReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reconcile",
+		Name:      "total",
+		Help:      "Number of resources reconciled by kind, result and router backend",
+	}, []string{"kind", "result", "backend"})
+
+	ReconcileConflicts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reconcile",
+		Name:      "conflicts_total",
+		Help:      "Number of GameServer reconciles retried because the GameServer was modified concurrently",
+	})
+
+	APIWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "reconcile",
+		Name:      "api_write_duration_seconds",
+		Help:      "Latency of the create, update, patch and delete requests sent to the API server",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	}, []string{"kind", "verb"})
+
+	SkippedGameServers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reconcile",
+		Name:      "skipped_gameservers_total",
+		Help:      "Number of GameServer reconciles skipped by reason",
+	}, []string{"reason"})
+
+	ManagedGameServers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "managed_gameservers",
+		Help:      "Number of GameServers published by the controller per namespace and router backend",
+	}, []string{"namespace", "backend"})
+
+	DryRunWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dry_run",
+		Name:      "writes_total",
+		Help:      "Number of writes accepted by the API server in dry-run mode by kind and verb",
+	}, []string{"kind", "verb"})
+
+	HostnameConflicts = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "routes",
+		Name:      "hostname_conflicts",
+		Help:      "Number of host and path pairs claimed by more than one GameServer",
+	})
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		OrphanSweeps,
+		OrphanSweepErrors,
+		OrphanResources,
+		OrphanResourcesDeleted,
+		OrphanSweepLastRun,
+		LeaderElectionLeader,
+		ShardMembers,
+		ShardRebalances,
+		ShardQueueDepth,
+		ShardOwnedGameServers,
+		HostnameConflicts,
+		ReconcileTotal,
+		ReconcileConflicts,
+		APIWriteDuration,
+		SkippedGameServers,
+		ManagedGameServers,
+		TimeToRoute,
+		DryRunWrites,
+	)
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Stages of the time to route, measured from the moment the GameServer was observed Scheduled.
+const (
+	StageService = "service"
+	StageRoute   = "route"
+	StageReady   = "ready"
+)
+
+var TimeToRoute = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "time_to_route_seconds",
+	Help:      "Time from a GameServer observed Scheduled to its Service created, routes created and ingress-ready",
+	Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+}, []string{"namespace", "fleet", "backend", "stage"})
+
+// RouteDurations are the durations of each stage measured from the moment the GameServer was observed Scheduled.
+// Stages that were not observed are zero.
+type RouteDurations struct {
+	Service time.Duration
+	Route   time.Duration
+	Ready   time.Duration
+}
+
+// String encodes the durations as the value of octops.io/time-to-route, i.e. {"service":"1.2s","route":"1.5s"}.
+func (d RouteDurations) String() string {
+	values := map[string]string{}
+	for stage, duration := range d.stages() {
+		values[stage] = duration.Round(time.Millisecond).String()
+	}
+
+	b, _ := json.Marshal(values)
+	return string(b)
+}
+
+func (d RouteDurations) stages() map[string]time.Duration {
+	stages := map[string]time.Duration{}
+	if d.Service > 0 {
+		stages[StageService] = d.Service
+	}
+	if d.Route > 0 {
+		stages[StageRoute] = d.Route
+	}
+	if d.Ready > 0 {
+		stages[StageReady] = d.Ready
+	}
+
+	return stages
+}
+
+type routeTimestamps struct {
+	scheduled time.Time
+	service   time.Time
+	route     time.Time
+}
+
+// RouteTimer records the time of the state transitions the controller observes for each GameServer. Timestamps are
+// kept in memory, GameServers that were already past Scheduled when the controller started are not measured.
+type RouteTimer struct {
+	mu          sync.Mutex
+	gameservers map[types.UID]*routeTimestamps
+	now         func() time.Time
+}
+
+func NewRouteTimer() *RouteTimer {
+	return &RouteTimer{
+		gameservers: map[types.UID]*routeTimestamps{},
+		now:         time.Now,
+	}
+}
+
+// Scheduled starts measuring a GameServer in the Scheduled state that is not yet ingress-ready.
+func (t *RouteTimer) Scheduled(gs *agonesv1.GameServer) {
+	if gs.Status.State != agonesv1.GameServerStateScheduled || gs.Annotations[gameserver.OctopsAnnotationGameServerIngressReady] == "true" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.gameservers[gs.UID]; !ok {
+		t.gameservers[gs.UID] = &routeTimestamps{scheduled: t.now()}
+	}
+}
+
+// ServiceCreated records the first time the Service of the GameServer was observed.
+func (t *RouteTimer) ServiceCreated(gs *agonesv1.GameServer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ts, ok := t.gameservers[gs.UID]; ok && ts.service.IsZero() {
+		ts.service = t.now()
+	}
+}
+
+// RouteCreated records the first time every route of the GameServer was reconciled.
+func (t *RouteTimer) RouteCreated(gs *agonesv1.GameServer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ts, ok := t.gameservers[gs.UID]; ok && ts.route.IsZero() {
+		ts.route = t.now()
+	}
+}
+
+// Durations returns the durations of the GameServer measured up to now as the time it becomes ingress-ready. The
+// second value is false if the GameServer is not measured.
+func (t *RouteTimer) Durations(gs *agonesv1.GameServer) (RouteDurations, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ts, ok := t.gameservers[gs.UID]
+	if !ok {
+		return RouteDurations{}, false
+	}
+
+	d := RouteDurations{Ready: t.now().Sub(ts.scheduled)}
+	if !ts.service.IsZero() {
+		d.Service = ts.service.Sub(ts.scheduled)
+	}
+	if !ts.route.IsZero() {
+		d.Route = ts.route.Sub(ts.scheduled)
+	}
+
+	return d, true
+}
+
+// Ready observes the durations of a GameServer annotated with ingress-ready and stops measuring it.
+func (t *RouteTimer) Ready(gs *agonesv1.GameServer, d RouteDurations) {
+	fleet := gs.Labels[gameserver.AgonesFleetNameLabel]
+	backend := gameserver.RouterBackendsString(gs)
+	for stage, duration := range d.stages() {
+		TimeToRoute.WithLabelValues(gs.Namespace, fleet, backend, stage).Observe(duration.Seconds())
+	}
+
+	t.Forget(gs)
+}
+
+// Forget stops measuring a GameServer, e.g. when it is deleted before becoming ingress-ready.
+func (t *RouteTimer) Forget(gs *agonesv1.GameServer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.gameservers, gs.UID)
+}
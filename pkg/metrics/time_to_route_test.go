@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func Test_RouteTimer(t *testing.T) {
+	TimeToRoute.Reset()
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	timer := NewRouteTimer()
+	timer.now = func() time.Time { return now }
+
+	gs := &agonesv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "game-1",
+			Namespace: "default",
+			UID:       types.UID("game-1"),
+			Labels:    map[string]string{gameserver.AgonesFleetNameLabel: "fleet-1"},
+		},
+		Status: agonesv1.GameServerStatus{State: agonesv1.GameServerStateCreating},
+	}
+
+	// Only GameServers observed Scheduled are measured
+	timer.ServiceCreated(gs)
+	_, ok := timer.Durations(gs)
+	require.False(t, ok)
+
+	gs.Status.State = agonesv1.GameServerStateScheduled
+	timer.Scheduled(gs)
+
+	now = now.Add(time.Second)
+	timer.ServiceCreated(gs)
+	now = now.Add(time.Second)
+	timer.RouteCreated(gs)
+	// Later observations don't move the timestamps
+	now = now.Add(time.Second)
+	timer.Scheduled(gs)
+	timer.ServiceCreated(gs)
+
+	d, ok := timer.Durations(gs)
+	require.True(t, ok)
+	require.Equal(t, RouteDurations{Service: time.Second, Route: 2 * time.Second, Ready: 3 * time.Second}, d)
+	require.Equal(t, `{"ready":"3s","route":"2s","service":"1s"}`, d.String())
+
+	timer.Ready(gs, d)
+	require.Equal(t, 3, testutil.CollectAndCount(TimeToRoute))
+	require.Equal(t, uint64(1), histogramCount(t, TimeToRoute.WithLabelValues("default", "fleet-1", "ingress", StageReady)))
+
+	_, ok = timer.Durations(gs)
+	require.False(t, ok, "ready gameservers are no longer measured")
+}
+
+func histogramCount(t *testing.T, observer prometheus.Observer) uint64 {
+	m := &dto.Metric{}
+	require.NoError(t, observer.(prometheus.Metric).Write(m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+func Test_RouteTimer_IngressReady(t *testing.T) {
+	timer := NewRouteTimer()
+	gs := &agonesv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:         types.UID("game-1"),
+			Annotations: map[string]string{gameserver.OctopsAnnotationGameServerIngressReady: "true"},
+		},
+		Status: agonesv1.GameServerStatus{State: agonesv1.GameServerStateScheduled},
+	}
+
+	timer.Scheduled(gs)
+	_, ok := timer.Durations(gs)
+	require.False(t, ok)
+}
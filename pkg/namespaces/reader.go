@@ -0,0 +1,106 @@
+package namespaces
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reader reads objects from the reader of their namespace, usually a cache restricted to that namespace.
+// Namespaces are read from their own reader. Lists without a namespace are aggregated across every namespace.
+type Reader struct {
+	mu      sync.RWMutex
+	readers map[string]client.Reader
+}
+
+var _ client.Reader = &Reader{}
+
+func NewReader() *Reader {
+	return &Reader{readers: map[string]client.Reader{}}
+}
+
+func (r *Reader) Set(namespace string, reader client.Reader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.readers[namespace] = reader
+}
+
+func (r *Reader) Delete(namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.readers, namespace)
+}
+
+func (r *Reader) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	namespace := key.Namespace
+	if _, ok := obj.(*corev1.Namespace); ok {
+		namespace = key.Name
+	}
+
+	r.mu.RLock()
+	reader, ok := r.readers[namespace]
+	r.mu.RUnlock()
+
+	if !ok {
+		return notWatched(namespace, key.Name)
+	}
+
+	return reader.Get(ctx, key, obj, opts...)
+}
+
+func (r *Reader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+
+	r.mu.RLock()
+	readers := map[string]client.Reader{}
+	for ns, reader := range r.readers {
+		if len(listOpts.Namespace) == 0 || listOpts.Namespace == ns {
+			readers[ns] = reader
+		}
+	}
+	r.mu.RUnlock()
+
+	var items []runtime.Object
+	for ns, reader := range readers {
+		nsList, ok := list.DeepCopyObject().(client.ObjectList)
+		if !ok {
+			return errors.Errorf("unexpected list type %T", list)
+		}
+
+		if err := reader.List(ctx, nsList, opts...); err != nil {
+			return errors.Wrapf(err, "failed to list namespace %s", ns)
+		}
+
+		nsItems, err := meta.ExtractList(nsList)
+		if err != nil {
+			return err
+		}
+		items = append(items, nsItems...)
+	}
+
+	return meta.SetList(list, items)
+}
+
+// notWatched is a NotFound error, objects of a namespace that is no longer watched are gone for the controller and
+// requests for them are dropped instead of retried.
+func notWatched(namespace, name string) error {
+	return &k8serrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusNotFound,
+		Reason:  metav1.StatusReasonNotFound,
+		Details: &metav1.StatusDetails{Name: name},
+		Message: fmt.Sprintf("namespace %s is not watched by the controller", namespace),
+	}}
+}
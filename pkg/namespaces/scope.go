@@ -0,0 +1,201 @@
+package namespaces
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Handler starts and stops watching the resources of a namespace.
+type Handler interface {
+	AddNamespace(ctx context.Context, namespace string) error
+	RemoveNamespace(namespace string)
+}
+
+type Options struct {
+	// Namespaces is a static list of namespaces to watch.
+	Namespaces []string
+	// Selector selects Namespace objects by label. Namespaces are added and removed as their labels change.
+	Selector labels.Selector
+}
+
+// Enabled checks if the controller is restricted to a set of namespaces.
+func (o Options) Enabled() bool {
+	return len(o.Namespaces) > 0 || (o.Selector != nil && !o.Selector.Empty())
+}
+
+// Scope keeps the handlers in sync with the namespaces the controller is restricted to. Static namespaces are added
+// once on start. Namespaces matching the selector are watched and added or removed while the controller runs.
+type Scope struct {
+	logger   *logrus.Entry
+	client   kubernetes.Interface
+	options  Options
+	handlers []Handler
+	mu       sync.Mutex
+	active   map[string]bool
+	static   map[string]bool
+}
+
+func NewScope(client kubernetes.Interface, options Options) *Scope {
+	static := map[string]bool{}
+	for _, ns := range options.Namespaces {
+		if len(ns) > 0 {
+			static[ns] = true
+		}
+	}
+
+	return &Scope{
+		logger:  runtime.Logger().WithField("component", "namespaces"),
+		client:  client,
+		options: options,
+		active:  map[string]bool{},
+		static:  static,
+	}
+}
+
+// AddHandler registers a handler. Handlers are called in the order they were registered.
+func (s *Scope) AddHandler(handler Handler) {
+	s.handlers = append(s.handlers, handler)
+}
+
+// Contains checks if the namespace is currently watched.
+func (s *Scope) Contains(namespace string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.active[namespace]
+}
+
+// Namespaces returns the namespaces currently watched.
+func (s *Scope) Namespaces() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]string, 0, len(s.active))
+	for ns := range s.active {
+		result = append(result, ns)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Start adds the static namespaces and watches the namespaces matching the selector until the context is cancelled.
+func (s *Scope) Start(ctx context.Context) error {
+	for ns := range s.static {
+		if err := s.add(ctx, ns); err != nil {
+			return err
+		}
+	}
+
+	if s.options.Selector == nil || s.options.Selector.Empty() {
+		<-ctx.Done()
+		return nil
+	}
+
+	s.logger.Infof("watching namespaces matching %s", s.options.Selector.String())
+	factory := informers.NewSharedInformerFactoryWithOptions(s.client, 0, informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.LabelSelector = s.options.Selector.String()
+	}))
+
+	informer := factory.Core().V1().Namespaces().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.onNamespace(ctx, obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			s.onNamespace(ctx, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				s.remove(ns.Name)
+			}
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to watch namespaces")
+	}
+
+	factory.Start(ctx.Done())
+	<-ctx.Done()
+	factory.Shutdown()
+
+	return nil
+}
+
+// NeedLeaderElection returns false so standbys keep their informers warm.
+func (s *Scope) NeedLeaderElection() bool {
+	return false
+}
+
+// onNamespace adds namespaces matching the selector and removes the ones that stopped matching. The watch
+// also sends a delete event when labels stop matching, this check covers resyncs.
+func (s *Scope) onNamespace(ctx context.Context, obj interface{}) {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+
+	if !s.options.Selector.Matches(labels.Set(ns.Labels)) || ns.Status.Phase == corev1.NamespaceTerminating {
+		s.remove(ns.Name)
+		return
+	}
+
+	if err := s.add(ctx, ns.Name); err != nil {
+		s.logger.WithError(err).Errorf("failed to watch namespace %s", ns.Name)
+	}
+}
+
+func (s *Scope) add(ctx context.Context, namespace string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active[namespace] {
+		return nil
+	}
+
+	for i, handler := range s.handlers {
+		if err := handler.AddNamespace(ctx, namespace); err != nil {
+			for _, added := range s.handlers[:i] {
+				added.RemoveNamespace(namespace)
+			}
+			return errors.Wrapf(err, "failed to add namespace %s", namespace)
+		}
+	}
+
+	s.active[namespace] = true
+	s.logger.Infof("namespace %s added", namespace)
+
+	return nil
+}
+
+func (s *Scope) remove(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Namespaces listed explicitly are always watched
+	if !s.active[namespace] || s.static[namespace] {
+		return
+	}
+
+	for _, handler := range s.handlers {
+		handler.RemoveNamespace(namespace)
+	}
+
+	delete(s.active, namespace)
+	s.logger.Infof("namespace %s removed", namespace)
+}
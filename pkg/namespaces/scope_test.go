@@ -0,0 +1,125 @@
+package namespaces
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeHandler struct {
+	mu         sync.Mutex
+	namespaces map[string]bool
+}
+
+func (h *fakeHandler) AddNamespace(_ context.Context, namespace string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.namespaces[namespace] = true
+	return nil
+}
+
+func (h *fakeHandler) RemoveNamespace(namespace string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.namespaces, namespace)
+}
+
+func (h *fakeHandler) has(namespace string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.namespaces[namespace]
+}
+
+func newNamespace(name string, lbls map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: lbls}}
+}
+
+func Test_Options_Enabled(t *testing.T) {
+	require.False(t, Options{}.Enabled())
+	require.False(t, Options{Selector: labels.Everything()}.Enabled())
+	require.True(t, Options{Namespaces: []string{"games"}}.Enabled())
+	require.True(t, Options{Selector: labels.SelectorFromSet(labels.Set{"octops.io/ingress": "enabled"})}.Enabled())
+}
+
+func Test_Scope_Selector(t *testing.T) {
+	client := fake.NewClientset(
+		newNamespace("games", map[string]string{"octops.io/ingress": "enabled"}),
+		newNamespace("other", nil),
+	)
+
+	handler := &fakeHandler{namespaces: map[string]bool{}}
+	scope := NewScope(client, Options{
+		Namespaces: []string{"static"},
+		Selector:   labels.SelectorFromSet(labels.Set{"octops.io/ingress": "enabled"}),
+	})
+	scope.AddHandler(handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		assert.NoError(t, scope.Start(ctx))
+	}()
+
+	require.Eventually(t, func() bool { return handler.has("games") && handler.has("static") }, time.Second*5, time.Millisecond*20)
+	require.False(t, handler.has("other"))
+
+	// namespaces that start matching the selector are added at runtime
+	_, err := client.CoreV1().Namespaces().Create(ctx, newNamespace("arena", map[string]string{"octops.io/ingress": "enabled"}), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return handler.has("arena") }, time.Second*5, time.Millisecond*20)
+	require.Equal(t, []string{"arena", "games", "static"}, scope.Namespaces())
+
+	// the fake clientset does not filter watches, the update handler removes namespaces that stopped matching
+	_, err = client.CoreV1().Namespaces().Update(ctx, newNamespace("arena", nil), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return !handler.has("arena") }, time.Second*5, time.Millisecond*20)
+	require.True(t, scope.Contains("static"))
+}
+
+func Test_Reader(t *testing.T) {
+	scheme := k8sruntime.NewScheme()
+	require.NoError(t, agonesv1.AddToScheme(scheme))
+
+	newReader := func(namespace string) client.Reader {
+		return ctrlfake.NewClientBuilder().WithScheme(scheme).WithObjects(&agonesv1.GameServer{
+			ObjectMeta: metav1.ObjectMeta{Name: "game-1", Namespace: namespace},
+		}).Build()
+	}
+
+	reader := NewReader()
+	reader.Set("games", newReader("games"))
+	reader.Set("arena", newReader("arena"))
+
+	gs := &agonesv1.GameServer{}
+	require.NoError(t, reader.Get(context.Background(), client.ObjectKey{Namespace: "games", Name: "game-1"}, gs))
+	err := reader.Get(context.Background(), client.ObjectKey{Namespace: "other", Name: "game-1"}, gs)
+	require.EqualError(t, err, "namespace other is not watched by the controller")
+	require.True(t, k8serrors.IsNotFound(err))
+
+	list := &agonesv1.GameServerList{}
+	require.NoError(t, reader.List(context.Background(), list))
+	require.Len(t, list.Items, 2)
+
+	require.NoError(t, reader.List(context.Background(), list, client.InNamespace("arena")))
+	require.Len(t, list.Items, 1)
+
+	reader.Delete("arena")
+	require.NoError(t, reader.List(context.Background(), list))
+	require.Len(t, list.Items, 1)
+}
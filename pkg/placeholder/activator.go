@@ -0,0 +1,103 @@
+package placeholder
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ActiveLabel is set on the Pod of the replica that keeps the registry up to date. The placeholder Service selects
+// it, so requests never reach standbys that don't know the state of the GameServers.
+const ActiveLabel = "octops.io/placeholder-active"
+
+// Activator labels the Pod of the replica with ActiveLabel while it is the leader.
+type Activator struct {
+	logger    *logrus.Entry
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	dryRun    bool
+	retry     time.Duration
+}
+
+// NewActivator returns an Activator for the Pod. With dryRun the label is only validated by the API server.
+func NewActivator(client kubernetes.Interface, namespace, name string, dryRun bool) *Activator {
+	return &Activator{
+		logger:    runtime.Logger().WithFields(logrus.Fields{"component": "placeholder", "pod": namespace + "/" + name}),
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		dryRun:    dryRun,
+		retry:     time.Second * 5,
+	}
+}
+
+// Start labels the Pod, retrying until it succeeds, and removes the label when the context is cancelled.
+func (a *Activator) Start(ctx context.Context) error {
+	for {
+		err := a.label(ctx, true)
+		if err == nil {
+			break
+		}
+
+		a.logger.WithError(err).Error("failed to activate the placeholder backend")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(a.retry):
+		}
+	}
+
+	a.logger.Info("placeholder backend activated")
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if err := a.Deactivate(shutdownCtx); err != nil {
+		a.logger.WithError(err).Warn("failed to deactivate the placeholder backend")
+	}
+
+	return nil
+}
+
+// Deactivate removes the label, e.g. left behind by a previous container of the Pod that was the leader.
+func (a *Activator) Deactivate(ctx context.Context) error {
+	return a.label(ctx, false)
+}
+
+// NeedLeaderElection only activates the leader, the only replica that keeps the registry up to date.
+func (a *Activator) NeedLeaderElection() bool {
+	return true
+}
+
+func (a *Activator) label(ctx context.Context, active bool) error {
+	var value interface{}
+	if active {
+		value = "true"
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{ActiveLabel: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	options := metav1.PatchOptions{}
+	if a.dryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+
+	_, err = a.client.CoreV1().Pods(a.namespace).Patch(ctx, a.name, types.MergePatchType, patch, options)
+	return errors.Wrapf(err, "failed to label pod %s/%s", a.namespace, a.name)
+}
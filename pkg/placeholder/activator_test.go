@@ -0,0 +1,41 @@
+package placeholder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_Activator(t *testing.T) {
+	client := fake.NewClientset(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "controller-1",
+		Namespace: "octops-system",
+		Labels:    map[string]string{"app": "octops-ingress-controller", ActiveLabel: "true"},
+	}})
+	activator := NewActivator(client, "octops-system", "controller-1", false)
+
+	labels := func() map[string]string {
+		pod, err := client.CoreV1().Pods("octops-system").Get(context.Background(), "controller-1", metav1.GetOptions{})
+		require.NoError(t, err)
+		return pod.Labels
+	}
+
+	// A label left behind by a previous leader is removed before the replica is elected
+	require.NoError(t, activator.Deactivate(context.Background()))
+	require.Equal(t, map[string]string{"app": "octops-ingress-controller"}, labels())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- activator.Start(ctx) }()
+
+	require.Eventually(t, func() bool { return labels()[ActiveLabel] == "true" }, time.Second*5, time.Millisecond*10)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.NotContains(t, labels(), ActiveLabel)
+}
@@ -0,0 +1,164 @@
+package placeholder
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+)
+
+// State describes why a GameServer is not routable and drives the response served by the placeholder backend.
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateDraining State = "draining"
+	StateGone     State = "gone"
+
+	defaultGoneTTL = time.Minute * 10
+)
+
+// ServiceLabel is set on the ExternalName Services created in every namespace that routes Ingresses to the
+// placeholder backend.
+const ServiceLabel = "octops.io/placeholder-service"
+
+// Backend identifies the Service that exposes the placeholder server.
+// Routes for non-routable GameServers point to it instead of the GameServer Service.
+type Backend struct {
+	Name      string
+	Namespace string
+	Port      int32
+	// ClusterDomain is the DNS domain of the cluster, cluster.local if empty.
+	ClusterDomain string
+}
+
+// Host returns the DNS name of the placeholder Service.
+func (b Backend) Host() string {
+	domain := b.ClusterDomain
+	if len(domain) == 0 {
+		domain = "cluster.local"
+	}
+
+	return fmt.Sprintf("%s.%s.svc.%s", b.Name, b.Namespace, domain)
+}
+
+// StateFor maps the GameServer state to a placeholder state.
+// It returns false if the GameServer is expected to be routed to its own Service.
+func StateFor(gs *agonesv1.GameServer) (State, bool) {
+	if gs == nil {
+		return "", false
+	}
+
+	switch gs.Status.State {
+	case "",
+		agonesv1.GameServerStatePortAllocation,
+		agonesv1.GameServerStateCreating,
+		agonesv1.GameServerStateStarting:
+		return StateStarting, true
+	case agonesv1.GameServerStateShutdown,
+		agonesv1.GameServerStateUnhealthy,
+		agonesv1.GameServerStateError:
+		return StateDraining, true
+	}
+
+	return "", false
+}
+
+// Entry is the placeholder information kept for a single host and path.
+type Entry struct {
+	GameServer string `json:"gameserver"`
+	State      State  `json:"state"`
+	expiresAt  time.Time
+}
+
+// Registry keeps track of the hosts and paths claimed by GameServers that are not routable.
+// Entries for deleted GameServers expire after the configured TTL.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+	goneTTL time.Duration
+	now     func() time.Time
+}
+
+func NewRegistry(goneTTL time.Duration) *Registry {
+	if goneTTL <= 0 {
+		goneTTL = defaultGoneTTL
+	}
+
+	return &Registry{
+		entries: map[string]Entry{},
+		goneTTL: goneTTL,
+		now:     time.Now,
+	}
+}
+
+// Set records the state for every host and path the GameServer is reachable on.
+func (r *Registry) Set(gs *agonesv1.GameServer, state State) {
+	targets, err := gameserver.GetRouteTargets(gs)
+	if err != nil {
+		return
+	}
+
+	entry := Entry{GameServer: k8sutil.Namespaced(gs), State: state}
+	if state == StateGone {
+		entry.expiresAt = r.now().Add(r.goneTTL)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range targets {
+		r.entries[key(t.Host, t.Path)] = entry
+	}
+}
+
+// Remove drops the entries for a GameServer that became routable.
+func (r *Registry) Remove(gs *agonesv1.GameServer) {
+	targets, err := gameserver.GetRouteTargets(gs)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range targets {
+		delete(r.entries, key(t.Host, t.Path))
+	}
+}
+
+// Lookup finds the entry for a request. Path routing entries take precedence over domain routing entries.
+func (r *Registry) Lookup(host, path string) (Entry, bool) {
+	host = strings.ToLower(host)
+	candidates := []string{key(host, "/")}
+	if segment := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]; len(segment) > 0 {
+		candidates = append([]string{key(host, "/"+segment)}, candidates...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range candidates {
+		entry, ok := r.entries[k]
+		if !ok {
+			continue
+		}
+
+		if !entry.expiresAt.IsZero() && r.now().After(entry.expiresAt) {
+			delete(r.entries, k)
+			continue
+		}
+
+		return entry, true
+	}
+
+	return Entry{}, false
+}
+
+func key(host, path string) string {
+	return strings.ToLower(strings.TrimSpace(host)) + path
+}
@@ -0,0 +1,150 @@
+package placeholder
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatHTML Format = "html"
+)
+
+var htmlTemplate = template.Must(template.New("placeholder").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{ .Title }}</title>{{ if .RetryAfter }}<meta http-equiv="refresh" content="{{ .RetryAfter }}">{{ end }}</head>
+<body>
+<h1>{{ .Title }}</h1>
+<p data-state="{{ .State }}">{{ .Message }}</p>
+</body>
+</html>
+`))
+
+type Options struct {
+	// Addr is the TCP address the placeholder server binds to.
+	Addr string
+	// Format is the response body format, json or html.
+	Format Format
+	// RetryAfter is returned on the Retry-After header for starting and draining GameServers.
+	RetryAfter time.Duration
+}
+
+type response struct {
+	GameServer string `json:"gameserver,omitempty"`
+	State      State  `json:"state"`
+	Message    string `json:"message"`
+	RetryAfter int    `json:"retryAfter,omitempty"`
+}
+
+// Server answers requests for hosts and paths of GameServers that are not routable.
+type Server struct {
+	logger   *logrus.Entry
+	registry *Registry
+	options  Options
+}
+
+func NewServer(registry *Registry, options Options) (*Server, error) {
+	switch options.Format {
+	case "":
+		options.Format = FormatJSON
+	case FormatJSON, FormatHTML:
+	default:
+		return nil, errors.Errorf("placeholder format %q is not supported, use json or html", options.Format)
+	}
+
+	return &Server{
+		logger:   runtime.Logger().WithField("component", "placeholder"),
+		registry: registry,
+		options:  options,
+	}, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	resp := response{State: "unknown", Message: "Game server not found"}
+	status := http.StatusNotFound
+
+	if entry, ok := s.registry.Lookup(host, r.URL.Path); ok {
+		resp.GameServer = entry.GameServer
+		resp.State = entry.State
+
+		switch entry.State {
+		case StateStarting:
+			status = http.StatusServiceUnavailable
+			resp.Message = "Game server is starting"
+		case StateDraining:
+			status = http.StatusServiceUnavailable
+			resp.Message = "Game server is shutting down"
+		case StateGone:
+			status = http.StatusGone
+			resp.Message = "Game server is gone"
+		}
+	}
+
+	if status == http.StatusServiceUnavailable && s.options.RetryAfter > 0 {
+		resp.RetryAfter = int(s.options.RetryAfter.Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(resp.RetryAfter))
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	s.write(w, status, resp)
+}
+
+func (s *Server) write(w http.ResponseWriter, status int, resp response) {
+	if s.options.Format == FormatHTML {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_ = htmlTemplate.Execute(w, struct {
+			response
+			Title string
+		}{resp, http.StatusText(status)})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// Start runs the placeholder server until the context is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.options.Addr,
+		Handler:           s,
+		ReadHeaderTimeout: time.Second * 5,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	s.logger.Infof("placeholder server listening on %s", s.options.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(err, "placeholder server failed")
+	}
+
+	return nil
+}
+
+// NeedLeaderElection allows every replica to answer placeholder requests.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
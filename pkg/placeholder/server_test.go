@@ -0,0 +1,108 @@
+package placeholder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_ServeHTTP(t *testing.T) {
+	domainGS := newGameServer("game-1", map[string]string{
+		gameserver.OctopsAnnotationIngressMode:   string(gameserver.IngressRoutingModeDomain),
+		gameserver.OctopsAnnotationIngressDomain: "example.com",
+	})
+	pathGS := newGameServer("game-2", map[string]string{
+		gameserver.OctopsAnnotationIngressMode: string(gameserver.IngressRoutingModePath),
+		gameserver.OctopsAnnotationIngressFQDN: "servers.example.com",
+	})
+
+	registry := NewRegistry(time.Minute)
+	registry.Set(domainGS, StateStarting)
+	registry.Set(pathGS, StateGone)
+
+	server, err := NewServer(registry, Options{Format: FormatJSON, RetryAfter: time.Second * 5})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		host       string
+		path       string
+		status     int
+		state      State
+		retryAfter string
+	}{
+		{
+			name:       "domain routing mode starting",
+			host:       "game-1.example.com:443",
+			path:       "/",
+			status:     http.StatusServiceUnavailable,
+			state:      StateStarting,
+			retryAfter: "5",
+		},
+		{
+			name:   "path routing mode gone",
+			host:   "servers.example.com",
+			path:   "/game-2/index.html",
+			status: http.StatusGone,
+			state:  StateGone,
+		},
+		{
+			name:   "unknown game server",
+			host:   "servers.example.com",
+			path:   "/game-3",
+			status: http.StatusNotFound,
+			state:  "unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+
+			server.ServeHTTP(rec, req)
+
+			var body response
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			require.Equal(t, tc.status, rec.Code)
+			require.Equal(t, tc.state, body.State)
+			require.Equal(t, tc.retryAfter, rec.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func Test_RegistryGoneExpires(t *testing.T) {
+	gs := newGameServer("game-1", map[string]string{
+		gameserver.OctopsAnnotationIngressDomain: "example.com",
+	})
+
+	now := time.Now()
+	registry := NewRegistry(time.Minute)
+	registry.now = func() time.Time { return now }
+	registry.Set(gs, StateGone)
+
+	_, ok := registry.Lookup("game-1.example.com", "/")
+	require.True(t, ok)
+
+	now = now.Add(time.Minute * 2)
+	_, ok = registry.Lookup("game-1.example.com", "/")
+	require.False(t, ok)
+}
+
+func newGameServer(name string, annotations map[string]string) *agonesv1.GameServer {
+	return &agonesv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"context"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Enforcer checks the hosts of GameServers against the policy. The labels of the namespace are only read when a rule
+// uses a namespace selector.
+type Enforcer struct {
+	policy     *Policy
+	namespaces client.Reader
+	timeout    time.Duration
+}
+
+func NewEnforcer(policy *Policy, namespaces client.Reader) (*Enforcer, error) {
+	if policy.UsesSelectors() && namespaces == nil {
+		return nil, errors.New("domain policy uses namespace selectors but namespaces can't be read")
+	}
+
+	return &Enforcer{
+		policy:     policy,
+		namespaces: namespaces,
+		timeout:    time.Second * 5,
+	}, nil
+}
+
+// CheckHost returns a ViolationError if the namespace of the GameServer is not allowed to use the host.
+func (e *Enforcer) CheckHost(gs *agonesv1.GameServer, host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	return e.Check(ctx, gs.Namespace, host)
+}
+
+// Check returns a ViolationError for the first host the namespace is not allowed to use.
+func (e *Enforcer) Check(ctx context.Context, namespace string, hosts ...string) error {
+	var namespaceLabels map[string]string
+	if e.policy.UsesSelectors() {
+		ns := &corev1.Namespace{}
+		if err := e.namespaces.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+			return errors.Wrapf(err, "failed to get namespace %s to check the domain policy", namespace)
+		}
+		namespaceLabels = ns.Labels
+	}
+
+	for _, host := range hosts {
+		if err := e.policy.Check(host, namespace, namespaceLabels); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
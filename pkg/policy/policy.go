@@ -0,0 +1,190 @@
+package policy
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// Action is applied to hosts that are not claimed by any rule.
+type Action string
+
+const (
+	ActionAllow Action = "allow"
+	ActionDeny  Action = "deny"
+)
+
+// Rule claims domains for a set of namespaces. A domain claims the host itself and all of its subdomains, a leading
+// "*." is accepted and has the same meaning.
+type Rule struct {
+	Domains           []string              `json:"domains"`
+	Namespaces        []string              `json:"namespaces,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	selector labels.Selector
+}
+
+// Policy maps domains and FQDNs to the namespaces allowed to publish GameServers under them. The rules claiming the
+// longest domain that matches a host decide which namespaces can use it, so a team can own a subdomain of a domain
+// claimed by the platform.
+type Policy struct {
+	// Default is the action for hosts no rule claims. Hosts are allowed if empty.
+	Default Action `json:"default,omitempty"`
+	Rules   []Rule `json:"rules"`
+}
+
+// ViolationError is returned when a namespace uses a host it is not allowed to.
+type ViolationError struct {
+	Host      string
+	Namespace string
+	Domain    string
+}
+
+func (e *ViolationError) Error() string {
+	if len(e.Domain) == 0 {
+		return fmt.Sprintf("host %s is not claimed by any rule of the domain policy and the default action is deny", e.Host)
+	}
+
+	return fmt.Sprintf("namespace %s is not allowed to use host %s, domain %s is claimed by other namespaces", e.Namespace, e.Host, e.Domain)
+}
+
+// IsViolation checks if the error, or any error it wraps, is a policy violation.
+func IsViolation(err error) bool {
+	var v *ViolationError
+	return errors.As(err, &v)
+}
+
+// Load reads a policy from a YAML or JSON file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read domain policy %s", path)
+	}
+
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, errors.Wrap(err, "failed to parse domain policy")
+	}
+
+	if err := p.compile(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *Policy) compile() error {
+	switch p.Default {
+	case "":
+		p.Default = ActionAllow
+	case ActionAllow, ActionDeny:
+	default:
+		return errors.Errorf("invalid default action %q, must be allow or deny", p.Default)
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if len(rule.Domains) == 0 {
+			return errors.Errorf("rule %d must claim at least one domain", i)
+		}
+
+		for j, d := range rule.Domains {
+			rule.Domains[j] = normalize(d)
+			if len(rule.Domains[j]) == 0 {
+				return errors.Errorf("rule %d has an empty domain", i)
+			}
+		}
+
+		if rule.NamespaceSelector == nil {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(rule.NamespaceSelector)
+		if err != nil {
+			return errors.Wrapf(err, "rule %d has an invalid namespace selector", i)
+		}
+		rule.selector = selector
+	}
+
+	return nil
+}
+
+// UsesSelectors checks if the labels of the namespace are required to evaluate the policy.
+func (p *Policy) UsesSelectors() bool {
+	for _, rule := range p.Rules {
+		if rule.selector != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check returns a ViolationError if the namespace, with the given labels, is not allowed to use the host.
+func (p *Policy) Check(host, namespace string, namespaceLabels map[string]string) error {
+	host = normalize(host)
+
+	domain, rules := p.claims(host)
+	if len(rules) == 0 {
+		if p.Default == ActionDeny {
+			return &ViolationError{Host: host, Namespace: namespace}
+		}
+
+		return nil
+	}
+
+	for _, rule := range rules {
+		for _, ns := range rule.Namespaces {
+			if ns == namespace {
+				return nil
+			}
+		}
+
+		if rule.selector != nil && rule.selector.Matches(labels.Set(namespaceLabels)) {
+			return nil
+		}
+	}
+
+	return &ViolationError{Host: host, Namespace: namespace, Domain: domain}
+}
+
+// claims returns the longest domain claimed for the host and every rule claiming it.
+func (p *Policy) claims(host string) (string, []Rule) {
+	var domains []string
+	byDomain := map[string][]Rule{}
+	for _, rule := range p.Rules {
+		for _, d := range rule.Domains {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				if _, ok := byDomain[d]; !ok {
+					domains = append(domains, d)
+				}
+				byDomain[d] = append(byDomain[d], rule)
+			}
+		}
+	}
+
+	if len(domains) == 0 {
+		return "", nil
+	}
+
+	sort.Slice(domains, func(i, j int) bool { return len(domains[i]) > len(domains[j]) })
+
+	return domains[0], byDomain[domains[0]]
+}
+
+func normalize(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "*.")
+
+	return strings.TrimSuffix(domain, ".")
+}
@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+default: allow
+rules:
+  - domains: ["ourflagship.com"]
+    namespaces: ["flagship"]
+  - domains: ["*.blue.ourflagship.com"]
+    namespaces: ["team-blue"]
+  - domains: ["internal.example.com"]
+    namespaceSelector:
+      matchLabels:
+        edge: internal
+`
+
+func Test_Policy_Check(t *testing.T) {
+	p, err := Parse([]byte(testPolicy))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		host      string
+		namespace string
+		labels    map[string]string
+		allowed   bool
+	}{
+		{
+			name:      "owner of the domain",
+			host:      "game-1.ourflagship.com",
+			namespace: "flagship",
+			allowed:   true,
+		},
+		{
+			name:      "another namespace hijacking the domain",
+			host:      "game-1.ourflagship.com",
+			namespace: "team-red",
+			allowed:   false,
+		},
+		{
+			name:      "apex of the domain",
+			host:      "OurFlagship.com.",
+			namespace: "team-red",
+			allowed:   false,
+		},
+		{
+			name:      "longest claimed domain wins",
+			host:      "game-1.blue.ourflagship.com",
+			namespace: "team-blue",
+			allowed:   true,
+		},
+		{
+			name:      "owner of the parent domain can't use a delegated subdomain",
+			host:      "game-1.blue.ourflagship.com",
+			namespace: "flagship",
+			allowed:   false,
+		},
+		{
+			name:      "suffix is not a subdomain",
+			host:      "game-1.notourflagship.com",
+			namespace: "team-red",
+			allowed:   true,
+		},
+		{
+			name:      "namespace selector matches",
+			host:      "game-1.internal.example.com",
+			namespace: "ops",
+			labels:    map[string]string{"edge": "internal"},
+			allowed:   true,
+		},
+		{
+			name:      "namespace selector does not match",
+			host:      "game-1.internal.example.com",
+			namespace: "ops",
+			labels:    map[string]string{"edge": "public"},
+			allowed:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.Check(tc.host, tc.namespace, tc.labels)
+			if tc.allowed {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			require.True(t, IsViolation(err))
+		})
+	}
+}
+
+func Test_Policy_DefaultDeny(t *testing.T) {
+	p, err := Parse([]byte("default: deny\nrules:\n  - domains: [example.com]\n    namespaces: [games]\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, p.Check("game-1.example.com", "games", nil))
+	require.True(t, IsViolation(p.Check("game-1.unclaimed.com", "games", nil)))
+	require.False(t, p.UsesSelectors())
+}
+
+func Test_Parse_Invalid(t *testing.T) {
+	for _, data := range []string{
+		"default: maybe\nrules: []\n",
+		"rules:\n  - namespaces: [games]\n",
+		"rules:\n  - domains: [example.com]\n    namespaceSelector:\n      matchExpressions:\n        - key: edge\n          operator: Unknown\n",
+		"rules:\n  - domain: example.com\n",
+	} {
+		_, err := Parse([]byte(data))
+		require.Error(t, err, data)
+	}
+}
@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// WebhookPath is the path the validating webhook is served on.
+const WebhookPath = "/validate-octops-domain-policy"
+
+// ResolveFunc returns the GameServer with the annotations it inherits, like the ones set on its Namespace.
+type ResolveFunc func(ctx context.Context, gs *agonesv1.GameServer) (*agonesv1.GameServer, error)
+
+// Validator rejects Fleets and GameServers that would publish hosts their namespace is not allowed to use. The hosts
+// are computed the same way the controller does, so the request is refused before any route is created.
+type Validator struct {
+	enforcer *Enforcer
+	decoder  admission.Decoder
+	resolve  ResolveFunc
+}
+
+var _ admission.Handler = &Validator{}
+
+func NewValidator(enforcer *Enforcer, scheme *runtime.Scheme, resolve ResolveFunc) *Validator {
+	return &Validator{
+		enforcer: enforcer,
+		decoder:  admission.NewDecoder(scheme),
+		resolve:  resolve,
+	}
+}
+
+func (v *Validator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	gs := &agonesv1.GameServer{}
+	switch req.Kind.Kind {
+	case "GameServer":
+		if err := v.decoder.Decode(req, gs); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	case "Fleet":
+		fleet := &agonesv1.Fleet{}
+		if err := v.decoder.Decode(req, fleet); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		// The name of the GameServers is not known yet, only the domains matter to the policy
+		gs.Name = fleet.Name
+		gs.Namespace = fleet.Namespace
+		gs.Annotations = fleet.Spec.Template.Annotations
+	default:
+		return admission.Allowed("")
+	}
+
+	if len(gs.Namespace) == 0 {
+		gs.Namespace = req.Namespace
+	}
+
+	if v.resolve != nil {
+		resolved, err := v.resolve(ctx, gs)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		gs = resolved
+	}
+
+	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationIngressMode); !ok {
+		return admission.Allowed("")
+	}
+
+	targets, err := gameserver.GetRouteTargets(gs)
+	if err != nil {
+		// Invalid annotations are reported by the controller, they are not a policy matter
+		return admission.Allowed("")
+	}
+
+	hosts := make([]string, len(targets))
+	for i, t := range targets {
+		hosts[i] = t.Host
+	}
+
+	if err := v.enforcer.Check(ctx, gs.Namespace, hosts...); err != nil {
+		if IsViolation(err) {
+			return admission.Denied(err.Error())
+		}
+
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	return admission.Allowed("")
+}
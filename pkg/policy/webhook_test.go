@@ -0,0 +1,71 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func Test_Validator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, agonesv1.AddToScheme(scheme))
+
+	p, err := Parse([]byte(testPolicy))
+	require.NoError(t, err)
+
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-red"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "flagship"}},
+	).Build()
+
+	enforcer, err := NewEnforcer(p, reader)
+	require.NoError(t, err)
+
+	// Namespace defaults are resolved before the policy is checked
+	resolve := func(_ context.Context, gs *agonesv1.GameServer) (*agonesv1.GameServer, error) {
+		return gameserver.WithDefaults(gs, map[string]string{gameserver.OctopsAnnotationIngressDomain: "ourflagship.com"}), nil
+	}
+	validator := NewValidator(enforcer, scheme, resolve)
+
+	fleet := func(namespace string, annotations map[string]string) admission.Request {
+		f := &agonesv1.Fleet{
+			TypeMeta:   metav1.TypeMeta{Kind: "Fleet", APIVersion: "agones.dev/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "fleet", Namespace: namespace},
+		}
+		f.Spec.Template.Annotations = annotations
+
+		raw, err := json.Marshal(f)
+		require.NoError(t, err)
+
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Kind:      metav1.GroupVersionKind{Group: "agones.dev", Version: "v1", Kind: "Fleet"},
+			Namespace: namespace,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	domain := map[string]string{gameserver.OctopsAnnotationIngressMode: "domain"}
+
+	response := validator.Handle(context.Background(), fleet("team-red", domain))
+	require.False(t, response.Allowed)
+	require.Contains(t, response.Result.Message, "namespace team-red is not allowed to use host fleet.ourflagship.com")
+
+	response = validator.Handle(context.Background(), fleet("flagship", domain))
+	require.True(t, response.Allowed)
+
+	response = validator.Handle(context.Background(), fleet("team-red", nil))
+	require.True(t, response.Allowed, "fleets without routing are not checked")
+}
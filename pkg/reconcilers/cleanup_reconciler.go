@@ -0,0 +1,261 @@
+package reconcilers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+type ServiceCleanupStore interface {
+	GetService(name, namespace string) (*corev1.Service, error)
+	DeleteService(ctx context.Context, service *corev1.Service, options metav1.DeleteOptions) error
+}
+
+type IngressCleanupStore interface {
+	GetIngress(name, namespace string) (*networkingv1.Ingress, error)
+	DeleteIngress(ctx context.Context, ingress *networkingv1.Ingress, options metav1.DeleteOptions) error
+}
+
+type HTTPRouteCleanupStore interface {
+	GetHTTPRoute(name, namespace string) (*gatewayv1.HTTPRoute, error)
+	DeleteHTTPRoute(ctx context.Context, route *gatewayv1.HTTPRoute, options metav1.DeleteOptions) error
+}
+
+// CleanupReconciler deletes Services, Ingresses and HTTPRoutes created for a GameServer that no longer belong to it.
+// That happens when the GameServer opts out removing the ingress mode annotation or switches the router backend.
+type CleanupReconciler struct {
+	services  ServiceCleanupStore
+	ingresses IngressCleanupStore
+	routes    HTTPRouteCleanupStore
+	recorder  *record.EventRecorder
+}
+
+// NewCleanupReconciler returns a CleanupReconciler. The routes store is optional and must be nil
+// when the Gateway API backend is disabled.
+func NewCleanupReconciler(services ServiceCleanupStore, ingresses IngressCleanupStore, routes HTTPRouteCleanupStore, recorder *record.EventRecorder) *CleanupReconciler {
+	return &CleanupReconciler{
+		services:  services,
+		ingresses: ingresses,
+		routes:    routes,
+		recorder:  recorder,
+	}
+}
+
+// DesiredKinds returns the kinds of resources the GameServer must own according to its annotations.
+func DesiredKinds(gs *agonesv1.GameServer) map[string]bool {
+	kinds := map[string]bool{}
+	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationIngressMode); !ok {
+		return kinds
+	}
+
+	kinds[record.ServiceKind] = true
+	for _, backend := range gameserver.GetRouterBackends(gs) {
+		switch backend {
+		case gameserver.RouterBackendGateway:
+			kinds[record.HTTPRouteKind] = true
+		default:
+			kinds[record.IngressKind] = true
+		}
+	}
+
+	return kinds
+}
+
+// Reconcile deletes every resource owned by the GameServer that is not part of its desired kinds.
+// It returns the kinds that were deleted.
+func (r *CleanupReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "CleanupReconciler.Reconcile", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
+	if gs.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	desired := DesiredKinds(gs)
+	reason := cleanupReason(gs)
+
+	var deleted []string
+	if !desired[record.IngressKind] {
+		ok, err := r.cleanupIngress(ctx, gs, reason)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			deleted = append(deleted, record.IngressKind)
+		}
+	}
+
+	if !desired[record.HTTPRouteKind] && r.routes != nil {
+		ok, err := r.cleanupHTTPRoute(ctx, gs, reason)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			deleted = append(deleted, record.HTTPRouteKind)
+		}
+	}
+
+	if !desired[record.ServiceKind] {
+		ok, err := r.cleanupService(ctx, gs, reason)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			deleted = append(deleted, record.ServiceKind)
+		}
+	}
+
+	return deleted, nil
+}
+
+// Withdraw deletes the Ingress and HTTPRoute owned by the GameServer, whatever its annotations request. It is used
+// when the routes must not be published, e.g. after losing a hostname conflict. It returns the kinds that were deleted.
+func (r *CleanupReconciler) Withdraw(ctx context.Context, gs *agonesv1.GameServer, reason string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "CleanupReconciler.Withdraw", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
+	var deleted []string
+	ok, err := r.cleanupIngress(ctx, gs, reason)
+	if err != nil {
+		return deleted, err
+	}
+	if ok {
+		deleted = append(deleted, record.IngressKind)
+	}
+
+	if r.routes != nil {
+		ok, err := r.cleanupHTTPRoute(ctx, gs, reason)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			deleted = append(deleted, record.HTTPRouteKind)
+		}
+	}
+
+	return deleted, nil
+}
+
+func (r *CleanupReconciler) cleanupService(ctx context.Context, gs *agonesv1.GameServer, reason string) (bool, error) {
+	service, err := r.services.GetService(gs.Name, gs.Namespace)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error retrieving Service %s from namespace %s", gs.Name, gs.Namespace)
+	}
+
+	if !IsOwnedBy(service, gs) {
+		return false, nil
+	}
+
+	start := time.Now()
+	err = r.services.DeleteService(ctx, service, k8sutil.DeleteOptions(service))
+	observeWrite(record.ServiceKind, metrics.VerbDelete, start)
+	if err != nil {
+		// Deleted concurrently, nothing was cleaned up
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		r.recorder.RecordFailed(gs, record.ServiceKind, err)
+		return false, errors.Wrapf(err, "failed to delete service %s for gameserver %s", service.Name, gs.Name)
+	}
+
+	r.recorder.RecordDeleted(gs, record.ServiceKind, reason)
+	return true, nil
+}
+
+func (r *CleanupReconciler) cleanupIngress(ctx context.Context, gs *agonesv1.GameServer, reason string) (bool, error) {
+	ingress, err := r.ingresses.GetIngress(gs.Name, gs.Namespace)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error retrieving Ingress %s from namespace %s", gs.Name, gs.Namespace)
+	}
+
+	if !IsOwnedBy(ingress, gs) {
+		return false, nil
+	}
+
+	start := time.Now()
+	err = r.ingresses.DeleteIngress(ctx, ingress, k8sutil.DeleteOptions(ingress))
+	observeWrite(record.IngressKind, metrics.VerbDelete, start)
+	if err != nil {
+		// Deleted concurrently, nothing was cleaned up
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		r.recorder.RecordFailed(gs, record.IngressKind, err)
+		return false, errors.Wrapf(err, "failed to delete ingress %s for gameserver %s", ingress.Name, gs.Name)
+	}
+
+	r.recorder.RecordDeleted(gs, record.IngressKind, reason)
+	return true, nil
+}
+
+func (r *CleanupReconciler) cleanupHTTPRoute(ctx context.Context, gs *agonesv1.GameServer, reason string) (bool, error) {
+	route, err := r.routes.GetHTTPRoute(gs.Name, gs.Namespace)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, errors.Wrapf(err, "error retrieving HTTPRoute %s from namespace %s", gs.Name, gs.Namespace)
+	}
+
+	if !IsOwnedBy(route, gs) {
+		return false, nil
+	}
+
+	start := time.Now()
+	err = r.routes.DeleteHTTPRoute(ctx, route, k8sutil.DeleteOptions(route))
+	observeWrite(record.HTTPRouteKind, metrics.VerbDelete, start)
+	if err != nil {
+		// Deleted concurrently, nothing was cleaned up
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		r.recorder.RecordFailed(gs, record.HTTPRouteKind, err)
+		return false, errors.Wrapf(err, "failed to delete HTTPRoute %s for gameserver %s", route.Name, gs.Name)
+	}
+
+	r.recorder.RecordDeleted(gs, record.HTTPRouteKind, reason)
+	return true, nil
+}
+
+// IsOwnedBy checks if the object was created by the controller for the GameServer.
+// Both the agones.dev/gameserver label and the owner reference must match.
+func IsOwnedBy(obj metav1.Object, gs *agonesv1.GameServer) bool {
+	if obj.GetLabels()[gameserver.AgonesGameServerNameLabel] != gs.Name {
+		return false
+	}
+
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == gs.UID && ref.Kind == "GameServer" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func cleanupReason(gs *agonesv1.GameServer) string {
+	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationIngressMode); !ok {
+		return fmt.Sprintf("annotation %s not present", gameserver.OctopsAnnotationIngressMode)
+	}
+
+	return fmt.Sprintf("%s changed to %s", gameserver.OctopsAnnotationRouterBackend, gameserver.RouterBackendsString(gs))
+}
@@ -0,0 +1,179 @@
+package reconcilers
+
+import (
+	"context"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8srecord "k8s.io/client-go/tools/record"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func Test_CleanupReconciler(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		foreign     bool
+		stale       bool
+		expected    []string
+	}{
+		{
+			name: "deletes HTTPRoute when backend changes to ingress",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationIngressMode: string(gameserver.IngressRoutingModeDomain),
+			},
+			expected: []string{record.HTTPRouteKind},
+		},
+		{
+			name: "deletes ingress when backend changes to gateway",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationIngressMode:   string(gameserver.IngressRoutingModeDomain),
+				gameserver.OctopsAnnotationRouterBackend: string(gameserver.RouterBackendGateway),
+			},
+			expected: []string{record.IngressKind},
+		},
+		{
+			name:        "deletes everything when annotation is removed",
+			annotations: map[string]string{},
+			expected:    []string{record.IngressKind, record.HTTPRouteKind, record.ServiceKind},
+		},
+		{
+			name:        "ignores resources owned by another gameserver",
+			annotations: map[string]string{},
+			foreign:     true,
+			expected:    nil,
+		},
+		{
+			name:        "ignores resources deleted concurrently",
+			annotations: map[string]string{},
+			stale:       true,
+			expected:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gs := newGameServer("game-1", "default", tc.annotations)
+			gs.UID = types.UID("gs-uid")
+
+			owner := gs
+			if tc.foreign {
+				owner = newGameServer("game-1", "default", nil)
+				owner.UID = types.UID("another-uid")
+			}
+
+			store := newFakeCleanupStore(owner)
+			store.stale = tc.stale
+			fakeRecorder := k8srecord.NewFakeRecorder(10)
+			reconciler := NewCleanupReconciler(store, store, store, record.NewEventRecorder(fakeRecorder))
+
+			deleted, err := reconciler.Reconcile(context.Background(), gs)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, deleted)
+			require.Len(t, fakeRecorder.Events, len(tc.expected))
+			for _, kind := range tc.expected {
+				require.True(t, store.deleted[kind])
+			}
+		})
+	}
+}
+
+func Test_CleanupReconciler_Withdraw(t *testing.T) {
+	gs := newGameServer("game-1", "default", map[string]string{
+		gameserver.OctopsAnnotationIngressMode:   string(gameserver.IngressRoutingModeDomain),
+		gameserver.OctopsAnnotationRouterBackend: "ingress,gateway",
+	})
+	gs.UID = types.UID("gs-uid")
+
+	store := newFakeCleanupStore(gs)
+	reconciler := NewCleanupReconciler(store, store, store, record.NewEventRecorder(k8srecord.NewFakeRecorder(10)))
+
+	deleted, err := reconciler.Withdraw(context.Background(), gs, "host conflict")
+	require.NoError(t, err)
+	require.Equal(t, []string{record.IngressKind, record.HTTPRouteKind}, deleted)
+	require.False(t, store.deleted[record.ServiceKind])
+
+	deleted, err = reconciler.Withdraw(context.Background(), gs, "host conflict")
+	require.NoError(t, err)
+	require.Empty(t, deleted)
+}
+
+type fakeCleanupStore struct {
+	service *corev1.Service
+	ingress *networkingv1.Ingress
+	route   *gatewayv1.HTTPRoute
+	deleted map[string]bool
+	// stale caches still return objects that were already deleted
+	stale bool
+}
+
+func newFakeCleanupStore(owner *agonesv1.GameServer) *fakeCleanupStore {
+	meta := func() metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:            owner.Name,
+			Namespace:       owner.Namespace,
+			Labels:          map[string]string{gameserver.AgonesGameServerNameLabel: owner.Name},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(owner, agonesv1.SchemeGroupVersion.WithKind("GameServer"))},
+		}
+	}
+
+	return &fakeCleanupStore{
+		service: &corev1.Service{ObjectMeta: meta()},
+		ingress: &networkingv1.Ingress{ObjectMeta: meta()},
+		route:   &gatewayv1.HTTPRoute{ObjectMeta: meta()},
+		deleted: map[string]bool{},
+	}
+}
+
+func (s *fakeCleanupStore) GetService(name, _ string) (*corev1.Service, error) {
+	if s.deleted[record.ServiceKind] {
+		return nil, k8serrors.NewNotFound(corev1.Resource("services"), name)
+	}
+	return s.service, nil
+}
+
+func (s *fakeCleanupStore) DeleteService(_ context.Context, obj *corev1.Service, _ metav1.DeleteOptions) error {
+	if s.stale {
+		return k8serrors.NewNotFound(corev1.Resource("services"), obj.Name)
+	}
+	s.deleted[record.ServiceKind] = true
+	return nil
+}
+
+func (s *fakeCleanupStore) GetIngress(name, _ string) (*networkingv1.Ingress, error) {
+	if s.deleted[record.IngressKind] {
+		return nil, k8serrors.NewNotFound(networkingv1.Resource("ingresses"), name)
+	}
+	return s.ingress, nil
+}
+
+func (s *fakeCleanupStore) DeleteIngress(_ context.Context, obj *networkingv1.Ingress, _ metav1.DeleteOptions) error {
+	if s.stale {
+		return k8serrors.NewNotFound(networkingv1.Resource("ingresses"), obj.Name)
+	}
+	s.deleted[record.IngressKind] = true
+	return nil
+}
+
+func (s *fakeCleanupStore) GetHTTPRoute(name, _ string) (*gatewayv1.HTTPRoute, error) {
+	if s.deleted[record.HTTPRouteKind] {
+		return nil, k8serrors.NewNotFound(gatewayv1.Resource("httproutes"), name)
+	}
+	return s.route, nil
+}
+
+func (s *fakeCleanupStore) DeleteHTTPRoute(_ context.Context, obj *gatewayv1.HTTPRoute, _ metav1.DeleteOptions) error {
+	if s.stale {
+		return k8serrors.NewNotFound(gatewayv1.Resource("httproutes"), obj.Name)
+	}
+	s.deleted[record.HTTPRouteKind] = true
+	return nil
+}
@@ -1,7 +1,23 @@
 package reconcilers
 
-import networkingv1 "k8s.io/api/networking/v1"
+import (
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
 
 var (
 	defaultPathType = networkingv1.PathTypePrefix
 )
+
+// HostCheck refuses hosts a GameServer is not allowed to be published on, e.g. by the domain policy.
+type HostCheck func(gs *agonesv1.GameServer, host string) error
+
+func checkHost(gs *agonesv1.GameServer, host string, checks []HostCheck) error {
+	for _, check := range checks {
+		if err := check(gs, host); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
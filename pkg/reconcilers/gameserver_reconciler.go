@@ -2,68 +2,119 @@ package reconcilers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
 	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
 	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 	"github.com/pkg/errors"
-	"k8s.io/client-go/util/retry"
 )
 
 type GameServerStore interface {
-	UpdateGameServer(ctx context.Context, gs *agonesv1.GameServer) (*agonesv1.GameServer, error)
+	PatchGameServerAnnotations(ctx context.Context, gs *agonesv1.GameServer, annotations map[string]string, remove ...string) (*agonesv1.GameServer, error)
 	GetGameServer(ctx context.Context, name, namespace string) (*agonesv1.GameServer, error)
 }
 
 type GameServerReconciler struct {
 	store    GameServerStore
 	recorder *record.EventRecorder
+	timer    *metrics.RouteTimer
 }
 
-func NewGameServerReconciler(store GameServerStore, recorder *record.EventRecorder) *GameServerReconciler {
-	return &GameServerReconciler{store: store, recorder: recorder}
+type GameServerReconcilerOption func(r *GameServerReconciler)
+
+// WithRouteTimer annotates GameServers measured by the timer with octops.io/time-to-route when they become
+// ingress-ready.
+func WithRouteTimer(timer *metrics.RouteTimer) GameServerReconcilerOption {
+	return func(r *GameServerReconciler) {
+		r.timer = timer
+	}
+}
+
+func NewGameServerReconciler(store GameServerStore, recorder *record.EventRecorder, opts ...GameServerReconcilerOption) *GameServerReconciler {
+	r := &GameServerReconciler{store: store, recorder: recorder}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
-func (r *GameServerReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer) (*agonesv1.GameServer, error) {
+// Reconcile publishes the status of each router backend on the GameServer. The GameServer is only annotated
+// with octops.io/ingress-ready=true when all the requested backends are ready.
+func (r *GameServerReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer, statuses map[gameserver.RouterBackend]gameserver.BackendStatus) (_ *agonesv1.GameServer, err error) {
+	ctx, span := tracing.Start(ctx, "GameServerReconciler.Reconcile", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
 	must, err := r.MustReconcile(gs)
 	if err != nil {
+		recordResult(gs, record.GameServerKind, metrics.ResultFailed)
 		return nil, errors.Wrapf(err, "failed to reconcile gameserver %s/%s", gs.Namespace, gs.Name)
 	}
 
-	if must == false {
+	status, err := backendStatus(statuses)
+	if err != nil {
+		recordResult(gs, record.GameServerKind, metrics.ResultFailed)
+		return nil, errors.Wrapf(err, "failed to encode backend status for gameserver %s/%s", gs.Namespace, gs.Name)
+	}
+
+	ready := allReady(statuses)
+	hostnames := gameserver.HostnameAnnotations(gs)
+	stale := gameserver.StaleHostnameAnnotations(gs)
+	if annotationsUpToDate(gs, must, ready, status, hostnames, stale) {
+		recordResult(gs, record.GameServerKind, metrics.ResultUnchanged)
 		return gs, nil
 	}
 
-	return r.reconcile(ctx, gs)
+	return r.reconcile(ctx, gs, status, ready, hostnames, stale)
 }
 
-func (r *GameServerReconciler) reconcile(ctx context.Context, gs *agonesv1.GameServer) (*agonesv1.GameServer, error) {
-	result := &agonesv1.GameServer{}
+// reconcile annotates the GameServer with its status and, if it is published using a shortened hostname label, with
+// the original and the shortened label. The stale hostname annotations are removed.
+func (r *GameServerReconciler) reconcile(ctx context.Context, gs *agonesv1.GameServer, status string, ready bool, hostnames map[string]string, stale []string) (*agonesv1.GameServer, error) {
+	annotations := map[string]string{
+		gameserver.OctopsAnnotationGameServerIngressReady: strconv.FormatBool(ready),
+	}
+	if len(status) > 0 {
+		annotations[gameserver.OctopsAnnotationRouterBackendStatus] = status
+	}
+	for k, v := range hostnames {
+		annotations[k] = v
+	}
 
-	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		g, err := r.store.GetGameServer(ctx, gs.Name, gs.Namespace)
-		if err != nil {
-			return errors.Wrapf(err, "failed to retrieve gameserver %s", k8sutil.Namespaced(gs))
+	var durations metrics.RouteDurations
+	var measured bool
+	if ready && r.timer != nil {
+		durations, measured = r.timer.Durations(gs)
+		if measured {
+			annotations[gameserver.OctopsAnnotationTimeToRoute] = durations.String()
 		}
+	}
 
-		deepCopy := g.DeepCopy()
-		deepCopy.Annotations[gameserver.OctopsAnnotationGameServerIngressReady] = "true"
-
-		result, err = r.store.UpdateGameServer(ctx, deepCopy)
-		if err != nil {
-			return errors.Wrapf(err, "failed to update gameserver %s", k8sutil.Namespaced(deepCopy))
-		}
+	// A merge patch only touches the annotations, so concurrent updates of other fields never conflict
+	start := time.Now()
+	result, err := r.store.PatchGameServerAnnotations(ctx, gs, annotations, stale...)
+	observeWrite(record.GameServerKind, metrics.VerbPatch, start)
+	if err != nil {
+		recordResult(gs, record.GameServerKind, metrics.ResultFailed)
+		return nil, errors.Wrapf(err, "failed to update gameserver %s", k8sutil.Namespaced(gs))
+	}
 
-		return nil
-	})
+	recordResult(gs, record.GameServerKind, metrics.ResultUpdated)
+	if measured {
+		r.timer.Ready(gs, durations)
+	}
 
-	if err != nil {
-		return nil, err
+	if !ready {
+		r.recorder.RecordEvent(result, fmt.Sprintf("GameServer annotated with %s=%s", gameserver.OctopsAnnotationRouterBackendStatus, status))
+		return result, nil
 	}
 
 	r.recorder.RecordEvent(result, fmt.Sprintf("GameServer annotated with %s", gameserver.OctopsAnnotationGameServerIngressReady))
@@ -71,6 +122,52 @@ func (r *GameServerReconciler) reconcile(ctx context.Context, gs *agonesv1.GameS
 	return result, nil
 }
 
+// backendStatus encodes the status of each router backend, i.e. {"gateway":"ready","ingress":"ready"}.
+func backendStatus(statuses map[gameserver.RouterBackend]gameserver.BackendStatus) (string, error) {
+	if len(statuses) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(statuses)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// annotationsUpToDate checks if the GameServer already has the annotations reconcile would write, so it doesn't have
+// to be patched. MustReconcile is false only if the GameServer is annotated with octops.io/ingress-ready=true, hence
+// must != ready means the readiness is unchanged: ready and already annotated, or still not ready.
+func annotationsUpToDate(gs *agonesv1.GameServer, must, ready bool, status string, hostnames map[string]string, stale []string) bool {
+	return must != ready &&
+		gs.Annotations[gameserver.OctopsAnnotationRouterBackendStatus] == status &&
+		hasAnnotations(gs, hostnames) &&
+		len(stale) == 0
+}
+
+// hasAnnotations checks if the GameServer already has all the annotations.
+func hasAnnotations(gs *agonesv1.GameServer, annotations map[string]string) bool {
+	for k, v := range annotations {
+		if gs.Annotations[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// allReady returns true if no backend was requested or all of them are ready.
+func allReady(statuses map[gameserver.RouterBackend]gameserver.BackendStatus) bool {
+	for _, status := range statuses {
+		if status != gameserver.BackendStatusReady {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (r *GameServerReconciler) recordDeprecatedAnnotations(gs *agonesv1.GameServer) {
 	if _, ok := gs.Annotations[gameserver.OctopsAnnotationIngressClassNameLegacy]; ok {
 
@@ -87,3 +87,74 @@ func Test_MustReconcile(t *testing.T) {
 		})
 	}
 }
+
+func Test_annotationsUpToDate(t *testing.T) {
+	status := `{"ingress":"ready"}`
+	testCase := []struct {
+		name        string
+		annotations map[string]string
+		ready       bool
+		hostnames   map[string]string
+		stale       []string
+		expected    bool
+	}{
+		{
+			name: "Should be up to date when ready and annotated",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationGameServerIngressReady: "true",
+				gameserver.OctopsAnnotationRouterBackendStatus:    status,
+			},
+			ready:    true,
+			expected: true,
+		},
+		{
+			name: "Should not be up to date when ready but not annotated",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationRouterBackendStatus: status,
+			},
+			ready:    true,
+			expected: false,
+		},
+		{
+			name: "Should not be up to date when the status changed",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationGameServerIngressReady: "true",
+				gameserver.OctopsAnnotationRouterBackendStatus:    `{"ingress":"failed"}`,
+			},
+			ready:    true,
+			expected: false,
+		},
+		{
+			name: "Should not be up to date when the hostname annotations are missing",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationGameServerIngressReady: "true",
+				gameserver.OctopsAnnotationRouterBackendStatus:    status,
+			},
+			ready:     true,
+			hostnames: map[string]string{gameserver.OctopsAnnotationHostnameLabel: "label"},
+			expected:  false,
+		},
+		{
+			name: "Should not be up to date with stale hostname annotations",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationGameServerIngressReady: "true",
+				gameserver.OctopsAnnotationRouterBackendStatus:    status,
+				gameserver.OctopsAnnotationHostnameLabel:          "label",
+			},
+			ready:    true,
+			stale:    []string{gameserver.OctopsAnnotationHostnameLabel},
+			expected: false,
+		},
+	}
+	reconciler := &GameServerReconciler{}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			gs := &agonesv1.GameServer{}
+			gs.Annotations = tc.annotations
+
+			must, err := reconciler.MustReconcile(gs)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, annotationsUpToDate(gs, must, tc.ready, status, tc.hostnames, tc.stale))
+		})
+	}
+}
@@ -1,12 +1,11 @@
 package reconcilers
 
 import (
-	"fmt"
 	"strings"
-	"text/template"
 
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
 	"github.com/pkg/errors"
 	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
@@ -39,7 +38,9 @@ func WithHTTPRouteParentRef() HTTPRouteOption {
 	}
 }
 
-func WithHTTPRouteRules(mode gameserver.IngressRoutingMode) HTTPRouteOption {
+// WithHTTPRouteRules sets the hostnames and rules of the HTTPRoute for the routing mode. Every hostname is verified
+// using the checks.
+func WithHTTPRouteRules(mode gameserver.IngressRoutingMode, checks ...HostCheck) HTTPRouteOption {
 	return func(gs *agonesv1.GameServer, route *gatewayv1.HTTPRoute) error {
 		port := gatewayv1.PortNumber(gameserver.GetGameServerPort(gs).Port)
 		backendRef := gatewayv1.HTTPBackendRef{
@@ -64,8 +65,12 @@ func WithHTTPRouteRules(mode gameserver.IngressRoutingMode) HTTPRouteOption {
 			if len(fqdns) == 0 {
 				return errors.Errorf(gameserver.ErrGameServerAnnotationEmpty, gs.Namespace, gs.Name, gameserver.OctopsAnnotationIngressFQDN)
 			}
-			for _, f := range strings.Split(fqdns, ",") {
-				hostnames = append(hostnames, gatewayv1.Hostname(strings.TrimSpace(f)))
+			hosts, err := gameserver.FQDNs(gs, fqdns)
+			if err != nil {
+				return err
+			}
+			for _, host := range hosts {
+				hostnames = append(hostnames, gatewayv1.Hostname(host))
 			}
 			pathValue = "/" + gs.Name
 
@@ -78,7 +83,11 @@ func WithHTTPRouteRules(mode gameserver.IngressRoutingMode) HTTPRouteOption {
 				return errors.Errorf(gameserver.ErrGameServerAnnotationEmpty, gs.Namespace, gs.Name, gameserver.OctopsAnnotationIngressDomain)
 			}
 			for _, d := range strings.Split(domains, ",") {
-				hostnames = append(hostnames, gatewayv1.Hostname(fmt.Sprintf("%s.%s", gs.Name, strings.TrimSpace(d))))
+				host, err := gameserver.Hostname(gs, d)
+				if err != nil {
+					return err
+				}
+				hostnames = append(hostnames, gatewayv1.Hostname(host))
 			}
 			pathValue = "/"
 
@@ -86,6 +95,12 @@ func WithHTTPRouteRules(mode gameserver.IngressRoutingMode) HTTPRouteOption {
 			return errors.Errorf("routing mode '%s' from gameserver %s/%s is not recognised", mode, gs.Namespace, gs.Name)
 		}
 
+		for _, hostname := range hostnames {
+			if err := checkHost(gs, string(hostname), checks); err != nil {
+				return err
+			}
+		}
+
 		route.Spec.Hostnames = hostnames
 		route.Spec.Rules = []gatewayv1.HTTPRouteRule{
 			{
@@ -105,6 +120,36 @@ func WithHTTPRouteRules(mode gameserver.IngressRoutingMode) HTTPRouteOption {
 	}
 }
 
+// WithHTTPRoutePlaceholderBackend points every rule to the placeholder Service. It must run after WithHTTPRouteRules.
+// The ReferenceGrantReconciler allows it when the placeholder Service lives in a different namespace.
+func WithHTTPRoutePlaceholderBackend(backend placeholder.Backend, state placeholder.State) HTTPRouteOption {
+	return func(gs *agonesv1.GameServer, route *gatewayv1.HTTPRoute) error {
+		port := gatewayv1.PortNumber(backend.Port)
+		ref := gatewayv1.BackendObjectReference{
+			Name: gatewayv1.ObjectName(backend.Name),
+			Port: &port,
+		}
+
+		if len(backend.Namespace) > 0 && backend.Namespace != gs.Namespace {
+			ns := gatewayv1.Namespace(backend.Namespace)
+			ref.Namespace = &ns
+		}
+
+		for i := range route.Spec.Rules {
+			route.Spec.Rules[i].BackendRefs = []gatewayv1.HTTPBackendRef{
+				{
+					BackendRef: gatewayv1.BackendRef{
+						BackendObjectReference: ref,
+					},
+				},
+			}
+		}
+
+		route.Annotations[gameserver.OctopsAnnotationPlaceholderState] = string(state)
+		return nil
+	}
+}
+
 func WithCustomHTTPRouteAnnotations() HTTPRouteOption {
 	return func(gs *agonesv1.GameServer, route *gatewayv1.HTTPRoute) error {
 		annotations := route.Annotations
@@ -124,14 +169,6 @@ func WithCustomHTTPRouteAnnotations() HTTPRouteOption {
 
 func WithCustomHTTPRouteAnnotationsTemplate() HTTPRouteOption {
 	return func(gs *agonesv1.GameServer, route *gatewayv1.HTTPRoute) error {
-		data := struct {
-			Name string
-			Port int32
-		}{
-			Name: gs.Name,
-			Port: gameserver.GetGameServerPort(gs).Port,
-		}
-
 		annotations := route.Annotations
 		for k, v := range gs.Annotations {
 			if strings.HasPrefix(k, gameserver.OctopsAnnotationCustomPrefix) {
@@ -140,18 +177,16 @@ func WithCustomHTTPRouteAnnotationsTemplate() HTTPRouteOption {
 					return errors.New("custom annotation does not contain a suffix")
 				}
 
-				if !strings.Contains(v, "{{") || !strings.Contains(v, "}}") {
+				if !gameserver.IsTemplate(v) {
 					continue
 				}
 
-				t, err := template.New("gs").Parse(v)
+				parsed, err := gameserver.ExecuteTemplate(gs, v)
 				if err != nil {
-					return errors.Errorf("%s:%s does not contain a valid template", custom, v)
+					return errors.Wrapf(err, "%s:%s does not contain a valid template", custom, v)
 				}
 
-				b := new(strings.Builder)
-				_ = t.Execute(b, data)
-				if parsed := b.String(); len(parsed) > 0 {
+				if len(parsed) > 0 {
 					annotations[custom] = parsed
 				}
 			}
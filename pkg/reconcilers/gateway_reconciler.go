@@ -2,11 +2,15 @@ package reconcilers
 
 import (
 	"context"
+	"time"
 
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
 	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
 	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 	"github.com/pkg/errors"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,72 +19,158 @@ import (
 
 type HTTPRouteStore interface {
 	CreateHTTPRoute(ctx context.Context, route *gatewayv1.HTTPRoute, options metav1.CreateOptions) (*gatewayv1.HTTPRoute, error)
+	UpdateHTTPRoute(ctx context.Context, route *gatewayv1.HTTPRoute, options metav1.UpdateOptions) (*gatewayv1.HTTPRoute, error)
 	GetHTTPRoute(name, namespace string) (*gatewayv1.HTTPRoute, error)
 }
 
 type GatewayReconciler struct {
 	store    HTTPRouteStore
 	recorder *record.EventRecorder
+	checks   []HostCheck
 }
 
-func NewGatewayReconciler(store HTTPRouteStore, recorder *record.EventRecorder) *GatewayReconciler {
+// NewGatewayReconciler returns a reconciler that refuses to publish hostnames rejected by any of the checks.
+func NewGatewayReconciler(store HTTPRouteStore, recorder *record.EventRecorder, checks ...HostCheck) *GatewayReconciler {
 	return &GatewayReconciler{
 		store:    store,
 		recorder: recorder,
+		checks:   checks,
 	}
 }
 
-func (r *GatewayReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer) (*gatewayv1.HTTPRoute, bool, error) {
+func (r *GatewayReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer) (_ *gatewayv1.HTTPRoute, _ bool, err error) {
+	ctx, span := tracing.Start(ctx, "GatewayReconciler.Reconcile", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
 	route, err := r.store.GetHTTPRoute(gs.Name, gs.Namespace)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			return r.reconcileNotFound(ctx, gs)
+			return r.reconcileNotFound(ctx, gs, r.options(gs)...)
 		}
 
+		recordResult(gs, record.HTTPRouteKind, metrics.ResultFailed)
 		return nil, false, errors.Wrapf(err, "error retrieving HTTPRoute %s from namespace %s", gs.Name, gs.Namespace)
 	}
 
+	// HTTPRoutes created while the GameServer was not routable point to the placeholder backend
+	if _, ok := route.Annotations[gameserver.OctopsAnnotationPlaceholderState]; ok {
+		result, err := r.reconcileUpdate(ctx, gs, route, "routed to gameserver", r.options(gs)...)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return result, true, nil
+	}
+
+	recordResult(gs, record.HTTPRouteKind, metrics.ResultUnchanged)
 	return route, false, nil
 }
 
-func (r *GatewayReconciler) reconcileNotFound(ctx context.Context, gs *agonesv1.GameServer) (*gatewayv1.HTTPRoute, bool, error) {
-	r.recorder.RecordCreating(gs, record.HTTPRouteKind)
+// ReconcilePlaceholder makes the HTTPRoute send traffic to the placeholder backend.
+// HTTPRoutes are only created for starting GameServers, draining GameServers only have existing HTTPRoutes updated.
+func (r *GatewayReconciler) ReconcilePlaceholder(ctx context.Context, gs *agonesv1.GameServer, backend placeholder.Backend, state placeholder.State) (_ *gatewayv1.HTTPRoute, err error) {
+	ctx, span := tracing.Start(ctx, "GatewayReconciler.ReconcilePlaceholder", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
 
-	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationTerminateTLS); ok {
-		r.recorder.RecordWarning(gs, record.HTTPRouteKind, "annotation octops.io/terminate-tls has no effect in gateway mode — configure TLS on the Gateway listener instead")
+	opts := append(r.options(gs), WithHTTPRoutePlaceholderBackend(backend, state))
+
+	route, err := r.store.GetHTTPRoute(gs.Name, gs.Namespace)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "error retrieving HTTPRoute %s from namespace %s", gs.Name, gs.Namespace)
+		}
+
+		if state != placeholder.StateStarting {
+			return nil, nil
+		}
+
+		result, _, err := r.reconcileNotFound(ctx, gs, opts...)
+		return result, err
 	}
-	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationIssuerName); ok {
-		r.recorder.RecordWarning(gs, record.HTTPRouteKind, "annotation octops.io/issuer-tls-name has no effect in gateway mode — use a cert-manager Certificate resource linked to the Gateway listener instead")
+
+	if route.Annotations[gameserver.OctopsAnnotationPlaceholderState] == string(state) {
+		return route, nil
 	}
 
+	return r.reconcileUpdate(ctx, gs, route, "routed to placeholder backend", opts...)
+}
+
+// Desired returns the HTTPRoute the reconciler creates for a routable GameServer.
+func (r *GatewayReconciler) Desired(gs *agonesv1.GameServer) (*gatewayv1.HTTPRoute, error) {
+	return newHTTPRoute(gs, r.options(gs)...)
+}
+
+func (r *GatewayReconciler) options(gs *agonesv1.GameServer) []HTTPRouteOption {
 	mode := gameserver.GetIngressRoutingMode(gs)
 
-	opts := []HTTPRouteOption{
+	return []HTTPRouteOption{
 		WithCustomHTTPRouteAnnotations(),
 		WithCustomHTTPRouteAnnotationsTemplate(),
 		WithHTTPRouteParentRef(),
-		WithHTTPRouteRules(mode),
+		WithHTTPRouteRules(mode, r.checks...),
+	}
+}
+
+func (r *GatewayReconciler) reconcileNotFound(ctx context.Context, gs *agonesv1.GameServer, opts ...HTTPRouteOption) (*gatewayv1.HTTPRoute, bool, error) {
+	r.recorder.RecordCreating(gs, record.HTTPRouteKind)
+
+	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationTerminateTLS); ok {
+		r.recorder.RecordWarning(gs, record.HTTPRouteKind, "annotation octops.io/terminate-tls has no effect in gateway mode — configure TLS on the Gateway listener instead")
+	}
+	if _, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationIssuerName); ok {
+		r.recorder.RecordWarning(gs, record.HTTPRouteKind, "annotation octops.io/issuer-tls-name has no effect in gateway mode — use a cert-manager Certificate resource linked to the Gateway listener instead")
 	}
 
 	route, err := newHTTPRoute(gs, opts...)
 	if err != nil {
+		recordResult(gs, record.HTTPRouteKind, metrics.ResultFailed)
 		r.recorder.RecordFailed(gs, record.HTTPRouteKind, err)
 		return nil, false, errors.Wrapf(err, "failed to create HTTPRoute for gameserver %s", gs.Name)
 	}
 
+	start := time.Now()
 	result, err := r.store.CreateHTTPRoute(ctx, route, metav1.CreateOptions{})
+	observeWrite(record.HTTPRouteKind, metrics.VerbCreate, start)
 	if err != nil {
 		if !k8serrors.IsAlreadyExists(err) {
+			recordResult(gs, record.HTTPRouteKind, metrics.ResultFailed)
 			r.recorder.RecordFailed(gs, record.HTTPRouteKind, err)
 			return nil, false, errors.Wrapf(err, "failed to push HTTPRoute %s for gameserver %s", route.Name, gs.Name)
 		}
 		runtime.Logger().Debug(err)
 	}
 
+	recordResult(gs, record.HTTPRouteKind, metrics.ResultCreated)
 	r.recorder.RecordSuccess(gs, record.HTTPRouteKind)
 	return result, true, nil
 }
 
+func (r *GatewayReconciler) reconcileUpdate(ctx context.Context, gs *agonesv1.GameServer, current *gatewayv1.HTTPRoute, reason string, opts ...HTTPRouteOption) (*gatewayv1.HTTPRoute, error) {
+	desired, err := newHTTPRoute(gs, opts...)
+	if err != nil {
+		recordResult(gs, record.HTTPRouteKind, metrics.ResultFailed)
+		r.recorder.RecordFailed(gs, record.HTTPRouteKind, err)
+		return nil, errors.Wrapf(err, "failed to create HTTPRoute for gameserver %s", gs.Name)
+	}
+
+	route := current.DeepCopy()
+	route.Annotations = desired.Annotations
+	route.Spec = desired.Spec
+
+	start := time.Now()
+	result, err := r.store.UpdateHTTPRoute(ctx, route, metav1.UpdateOptions{})
+	observeWrite(record.HTTPRouteKind, metrics.VerbUpdate, start)
+	if err != nil {
+		recordResult(gs, record.HTTPRouteKind, metrics.ResultFailed)
+		r.recorder.RecordFailed(gs, record.HTTPRouteKind, err)
+		return nil, errors.Wrapf(err, "failed to update HTTPRoute %s for gameserver %s", route.Name, gs.Name)
+	}
+
+	recordResult(gs, record.HTTPRouteKind, metrics.ResultUpdated)
+	r.recorder.RecordUpdated(gs, record.HTTPRouteKind, reason)
+	return result, nil
+}
+
 func newHTTPRoute(gs *agonesv1.GameServer, options ...HTTPRouteOption) (*gatewayv1.HTTPRoute, error) {
 	if gs == nil {
 		return nil, errors.New("gameserver can't be nil")
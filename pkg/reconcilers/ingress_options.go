@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
-	"text/template"
 
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
 	"github.com/pkg/errors"
 	networkingv1 "k8s.io/api/networking/v1"
 )
@@ -16,14 +16,6 @@ type IngressOption func(gs *agonesv1.GameServer, ingress *networkingv1.Ingress)
 
 func WithCustomAnnotationsTemplate() IngressOption {
 	return func(gs *agonesv1.GameServer, ingress *networkingv1.Ingress) error {
-		data := struct {
-			Name string
-			Port int32
-		}{
-			Name: gs.Name,
-			Port: gameserver.GetGameServerPort(gs).Port,
-		}
-
 		annotations := ingress.Annotations
 		for k, v := range gs.Annotations {
 			if strings.HasPrefix(k, gameserver.OctopsAnnotationCustomPrefix) {
@@ -32,18 +24,16 @@ func WithCustomAnnotationsTemplate() IngressOption {
 					return errors.New("custom annotation does not contain a suffix")
 				}
 
-				if !strings.Contains(v, "{{") || !strings.Contains(v, "}}") {
+				if !gameserver.IsTemplate(v) {
 					continue
 				}
 
-				t, err := template.New("gs").Parse(v)
+				parsed, err := gameserver.ExecuteTemplate(gs, v)
 				if err != nil {
-					return errors.Errorf("%s:%s does not contain a valid template", custom, v)
+					return errors.Wrapf(err, "%s:%s does not contain a valid template", custom, v)
 				}
 
-				b := new(strings.Builder)
-				err = t.Execute(b, data)
-				if parsed := b.String(); len(parsed) > 0 {
+				if len(parsed) > 0 {
 					annotations[custom] = parsed
 				}
 			}
@@ -73,7 +63,8 @@ func WithCustomAnnotations() IngressOption {
 	}
 }
 
-func WithTLS(mode gameserver.IngressRoutingMode) IngressOption {
+// WithTLS sets the TLS hosts of the Ingress. Every host is verified using the checks.
+func WithTLS(mode gameserver.IngressRoutingMode, checks ...HostCheck) IngressOption {
 	return func(gs *agonesv1.GameServer, ingress *networkingv1.Ingress) error {
 		terminate, ok := gameserver.HasAnnotation(gs, gameserver.OctopsAnnotationTerminateTLS)
 		if !ok || len(terminate) == 0 {
@@ -109,9 +100,14 @@ func WithTLS(mode gameserver.IngressRoutingMode) IngressOption {
 					tlsSecret = strings.ReplaceAll(fmt.Sprintf("%s-%s-tls", d, gs.Name), ".", "-")
 				}
 
+				host, err := gameserver.Hostname(gs, d)
+				if err != nil {
+					return []networkingv1.IngressTLS{}, err
+				}
+
 				tls[i] = networkingv1.IngressTLS{
 					Hosts: []string{
-						fmt.Sprintf("%s.%s", gs.Name, d),
+						host,
 					},
 					SecretName: tlsSecret,
 				}
@@ -161,13 +157,22 @@ func WithTLS(mode gameserver.IngressRoutingMode) IngressOption {
 			return err
 		}
 
+		for _, t := range tls {
+			for _, host := range t.Hosts {
+				if err := checkHost(gs, host, checks); err != nil {
+					return err
+				}
+			}
+		}
+
 		ingress.Spec.TLS = tls
 
 		return nil
 	}
 }
 
-func WithIngressRule(mode gameserver.IngressRoutingMode) IngressOption {
+// WithIngressRule sets the rules of the Ingress for the routing mode. Every host is verified using the checks.
+func WithIngressRule(mode gameserver.IngressRoutingMode, checks ...HostCheck) IngressOption {
 	return func(gs *agonesv1.GameServer, ingress *networkingv1.Ingress) error {
 		errMsgInvalidAnnotation := func(namespace, name, annotation string) error {
 			return errors.Errorf(gameserver.ErrGameServerAnnotationEmpty, namespace, name, annotation)
@@ -188,8 +193,12 @@ func WithIngressRule(mode gameserver.IngressRoutingMode) IngressOption {
 				return errMsgInvalidAnnotation(gs.Namespace, gs.Name, gameserver.OctopsAnnotationIngressFQDN)
 			}
 
-			for _, f := range strings.Split(fqdns, ",") {
-				rule := newIngressRule(f, "/"+gs.Name, gs.Name, gameserver.GetGameServerPort(gs).Port)
+			hosts, err := gameserver.FQDNs(gs, fqdns)
+			if err != nil {
+				return err
+			}
+			for _, host := range hosts {
+				rule := newIngressRule(host, "/"+gs.Name, gs.Name, gameserver.GetGameServerPort(gs).Port)
 				rules = append(rules, rule)
 			}
 		case gameserver.IngressRoutingModeDomain:
@@ -202,7 +211,10 @@ func WithIngressRule(mode gameserver.IngressRoutingMode) IngressOption {
 			}
 
 			for _, d := range strings.Split(domains, ",") {
-				host := fmt.Sprintf("%s.%s", gs.Name, d)
+				host, err := gameserver.Hostname(gs, d)
+				if err != nil {
+					return err
+				}
 				rule := newIngressRule(host, "/", gs.Name, gameserver.GetGameServerPort(gs).Port)
 				rules = append(rules, rule)
 			}
@@ -210,6 +222,12 @@ func WithIngressRule(mode gameserver.IngressRoutingMode) IngressOption {
 			return errors.Errorf("routing mode '%s' from gameserver %s/%s is not recognised", mode, gs.Namespace, gs.Name)
 		}
 
+		for _, rule := range rules {
+			if err := checkHost(gs, rule.Host, checks); err != nil {
+				return err
+			}
+		}
+
 		ingress.Spec.Rules = rules
 		return nil
 	}
@@ -251,6 +269,32 @@ func WithIngressClassName(className string) IngressOption {
 	}
 }
 
+// WithPlaceholderBackend points every rule to the placeholder Service. It must run after WithIngressRule.
+// Ingresses can't reference Services from other namespaces, so backend.Name is resolved in the GameServer namespace.
+func WithPlaceholderBackend(backend placeholder.Backend, state placeholder.State) IngressOption {
+	return func(gs *agonesv1.GameServer, ingress *networkingv1.Ingress) error {
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+
+			for i := range rule.HTTP.Paths {
+				rule.HTTP.Paths[i].Backend = networkingv1.IngressBackend{
+					Service: &networkingv1.IngressServiceBackend{
+						Name: backend.Name,
+						Port: networkingv1.ServiceBackendPort{
+							Number: backend.Port,
+						},
+					},
+				}
+			}
+		}
+
+		ingress.Annotations[gameserver.OctopsAnnotationPlaceholderState] = string(state)
+		return nil
+	}
+}
+
 func newIngressRule(host, path, serviceName string, port int32) networkingv1.IngressRule {
 	return networkingv1.IngressRule{
 		Host: strings.TrimSpace(host),
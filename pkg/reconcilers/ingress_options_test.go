@@ -5,9 +5,11 @@ import (
 	"strings"
 	"testing"
 
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 
 	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
@@ -92,7 +94,7 @@ func Test_WithCustomAnnotationsTemplate(t *testing.T) {
 				"octops-annotation/custom": "{{ .SomeField }}",
 			},
 			expected: map[string]string{},
-			wantErr:  false,
+			wantErr:  true,
 		},
 		{
 			name:           "with not custom annotation with template",
@@ -509,6 +511,15 @@ func Test_WithIngressRule(t *testing.T) {
 			expected:    newIngressRules("www.example.com,www.example.gg", "/test-game-server", "test-game-server", 7771),
 			wantErr:     false,
 		},
+		"routing mode path with spaces around domains": {
+			gsName: "test-game-server",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationIngressFQDN: " www.example.com, www.example.gg ",
+			},
+			routingMode: gameserver.IngressRoutingModePath,
+			expected:    newIngressRules("www.example.com,www.example.gg", "/test-game-server", "test-game-server", 7771),
+			wantErr:     false,
+		},
 		"routing mode path with error": {
 			gsName: "test-game-server",
 			annotations: map[string]string{
@@ -700,6 +711,47 @@ func TestIngressClassAnnotationNotPresent(t *testing.T) {
 	}
 }
 
+func Test_WithPlaceholderBackend(t *testing.T) {
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		hosts       string
+		path        string
+	}{
+		{
+			name: "domain routing mode",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationIngressMode:   string(gameserver.IngressRoutingModeDomain),
+				gameserver.OctopsAnnotationIngressDomain: "example.com,example.gg",
+			},
+			hosts: "game-1.example.com,game-1.example.gg",
+			path:  "/",
+		},
+		{
+			name: "path routing mode",
+			annotations: map[string]string{
+				gameserver.OctopsAnnotationIngressMode: string(gameserver.IngressRoutingModePath),
+				gameserver.OctopsAnnotationIngressFQDN: "servers.example.com",
+			},
+			hosts: "servers.example.com",
+			path:  "/game-1",
+		},
+	}
+
+	backend := placeholder.Backend{Name: "octops-placeholder", Namespace: "octops-system", Port: 80}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gs := newGameServer("game-1", "default", tc.annotations)
+			mode := gameserver.GetIngressRoutingMode(gs)
+
+			ingress, err := newIngress(gs, WithIngressRule(mode), WithPlaceholderBackend(backend, placeholder.StateStarting))
+			require.NoError(t, err)
+			require.Equal(t, newIngressRules(tc.hosts, tc.path, backend.Name, backend.Port), ingress.Spec.Rules)
+			require.Equal(t, string(placeholder.StateStarting), ingress.Annotations[gameserver.OctopsAnnotationPlaceholderState])
+		})
+	}
+}
+
 func newIngressRules(hosts, path, svcName string, port int32) []networkingv1.IngressRule {
 	var rules []networkingv1.IngressRule
 
@@ -730,3 +782,27 @@ func newIngressRules(hosts, path, svcName string, port int32) []networkingv1.Ing
 
 	return rules
 }
+
+func Test_WithIngressRule_HostCheck(t *testing.T) {
+	gs := newGameServer("game-1", "default", map[string]string{
+		gameserver.OctopsAnnotationIngressMode:   string(gameserver.IngressRoutingModeDomain),
+		gameserver.OctopsAnnotationIngressDomain: "example.com,ourflagship.com",
+		gameserver.OctopsAnnotationTerminateTLS:  "true",
+	})
+
+	var checked []string
+	check := func(gs *agonesv1.GameServer, host string) error {
+		checked = append(checked, host)
+		if strings.HasSuffix(host, "ourflagship.com") {
+			return errors.Errorf("host %s is not allowed", host)
+		}
+		return nil
+	}
+
+	_, err := newIngress(gs, WithIngressRule(gameserver.IngressRoutingModeDomain, check))
+	require.EqualError(t, err, "host game-1.ourflagship.com is not allowed")
+	require.Equal(t, []string{"game-1.example.com", "game-1.ourflagship.com"}, checked)
+
+	_, err = newIngress(gs, WithTLS(gameserver.IngressRoutingModeDomain, check))
+	require.EqualError(t, err, "host game-1.ourflagship.com is not allowed")
+}
@@ -2,11 +2,15 @@ package reconcilers
 
 import (
 	"context"
+	"time"
 
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
 	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
 	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 	"github.com/pkg/errors"
 	networkingv1 "k8s.io/api/networking/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -15,38 +19,89 @@ import (
 
 type IngressStore interface {
 	CreateIngress(ctx context.Context, ingress *networkingv1.Ingress, options metav1.CreateOptions) (*networkingv1.Ingress, error)
+	UpdateIngress(ctx context.Context, ingress *networkingv1.Ingress, options metav1.UpdateOptions) (*networkingv1.Ingress, error)
 	GetIngress(name, namespace string) (*networkingv1.Ingress, error)
 }
 
 type IngressReconciler struct {
 	store    IngressStore
 	recorder *record.EventRecorder
+	checks   []HostCheck
 }
 
-func NewIngressReconciler(store IngressStore, recorder *record.EventRecorder) *IngressReconciler {
+// NewIngressReconciler returns a reconciler that refuses to publish hosts rejected by any of the checks.
+func NewIngressReconciler(store IngressStore, recorder *record.EventRecorder, checks ...HostCheck) *IngressReconciler {
 	return &IngressReconciler{
 		store:    store,
 		recorder: recorder,
+		checks:   checks,
 	}
 }
 
-func (r *IngressReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer) (*networkingv1.Ingress, bool, error) {
+func (r *IngressReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer) (_ *networkingv1.Ingress, _ bool, err error) {
+	ctx, span := tracing.Start(ctx, "IngressReconciler.Reconcile", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
 	ingress, err := r.store.GetIngress(gs.Name, gs.Namespace)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			return r.reconcileNotFound(ctx, gs)
+			return r.reconcileNotFound(ctx, gs, r.options(gs)...)
 		}
 
+		recordResult(gs, record.IngressKind, metrics.ResultFailed)
 		return nil, false, errors.Wrapf(err, "error retrieving Ingress %s from namespace %s", gs.Name, gs.Namespace)
 	}
 
+	// Ingresses created while the GameServer was not routable point to the placeholder backend
+	if _, ok := ingress.Annotations[gameserver.OctopsAnnotationPlaceholderState]; ok {
+		result, err := r.reconcileUpdate(ctx, gs, ingress, "routed to gameserver", r.options(gs)...)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return result, true, nil
+	}
+
 	//TODO: Validate if details still match the GS info
+	recordResult(gs, record.IngressKind, metrics.ResultUnchanged)
 	return ingress, false, nil
 }
 
-func (r *IngressReconciler) reconcileNotFound(ctx context.Context, gs *agonesv1.GameServer) (*networkingv1.Ingress, bool, error) {
-	r.recorder.RecordCreating(gs, record.IngressKind)
+// ReconcilePlaceholder makes the Ingress route traffic to the placeholder backend.
+// Ingresses are only created for starting GameServers, draining GameServers only have existing Ingresses updated.
+func (r *IngressReconciler) ReconcilePlaceholder(ctx context.Context, gs *agonesv1.GameServer, backend placeholder.Backend, state placeholder.State) (_ *networkingv1.Ingress, err error) {
+	ctx, span := tracing.Start(ctx, "IngressReconciler.ReconcilePlaceholder", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
+	opts := append(r.options(gs), WithPlaceholderBackend(backend, state))
+
+	ingress, err := r.store.GetIngress(gs.Name, gs.Namespace)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return nil, errors.Wrapf(err, "error retrieving Ingress %s from namespace %s", gs.Name, gs.Namespace)
+		}
+
+		if state != placeholder.StateStarting {
+			return nil, nil
+		}
+
+		result, _, err := r.reconcileNotFound(ctx, gs, opts...)
+		return result, err
+	}
+
+	if ingress.Annotations[gameserver.OctopsAnnotationPlaceholderState] == string(state) {
+		return ingress, nil
+	}
+
+	return r.reconcileUpdate(ctx, gs, ingress, "routed to placeholder backend", opts...)
+}
+
+// Desired returns the Ingress the reconciler creates for a routable GameServer.
+func (r *IngressReconciler) Desired(gs *agonesv1.GameServer) (*networkingv1.Ingress, error) {
+	return newIngress(gs, r.options(gs)...)
+}
 
+func (r *IngressReconciler) options(gs *agonesv1.GameServer) []IngressOption {
 	mode := gameserver.GetIngressRoutingMode(gs)
 	issuer := gameserver.GetTLSCertIssuer(gs)
 	className := gameserver.GetIngressClassName(gs)
@@ -54,8 +109,8 @@ func (r *IngressReconciler) reconcileNotFound(ctx context.Context, gs *agonesv1.
 	opts := []IngressOption{
 		WithCustomAnnotations(),
 		WithCustomAnnotationsTemplate(),
-		WithIngressRule(mode),
-		WithTLS(mode),
+		WithIngressRule(mode, r.checks...),
+		WithTLS(mode, r.checks...),
 		WithIngressClassName(className),
 	}
 
@@ -63,25 +118,62 @@ func (r *IngressReconciler) reconcileNotFound(ctx context.Context, gs *agonesv1.
 		opts = append(opts, WithTLSCertIssuer(issuer))
 	}
 
+	return opts
+}
+
+func (r *IngressReconciler) reconcileNotFound(ctx context.Context, gs *agonesv1.GameServer, opts ...IngressOption) (*networkingv1.Ingress, bool, error) {
+	r.recorder.RecordCreating(gs, record.IngressKind)
+
 	ingress, err := newIngress(gs, opts...)
 	if err != nil {
+		recordResult(gs, record.IngressKind, metrics.ResultFailed)
 		r.recorder.RecordFailed(gs, record.IngressKind, err)
 		return nil, false, errors.Wrapf(err, "failed to create ingress for gameserver %s", gs.Name)
 	}
 
+	start := time.Now()
 	result, err := r.store.CreateIngress(ctx, ingress, metav1.CreateOptions{})
+	observeWrite(record.IngressKind, metrics.VerbCreate, start)
 	if err != nil {
 		if !k8serrors.IsAlreadyExists(err) {
+			recordResult(gs, record.IngressKind, metrics.ResultFailed)
 			r.recorder.RecordFailed(gs, record.IngressKind, err)
 			return nil, false, errors.Wrapf(err, "failed to push ingress %s for gameserver %s", ingress.Name, gs.Name)
 		}
 		runtime.Logger().Debug(err)
 	}
 
+	recordResult(gs, record.IngressKind, metrics.ResultCreated)
 	r.recorder.RecordSuccess(gs, record.IngressKind)
 	return result, true, nil
 }
 
+func (r *IngressReconciler) reconcileUpdate(ctx context.Context, gs *agonesv1.GameServer, current *networkingv1.Ingress, reason string, opts ...IngressOption) (*networkingv1.Ingress, error) {
+	desired, err := newIngress(gs, opts...)
+	if err != nil {
+		recordResult(gs, record.IngressKind, metrics.ResultFailed)
+		r.recorder.RecordFailed(gs, record.IngressKind, err)
+		return nil, errors.Wrapf(err, "failed to create ingress for gameserver %s", gs.Name)
+	}
+
+	ingress := current.DeepCopy()
+	ingress.Annotations = desired.Annotations
+	ingress.Spec = desired.Spec
+
+	start := time.Now()
+	result, err := r.store.UpdateIngress(ctx, ingress, metav1.UpdateOptions{})
+	observeWrite(record.IngressKind, metrics.VerbUpdate, start)
+	if err != nil {
+		recordResult(gs, record.IngressKind, metrics.ResultFailed)
+		r.recorder.RecordFailed(gs, record.IngressKind, err)
+		return nil, errors.Wrapf(err, "failed to update ingress %s for gameserver %s", ingress.Name, gs.Name)
+	}
+
+	recordResult(gs, record.IngressKind, metrics.ResultUpdated)
+	r.recorder.RecordUpdated(gs, record.IngressKind, reason)
+	return result, nil
+}
+
 func newIngress(gs *agonesv1.GameServer, options ...IngressOption) (*networkingv1.Ingress, error) {
 	if gs == nil {
 		return nil, errors.New("gameserver can't be nil")
@@ -0,0 +1,31 @@
+package reconcilers
+
+import (
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+)
+
+// observeWrite records the latency of a request sent to the API server, failed requests included.
+func observeWrite(kind, verb string, start time.Time) {
+	metrics.APIWriteDuration.WithLabelValues(kind, verb).Observe(time.Since(start).Seconds())
+}
+
+// recordResult counts the outcome of a reconcile. Services and GameServers are shared by every router backend of
+// the GameServer, so their backend label lists all of them.
+func recordResult(gs *agonesv1.GameServer, kind, result string) {
+	var backend string
+	switch kind {
+	case record.IngressKind:
+		backend = string(gameserver.RouterBackendIngress)
+	case record.HTTPRouteKind:
+		backend = string(gameserver.RouterBackendGateway)
+	default:
+		backend = gameserver.RouterBackendsString(gs)
+	}
+
+	metrics.ReconcileTotal.WithLabelValues(kind, result, backend).Inc()
+}
@@ -0,0 +1,29 @@
+package reconcilers
+
+import (
+	"testing"
+
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RecordResult(t *testing.T) {
+	metrics.ReconcileTotal.Reset()
+
+	gs := newGameServer("game-1", "default", map[string]string{
+		gameserver.OctopsAnnotationRouterBackend: "ingress,gateway",
+	})
+
+	recordResult(gs, record.ServiceKind, metrics.ResultCreated)
+	recordResult(gs, record.IngressKind, metrics.ResultCreated)
+	recordResult(gs, record.HTTPRouteKind, metrics.ResultFailed)
+	recordResult(gs, record.GameServerKind, metrics.ResultUpdated)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.ReconcileTotal.WithLabelValues(record.ServiceKind, metrics.ResultCreated, "ingress,gateway")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.ReconcileTotal.WithLabelValues(record.IngressKind, metrics.ResultCreated, "ingress")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.ReconcileTotal.WithLabelValues(record.HTTPRouteKind, metrics.ResultFailed, "gateway")))
+	require.Equal(t, float64(1), testutil.ToFloat64(metrics.ReconcileTotal.WithLabelValues(record.GameServerKind, metrics.ResultUpdated, "ingress,gateway")))
+}
@@ -2,43 +2,192 @@ package reconcilers
 
 import (
 	"context"
+	"sync"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"k8s.io/apimachinery/pkg/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-// Reconciler handles events when resources are reconciled. The interval is configured on the Manager's level.
+// ConflictRequeueAfter is the delay before retrying a request that failed because the GameServer
+// was modified concurrently. Conflicts are expected and do not count as failures for the backoff.
+const ConflictRequeueAfter = time.Second
+
+// GameServerHandler reconciles the Service, routes and status of a single GameServer.
+type GameServerHandler interface {
+	Reconcile(ctx context.Context, logger *logrus.Entry, gs *agonesv1.GameServer) error
+}
+
+// Reconciler is called by the controller work queue for every GameServer request. The GameServer is read from the
+// manager cache so only the latest state is reconciled, no matter how many events were queued for the same key.
+// Errors are returned to the work queue that retries the request using exponential backoff.
 type Reconciler struct {
 	logger *logrus.Entry
-	Obj    runtime.Object
-	client.Client
-	Scheme *runtime.Scheme
-}
-
-// Warning: This method is possible not meant to be used. It has a particular use case but the broadcaster uses a shorter
-// Sync period that triggers OnUpdate events. Right now this Reconcile function is useless for the broadcaster.
-// It should be explored in the future.
-
-// TODO: Evaluate is Reconcile should be made an argument for the Controller. Reconcile can be used for general uses cases
-// where control over very specific events matter. Right now it is just a STDOUT output.
-// Reconcile is called on every reconcile event. It does not differ between add, update, delete.
-// Its function is purely informative and events are handled back to the broadcaster specific event handlers.
-func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
-	//ctx := context.Background()
-	//obj := r.obj.DeepCopyObject()
-	//if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
-	//	if apierrors.IsNotFound(err) {
-	//		r.logger.WithField("type", reflect.TypeOf(obj).String()).Debugf("resource \"%s\" not found", req.NamespacedName)
-	//		return ctrl.Result{}, nil
-	//	}
-	//
-	//	r.logger.WithError(err).Error()
-	//
-	//	return reconcile.Result{}, err
-	//}
-
-	//r.logger.Debugf("OnReconcile: %s (%s)", req.NamespacedName, reflect.TypeOf(obj).String())
+	client.Reader
+	handler GameServerHandler
+	owns    func(key types.NamespacedName) bool
+	done    func()
+	results ResultObserver
+	mu      sync.Mutex
+	retries map[types.NamespacedName]int
+}
+
+// ResultObserver is notified of the outcome of every reconcile and of GameServers that no longer exist.
+type ResultObserver interface {
+	Observe(key types.NamespacedName, err error)
+	Forget(key types.NamespacedName)
+}
+
+type ReconcilerOption func(r *Reconciler)
+
+// WithOwnership skips requests for GameServers the replica does not own, e.g. when they belong to another shard.
+func WithOwnership(owns func(key types.NamespacedName) bool) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.owns = owns
+	}
+}
+
+// WithResults reports the outcome of every reconcile to the observer, e.g. to keep the last error of each GameServer.
+func WithResults(observer ResultObserver) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.results = observer
+	}
+}
+
+// WithProgress calls done every time a request is processed, e.g. to detect a stalled work queue.
+func WithProgress(done func()) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.done = done
+	}
+}
+
+func NewReconciler(reader client.Reader, handler GameServerHandler, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		logger:  runtime.Logger().WithField("component", "reconciler"),
+		Reader:  reader,
+		handler: handler,
+		retries: map[types.NamespacedName]int{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (_ reconcile.Result, err error) {
+	if r.done != nil {
+		defer r.done()
+	}
+
+	if r.owns != nil && !r.owns(req.NamespacedName) {
+		return reconcile.Result{}, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "Reconcile", tracing.Request(req.NamespacedName)...)
+	defer func() { tracing.End(span, err) }()
+
+	gs := &agonesv1.GameServer{}
+	if err := r.Get(ctx, req.NamespacedName, gs); err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Deleted GameServers are cleaned up by the garbage collector using owner references
+			r.logger.Debugf("gameserver %s not found", req.NamespacedName)
+			r.forget(req.NamespacedName)
+			r.forgetResult(req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+
+		return reconcile.Result{}, errors.Wrapf(err, "failed to get gameserver %s", req.NamespacedName)
+	}
 
+	if gs.DeletionTimestamp != nil {
+		r.forget(req.NamespacedName)
+		r.forgetResult(req.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	span.SetAttributes(tracing.GameServer(gs)...)
+	span.SetAttributes(tracing.AttemptKey.Int(r.attempt(req.NamespacedName)))
+
+	logger := r.logger.WithFields(logrus.Fields{
+		runtime.FieldGameServer:  req.Name,
+		runtime.FieldNamespace:   req.Namespace,
+		runtime.FieldReconcileID: string(controller.ReconcileIDFromContext(ctx)),
+	})
+
+	err = r.handler.Reconcile(ctx, logger, gs)
+	if r.results != nil {
+		r.results.Observe(req.NamespacedName, err)
+	}
+
+	if err != nil {
+		r.retry(req.NamespacedName)
+		if isConflict(err) {
+			metrics.ReconcileConflicts.Inc()
+			logger.WithError(err).Debug("conflict reconciling gameserver, requeueing")
+			span.AddEvent("requeued after conflict", trace.WithAttributes(attribute.String("error", err.Error())))
+			return reconcile.Result{RequeueAfter: ConflictRequeueAfter}, nil
+		}
+
+		return reconcile.Result{}, err
+	}
+
+	r.forget(req.NamespacedName)
 	return reconcile.Result{}, nil
 }
+
+// attempt returns the number of the attempt to reconcile the GameServer, starting at 1. Retries are counted until
+// the GameServer is reconciled or deleted.
+func (r *Reconciler) attempt(key types.NamespacedName) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.retries[key] + 1
+}
+
+func (r *Reconciler) retry(key types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.retries[key]++
+}
+
+func (r *Reconciler) forget(key types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.retries, key)
+}
+
+func (r *Reconciler) forgetResult(key types.NamespacedName) {
+	if r.results != nil {
+		r.results.Forget(key)
+	}
+}
+
+// isConflict checks wrapped errors and every error of an aggregate for a conflict.
+func isConflict(err error) bool {
+	if k8serrors.IsConflict(err) {
+		return true
+	}
+
+	if agg, ok := err.(interface{ Errors() []error }); ok {
+		for _, e := range agg.Errors() {
+			if isConflict(e) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package reconcilers
+
+import (
+	"context"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type fakeHandler struct {
+	calls int
+	err   error
+}
+
+func (h *fakeHandler) Reconcile(_ context.Context, _ *logrus.Entry, _ *agonesv1.GameServer) error {
+	h.calls++
+	return h.err
+}
+
+func Test_Reconciler_Reconcile(t *testing.T) {
+	conflict := k8serrors.NewConflict(agonesv1.Resource("gameservers"), "game-1", errors.New("modified"))
+
+	testCases := []struct {
+		name           string
+		request        string
+		err            error
+		expectedCalls  int
+		expectedResult reconcile.Result
+		expectErr      bool
+	}{
+		{
+			name:          "reconciles gameserver from cache",
+			request:       "game-1",
+			expectedCalls: 1,
+		},
+		{
+			name:          "ignores gameserver not found",
+			request:       "game-2",
+			expectedCalls: 0,
+		},
+		{
+			name:          "returns error for retry with backoff",
+			request:       "game-1",
+			err:           errors.New("failed to create ingress"),
+			expectedCalls: 1,
+			expectErr:     true,
+		},
+		{
+			name:           "requeues conflicts without error",
+			request:        "game-1",
+			err:            errors.Wrap(conflict, "failed to update gameserver"),
+			expectedCalls:  1,
+			expectedResult: reconcile.Result{RequeueAfter: ConflictRequeueAfter},
+		},
+		{
+			name:           "requeues aggregated conflicts without error",
+			request:        "game-1",
+			err:            utilerrors.NewAggregate([]error{errors.Wrap(conflict, "failed to update gameserver")}),
+			expectedCalls:  1,
+			expectedResult: reconcile.Result{RequeueAfter: ConflictRequeueAfter},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := k8sruntime.NewScheme()
+			require.NoError(t, agonesv1.AddToScheme(scheme))
+
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newGameServer("game-1", "default", nil)).Build()
+			handler := &fakeHandler{err: tc.err}
+			r := NewReconciler(c, handler)
+
+			result, err := r.Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: tc.request},
+			})
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tc.expectedResult, result)
+			require.Equal(t, tc.expectedCalls, handler.calls)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package reconcilers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
+	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+type ReferenceGrantStore interface {
+	GetReferenceGrant(ctx context.Context, name, namespace string) (*gatewayv1beta1.ReferenceGrant, error)
+	CreateReferenceGrant(ctx context.Context, grant *gatewayv1beta1.ReferenceGrant, options metav1.CreateOptions) (*gatewayv1beta1.ReferenceGrant, error)
+	UpdateReferenceGrant(ctx context.Context, grant *gatewayv1beta1.ReferenceGrant, options metav1.UpdateOptions) (*gatewayv1beta1.ReferenceGrant, error)
+}
+
+// ReferenceGrantReconciler lets the HTTPRoutes of every namespace reference the placeholder Service. It keeps a single
+// ReferenceGrant in the namespace of the placeholder Service, named after it, with one entry per namespace.
+type ReferenceGrantReconciler struct {
+	store    ReferenceGrantStore
+	recorder *record.EventRecorder
+	// granted are the namespaces known to be listed in the ReferenceGrant. They are forgotten whenever the
+	// ReferenceGrant changes, so entries removed by anyone are added again.
+	granted sync.Map
+}
+
+func NewReferenceGrantReconciler(store ReferenceGrantStore, recorder *record.EventRecorder) *ReferenceGrantReconciler {
+	return &ReferenceGrantReconciler{
+		store:    store,
+		recorder: recorder,
+	}
+}
+
+// Reconcile adds the namespace of the GameServer to the ReferenceGrant of the placeholder backend.
+func (r *ReferenceGrantReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer, backend placeholder.Backend) (err error) {
+	ctx, span := tracing.Start(ctx, "ReferenceGrantReconciler.Reconcile", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
+	if gs.Namespace == backend.Namespace {
+		return nil
+	}
+
+	if _, ok := r.granted.Load(gs.Namespace); ok {
+		return nil
+	}
+
+	grant, err := r.store.GetReferenceGrant(ctx, backend.Name, backend.Namespace)
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+
+		start := time.Now()
+		_, err = r.store.CreateReferenceGrant(ctx, newReferenceGrant(backend, gs.Namespace), metav1.CreateOptions{})
+		observeWrite(record.ReferenceGrantKind, metrics.VerbCreate, start)
+		if err != nil {
+			r.recorder.RecordFailed(gs, record.ReferenceGrantKind, err)
+			return errors.Wrap(err, "failed to create placeholder reference grant")
+		}
+
+		r.granted.Store(gs.Namespace, struct{}{})
+		return nil
+	}
+
+	for _, from := range grant.Spec.From {
+		if from.Kind == "HTTPRoute" && string(from.Namespace) == gs.Namespace {
+			r.granted.Store(gs.Namespace, struct{}{})
+			return nil
+		}
+	}
+
+	grant = grant.DeepCopy()
+	grant.Spec.From = append(grant.Spec.From, referenceGrantFrom(gs.Namespace))
+
+	start := time.Now()
+	_, err = r.store.UpdateReferenceGrant(ctx, grant, metav1.UpdateOptions{})
+	observeWrite(record.ReferenceGrantKind, metrics.VerbUpdate, start)
+	if err != nil {
+		r.recorder.RecordFailed(gs, record.ReferenceGrantKind, err)
+		return errors.Wrap(err, "failed to update placeholder reference grant")
+	}
+
+	r.granted.Store(gs.Namespace, struct{}{})
+	return nil
+}
+
+// OnAdd is called by the informer of the ReferenceGrant, namespaces already listed are found on the next Reconcile.
+func (r *ReferenceGrantReconciler) OnAdd(_ interface{}, _ bool) {}
+
+// OnUpdate forgets the granted namespaces, the ReferenceGrant may have been edited.
+func (r *ReferenceGrantReconciler) OnUpdate(_, _ interface{}) {
+	r.granted.Clear()
+}
+
+// OnDelete forgets the granted namespaces, the ReferenceGrant is created again by the next Reconcile.
+func (r *ReferenceGrantReconciler) OnDelete(_ interface{}) {
+	r.granted.Clear()
+}
+
+func newReferenceGrant(backend placeholder.Backend, namespace string) *gatewayv1beta1.ReferenceGrant {
+	return &gatewayv1beta1.ReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backend.Name,
+			Namespace: backend.Namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "octops",
+			},
+		},
+		Spec: gatewayv1beta1.ReferenceGrantSpec{
+			From: []gatewayv1beta1.ReferenceGrantFrom{referenceGrantFrom(namespace)},
+			To: []gatewayv1beta1.ReferenceGrantTo{
+				{
+					Group: "",
+					Kind:  "Service",
+					Name:  (*gatewayv1.ObjectName)(&backend.Name),
+				},
+			},
+		},
+	}
+}
+
+func referenceGrantFrom(namespace string) gatewayv1beta1.ReferenceGrantFrom {
+	return gatewayv1beta1.ReferenceGrantFrom{
+		Group:     gatewayv1.GroupName,
+		Kind:      "HTTPRoute",
+		Namespace: gatewayv1.Namespace(namespace),
+	}
+}
@@ -0,0 +1,68 @@
+package reconcilers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8srecord "k8s.io/client-go/tools/record"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+type fakeReferenceGrantStore struct {
+	grant  *gatewayv1beta1.ReferenceGrant
+	writes int
+}
+
+func (s *fakeReferenceGrantStore) GetReferenceGrant(_ context.Context, name, _ string) (*gatewayv1beta1.ReferenceGrant, error) {
+	if s.grant == nil {
+		return nil, k8serrors.NewNotFound(gatewayv1beta1.Resource("referencegrants"), name)
+	}
+	return s.grant.DeepCopy(), nil
+}
+
+func (s *fakeReferenceGrantStore) CreateReferenceGrant(_ context.Context, grant *gatewayv1beta1.ReferenceGrant, _ metav1.CreateOptions) (*gatewayv1beta1.ReferenceGrant, error) {
+	s.writes++
+	s.grant = grant
+	return grant, nil
+}
+
+func (s *fakeReferenceGrantStore) UpdateReferenceGrant(_ context.Context, grant *gatewayv1beta1.ReferenceGrant, _ metav1.UpdateOptions) (*gatewayv1beta1.ReferenceGrant, error) {
+	s.writes++
+	s.grant = grant
+	return grant, nil
+}
+
+func Test_ReferenceGrantReconciler(t *testing.T) {
+	store := &fakeReferenceGrantStore{}
+	reconciler := NewReferenceGrantReconciler(store, record.NewEventRecorder(k8srecord.NewFakeRecorder(10)))
+	backend := placeholder.Backend{Name: "octops-placeholder", Namespace: "octops-system", Port: 80}
+
+	for _, namespace := range []string{"games", "games", "arena", "octops-system"} {
+		require.NoError(t, reconciler.Reconcile(context.Background(), newGameServer("game-1", namespace, nil), backend))
+	}
+	require.Equal(t, 2, store.writes)
+
+	require.Equal(t, "octops-placeholder", store.grant.Name)
+	require.Equal(t, "octops-system", store.grant.Namespace)
+	require.Len(t, store.grant.Spec.From, 2)
+	require.Equal(t, "games", string(store.grant.Spec.From[0].Namespace))
+	require.Equal(t, "arena", string(store.grant.Spec.From[1].Namespace))
+	require.Equal(t, "octops-placeholder", string(*store.grant.Spec.To[0].Name))
+
+	// Namespaces already listed by a grant created before are not added twice
+	reconciler = NewReferenceGrantReconciler(store, record.NewEventRecorder(k8srecord.NewFakeRecorder(10)))
+	require.NoError(t, reconciler.Reconcile(context.Background(), newGameServer("game-1", "arena", nil), backend))
+	require.Equal(t, 2, store.writes)
+	// The grant deleted by anyone is created again
+	deleted := store.grant
+	store.grant = nil
+	reconciler.OnDelete(deleted)
+	require.NoError(t, reconciler.Reconcile(context.Background(), newGameServer("game-1", "arena", nil), backend))
+	require.Equal(t, 3, store.writes)
+	require.Len(t, store.grant.Spec.From, 1)
+}
@@ -6,21 +6,12 @@ import (
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"strings"
-	"text/template"
 )
 
 type ServiceOption func(gs *agonesv1.GameServer, service *corev1.Service) error
 
 func WithCustomServiceAnnotationsTemplate() ServiceOption {
 	return func(gs *agonesv1.GameServer, service *corev1.Service) error {
-		data := struct {
-			Name string
-			Port int32
-		}{
-			Name: gs.Name,
-			Port: gameserver.GetGameServerPort(gs).Port,
-		}
-
 		annotations := service.Annotations
 		for k, v := range gs.Annotations {
 			if strings.HasPrefix(k, gameserver.OctopsAnnotationCustomServicePrefix) {
@@ -29,18 +20,16 @@ func WithCustomServiceAnnotationsTemplate() ServiceOption {
 					return errors.Errorf("custom annotation %s does not contain a suffix", k)
 				}
 
-				if !strings.Contains(v, "{{") || !strings.Contains(v, "}}") {
+				if !gameserver.IsTemplate(v) {
 					continue
 				}
 
-				t, err := template.New("gs").Parse(v)
+				parsed, err := gameserver.ExecuteTemplate(gs, v)
 				if err != nil {
-					return errors.Errorf("%s:%s does not contain a valid template", custom, v)
+					return errors.Wrapf(err, "%s:%s does not contain a valid template", custom, v)
 				}
 
-				b := new(strings.Builder)
-				err = t.Execute(b, data)
-				if parsed := b.String(); len(parsed) > 0 {
+				if len(parsed) > 0 {
 					annotations[custom] = parsed
 				}
 			}
@@ -87,7 +87,7 @@ func Test_WithCustomServiceAnnotationsTemplate(t *testing.T) {
 				"octops.service-annotation/custom": "{{ .SomeField }}",
 			},
 			expected: map[string]string{},
-			wantErr:  false,
+			wantErr:  true,
 		},
 		{
 			name:           "with not custom annotation with template",
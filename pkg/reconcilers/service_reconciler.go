@@ -2,11 +2,15 @@ package reconcilers
 
 import (
 	"context"
+	"time"
 
 	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
 	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
 	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
 	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -17,6 +21,7 @@ import (
 type ServiceStore interface {
 	CreateService(ctx context.Context, service *corev1.Service, options metav1.CreateOptions) (*corev1.Service, error)
 	GetService(name, namespace string) (*corev1.Service, error)
+	GetPlaceholderService(name, namespace string) (*corev1.Service, error)
 }
 
 type ServiceReconciler struct {
@@ -31,43 +36,86 @@ func NewServiceReconciler(store ServiceStore, recorder *record.EventRecorder) *S
 	}
 }
 
-func (r *ServiceReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer) (*corev1.Service, error) {
+func (r *ServiceReconciler) Reconcile(ctx context.Context, gs *agonesv1.GameServer) (_ *corev1.Service, err error) {
+	ctx, span := tracing.Start(ctx, "ServiceReconciler.Reconcile", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
 	service, err := r.store.GetService(gs.Name, gs.Namespace)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			return r.reconcileNotFound(ctx, gs)
 		}
 
+		recordResult(gs, record.ServiceKind, metrics.ResultFailed)
 		return &corev1.Service{}, errors.Wrapf(err, "error retrieving Service %s from namespace %s", gs.Name, gs.Namespace)
 	}
 
 	//TODO: Validate if details still match the GS info
+	recordResult(gs, record.ServiceKind, metrics.ResultUnchanged)
 	return service, nil
 }
 
-func (r *ServiceReconciler) reconcileNotFound(ctx context.Context, gs *agonesv1.GameServer) (*corev1.Service, error) {
-	r.recorder.RecordCreating(gs, record.ServiceKind)
+// ReconcilePlaceholder makes sure the namespace of the GameServer has a Service resolving to the placeholder backend.
+// Ingresses can only reference Services from their own namespace, so an ExternalName Service is shared by
+// all GameServers of a namespace. It is not owned by any GameServer, the orphan sweeper deletes it once no Ingress
+// of the namespace references it.
+func (r *ServiceReconciler) ReconcilePlaceholder(ctx context.Context, gs *agonesv1.GameServer, backend placeholder.Backend) (_ *corev1.Service, err error) {
+	ctx, span := tracing.Start(ctx, "ServiceReconciler.ReconcilePlaceholder", tracing.GameServer(gs)...)
+	defer func() { tracing.End(span, err) }()
+
+	if gs.Namespace == backend.Namespace {
+		return nil, nil
+	}
 
-	opts := []ServiceOption{
-		WithCustomServiceAnnotations(),
-		WithCustomServiceAnnotationsTemplate(),
+	// The Service is recreated if it was deleted by anyone, not only by the orphan sweeper
+	if _, err := r.store.GetPlaceholderService(backend.Name, gs.Namespace); err == nil {
+		return nil, nil
+	} else if !k8serrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "failed to get placeholder service")
 	}
 
-	service, err := newService(gs, opts...)
+	start := time.Now()
+	result, err := r.store.CreateService(ctx, newPlaceholderService(gs.Namespace, backend), metav1.CreateOptions{})
+	observeWrite(record.ServiceKind, metrics.VerbCreate, start)
 	if err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			r.recorder.RecordFailed(gs, record.ServiceKind, err)
+			return nil, errors.Wrap(err, "failed to create placeholder service")
+		}
+		runtime.Logger().Debug(err)
+	}
+
+	return result, nil
+}
+
+// Desired returns the Service the reconciler creates for a GameServer.
+func (r *ServiceReconciler) Desired(gs *agonesv1.GameServer) (*corev1.Service, error) {
+	return newService(gs, WithCustomServiceAnnotations(), WithCustomServiceAnnotationsTemplate())
+}
+
+func (r *ServiceReconciler) reconcileNotFound(ctx context.Context, gs *agonesv1.GameServer) (*corev1.Service, error) {
+	r.recorder.RecordCreating(gs, record.ServiceKind)
+
+	service, err := r.Desired(gs)
+	if err != nil {
+		recordResult(gs, record.ServiceKind, metrics.ResultFailed)
 		r.recorder.RecordFailed(gs, record.ServiceKind, err)
 		return nil, errors.Wrapf(err, "failed to create service for gameserver %s", gs.Name)
 	}
 
+	start := time.Now()
 	result, err := r.store.CreateService(ctx, service, metav1.CreateOptions{})
+	observeWrite(record.ServiceKind, metrics.VerbCreate, start)
 	if err != nil {
 		if !k8serrors.IsAlreadyExists(err) {
+			recordResult(gs, record.ServiceKind, metrics.ResultFailed)
 			r.recorder.RecordFailed(gs, record.ServiceKind, err)
 			return nil, errors.Wrap(err, "failed to create service")
 		}
 		runtime.Logger().Debug(err)
 	}
 
+	recordResult(gs, record.ServiceKind, metrics.ResultCreated)
 	r.recorder.RecordSuccess(gs, record.ServiceKind)
 	return result, nil
 }
@@ -109,3 +157,26 @@ func newService(gs *agonesv1.GameServer, options ...ServiceOption) (*corev1.Serv
 
 	return service, nil
 }
+
+func newPlaceholderService(namespace string, backend placeholder.Backend) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backend.Name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "octops",
+				placeholder.ServiceLabel:       "true",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: backend.Host(),
+			Ports: []corev1.ServicePort{
+				{
+					Name: "placeholder",
+					Port: backend.Port,
+				},
+			},
+		},
+	}
+}
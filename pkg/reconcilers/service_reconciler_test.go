@@ -0,0 +1,73 @@
+package reconcilers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8srecord "k8s.io/client-go/tools/record"
+)
+
+// fakeServiceStore caches the placeholder Services it creates, like the informer filtered on their label.
+type fakeServiceStore struct {
+	creates      int
+	placeholders map[string]*corev1.Service
+}
+
+func (s *fakeServiceStore) CreateService(_ context.Context, service *corev1.Service, _ metav1.CreateOptions) (*corev1.Service, error) {
+	s.creates++
+	if s.placeholders == nil {
+		s.placeholders = map[string]*corev1.Service{}
+	}
+	s.placeholders[service.Namespace] = service
+	return service, nil
+}
+
+func (s *fakeServiceStore) GetService(name, _ string) (*corev1.Service, error) {
+	return nil, k8serrors.NewNotFound(corev1.Resource("services"), name)
+}
+
+func (s *fakeServiceStore) GetPlaceholderService(name, namespace string) (*corev1.Service, error) {
+	if service, ok := s.placeholders[namespace]; ok {
+		return service, nil
+	}
+	return nil, k8serrors.NewNotFound(corev1.Resource("services"), name)
+}
+
+func Test_ServiceReconciler_ReconcilePlaceholder(t *testing.T) {
+	store := &fakeServiceStore{}
+	reconciler := NewServiceReconciler(store, record.NewEventRecorder(k8srecord.NewFakeRecorder(10)))
+	backend := placeholder.Backend{Name: "octops-placeholder", Namespace: "octops-system", Port: 80}
+
+	for i := 0; i < 3; i++ {
+		_, err := reconciler.ReconcilePlaceholder(context.Background(), newGameServer("game-1", "games", nil), backend)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, store.creates)
+
+	// The namespace of the placeholder backend doesn't need one
+	_, err := reconciler.ReconcilePlaceholder(context.Background(), newGameServer("game-1", "octops-system", nil), backend)
+	require.NoError(t, err)
+	require.Equal(t, 1, store.creates)
+
+	// Deleted by anyone, e.g. kubectl or the orphan sweeper
+	delete(store.placeholders, "games")
+	_, err = reconciler.ReconcilePlaceholder(context.Background(), newGameServer("game-1", "games", nil), backend)
+	require.NoError(t, err)
+	require.Equal(t, 2, store.creates)
+}
+
+func Test_newPlaceholderService(t *testing.T) {
+	backend := placeholder.Backend{Name: "octops-placeholder", Namespace: "octops-system", Port: 80}
+	service := newPlaceholderService("games", backend)
+	require.Equal(t, "octops-placeholder.octops-system.svc.cluster.local", service.Spec.ExternalName)
+	require.Equal(t, "true", service.Labels[placeholder.ServiceLabel])
+
+	backend.ClusterDomain = "games.internal"
+	require.Equal(t, "octops-placeholder.octops-system.svc.games.internal", newPlaceholderService("games", backend).Spec.ExternalName)
+}
@@ -0,0 +1,122 @@
+package reconcilers
+
+import (
+	"context"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	k8srecord "k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// tracedHandler runs the cleanup reconciler and then returns the next error, like the event handler failing on a
+// later step.
+type tracedHandler struct {
+	cleanup *CleanupReconciler
+	errs    []error
+}
+
+func (h *tracedHandler) Reconcile(ctx context.Context, _ *logrus.Entry, gs *agonesv1.GameServer) error {
+	if _, err := h.cleanup.Reconcile(ctx, gs); err != nil {
+		return err
+	}
+
+	err := h.errs[0]
+	h.errs = h.errs[1:]
+	return err
+}
+
+func Test_Reconciler_Tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	gs := newGameServer("game-1", "default", nil)
+	gs.UID = types.UID("gs-uid")
+
+	scheme := k8sruntime.NewScheme()
+	require.NoError(t, agonesv1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).Build()
+
+	store := newFakeCleanupStore(gs)
+	conflict := k8serrors.NewConflict(agonesv1.Resource("gameservers"), "game-1", errors.New("modified"))
+	handler := &tracedHandler{
+		cleanup: NewCleanupReconciler(store, store, store, record.NewEventRecorder(k8srecord.NewFakeRecorder(10))),
+		errs:    []error{conflict, nil, errors.New("failed to create ingress")},
+	}
+	r := NewReconciler(c, handler)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "game-1"}}
+
+	reconcileOnce := func() tracetest.SpanStubs {
+		exporter.Reset()
+		_, _ = r.Reconcile(context.Background(), req)
+		return exporter.GetSpans()
+	}
+
+	t.Run("conflict is recorded as an event of the first attempt", func(t *testing.T) {
+		spans := reconcileOnce()
+		require.Len(t, spans, 2)
+
+		child, root := spans[0], spans[1]
+		require.Equal(t, "CleanupReconciler.Reconcile", child.Name)
+		require.Equal(t, "Reconcile", root.Name)
+		require.Equal(t, root.SpanContext.SpanID(), child.Parent.SpanID())
+		require.Equal(t, root.SpanContext.TraceID(), child.SpanContext.TraceID())
+
+		attrs := attributes(root.Attributes)
+		require.Equal(t, "default", attrs[tracing.NamespaceKey].AsString())
+		require.Equal(t, "game-1", attrs[tracing.GameServerKey].AsString())
+		require.Equal(t, "gs-uid", attrs[tracing.UIDKey].AsString())
+		require.Equal(t, int64(1), attrs[tracing.AttemptKey].AsInt64())
+		require.Equal(t, "gs-uid", attributes(child.Attributes)[tracing.UIDKey].AsString())
+
+		require.Len(t, root.Events, 1)
+		require.Equal(t, "requeued after conflict", root.Events[0].Name)
+		require.Equal(t, codes.Unset, root.Status.Code)
+	})
+
+	t.Run("retry is traced as the second attempt", func(t *testing.T) {
+		spans := reconcileOnce()
+		require.Len(t, spans, 2)
+
+		root := spans[1]
+		require.Equal(t, int64(2), attributes(root.Attributes)[tracing.AttemptKey].AsInt64())
+		require.Empty(t, root.Events)
+		require.Equal(t, codes.Unset, root.Status.Code)
+	})
+
+	t.Run("attempts are reset after success and errors set the status", func(t *testing.T) {
+		spans := reconcileOnce()
+		require.Len(t, spans, 2)
+
+		root := spans[1]
+		require.Equal(t, int64(1), attributes(root.Attributes)[tracing.AttemptKey].AsInt64())
+		require.Equal(t, codes.Error, root.Status.Code)
+		require.Equal(t, "failed to create ingress", root.Status.Description)
+	})
+}
+
+func attributes(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	result := map[attribute.Key]attribute.Value{}
+	for _, kv := range kvs {
+		result[kv.Key] = kv.Value
+	}
+
+	return result
+}
@@ -0,0 +1,65 @@
+package record
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultHistorySize is the number of events kept for each GameServer.
+const DefaultHistorySize = 10
+
+// Event is an event recorded on a GameServer by the controller.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Reason  string    `json:"reason"`
+	Message string    `json:"message"`
+}
+
+// History keeps the last events recorded on each GameServer in memory, so they can be inspected without listing the
+// Events of the cluster. Only events recorded by this replica are kept.
+type History struct {
+	mu     sync.RWMutex
+	size   int
+	events map[types.NamespacedName][]Event
+}
+
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+
+	return &History{
+		size:   size,
+		events: map[types.NamespacedName][]Event{},
+	}
+}
+
+func (h *History) add(key types.NamespacedName, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := append(h.events[key], event)
+	if len(events) > h.size {
+		events = append([]Event(nil), events[len(events)-h.size:]...)
+	}
+	h.events[key] = events
+}
+
+// Events returns the events of the GameServer, oldest first.
+func (h *History) Events(key types.NamespacedName) []Event {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return append([]Event(nil), h.events[key]...)
+}
+
+// Forget drops the events of a deleted GameServer.
+func (h *History) Forget(key types.NamespacedName) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.events, key)
+}
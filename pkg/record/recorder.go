@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
 )
 
 const (
-	IngressKind   = "Ingress"
-	ServiceKind   = "Service"
-	HTTPRouteKind = "HTTPRoute"
+	IngressKind        = "Ingress"
+	ServiceKind        = "Service"
+	HTTPRouteKind      = "HTTPRoute"
+	GameServerKind     = "GameServer"
+	ReferenceGrantKind = "ReferenceGrant"
 
 	EventTypeNormal         string = "Normal"
 	EventTypeWarning               = "Warning"
@@ -18,6 +22,9 @@ const (
 	ReasonReconciled               = "Created"
 	ReasonReconcileCreating        = "Creating"
 	ReasonReconcileUpdated         = "Updated"
+	ReasonReconcileDeleted         = "Deleted"
+	ReasonOrphaned                 = "Orphaned"
+	ReasonHostConflict             = "HostConflict"
 )
 
 type Recorder interface {
@@ -26,10 +33,33 @@ type Recorder interface {
 
 type EventRecorder struct {
 	recorder Recorder
+	history  *History
+	dryRun   bool
 }
 
-func NewEventRecorder(recorder Recorder) *EventRecorder {
-	return &EventRecorder{recorder: recorder}
+type EventRecorderOption func(r *EventRecorder)
+
+// WithHistory also keeps the events recorded on GameServers in the history.
+func WithHistory(history *History) EventRecorderOption {
+	return func(r *EventRecorder) {
+		r.history = history
+	}
+}
+
+// WithDryRun does not create Events, so a controller running in dry-run mode doesn't report changes it never made.
+// Events are still kept in the history.
+func WithDryRun() EventRecorderOption {
+	return func(r *EventRecorder) {
+		r.dryRun = true
+	}
+}
+
+func NewEventRecorder(recorder Recorder, opts ...EventRecorderOption) *EventRecorder {
+	r := &EventRecorder{recorder: recorder}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *EventRecorder) RecordFailed(gs *agonesv1.GameServer, kind string, err error) {
@@ -40,6 +70,14 @@ func (r *EventRecorder) RecordSuccess(gs *agonesv1.GameServer, kind string) {
 	r.recordEvent(gs, EventTypeNormal, ReasonReconciled, fmt.Sprintf("%s created for gameserver %s/%s", kind, gs.Namespace, gs.Name))
 }
 
+func (r *EventRecorder) RecordUpdated(gs *agonesv1.GameServer, kind string, message string) {
+	r.recordEvent(gs, EventTypeNormal, ReasonReconcileUpdated, fmt.Sprintf("%s updated for gameserver %s/%s: %s", kind, gs.Namespace, gs.Name, message))
+}
+
+func (r *EventRecorder) RecordDeleted(gs *agonesv1.GameServer, kind string, message string) {
+	r.recordEvent(gs, EventTypeNormal, ReasonReconcileDeleted, fmt.Sprintf("%s deleted for gameserver %s/%s: %s", kind, gs.Namespace, gs.Name, message))
+}
+
 func (r *EventRecorder) RecordCreating(gs *agonesv1.GameServer, kind string) {
 	r.recordEvent(gs, EventTypeNormal, ReasonReconcileCreating, fmt.Sprintf("Creating %s for gameserver %s/%s", kind, gs.Namespace, gs.Name))
 }
@@ -48,10 +86,43 @@ func (r *EventRecorder) RecordWarning(gs *agonesv1.GameServer, kind string, mess
 	r.recordEvent(gs, EventTypeWarning, ReasonReconcileFailed, fmt.Sprintf("%s warning for gameserver %s/%s: %s", kind, gs.Namespace, gs.Name, message))
 }
 
+// RecordConflict records a warning on a GameServer whose host and path pair is claimed by another GameServer.
+func (r *EventRecorder) RecordConflict(gs *agonesv1.GameServer, message string) {
+	r.recordEvent(gs, EventTypeWarning, ReasonHostConflict, message)
+}
+
 func (r *EventRecorder) RecordEvent(gs *agonesv1.GameServer, eventMessage string) {
 	r.recordEvent(gs, EventTypeNormal, ReasonReconcileUpdated, fmt.Sprintf("%s for %s", eventMessage, k8sutil.Namespaced(gs)))
 }
 
 func (r *EventRecorder) recordEvent(object runtime.Object, eventType, reason, message string) {
-	r.recorder.Event(object, eventType, reason, message)
+	if !r.dryRun {
+		r.recorder.Event(object, eventType, reason, message)
+	}
+
+	if gs, ok := object.(*agonesv1.GameServer); ok && r.history != nil {
+		r.history.add(types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}, Event{
+			Time:    time.Now(),
+			Type:    eventType,
+			Reason:  reason,
+			Message: message,
+		})
+	}
+}
+
+// Forget drops the history of a deleted GameServer.
+func (r *EventRecorder) Forget(gs *agonesv1.GameServer) {
+	if r.history != nil {
+		r.history.Forget(types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name})
+	}
+}
+
+// RecordOrphan records an event on a resource that is not owned by a live GameServer.
+func (r *EventRecorder) RecordOrphan(object runtime.Object, kind, name string, deleted bool) {
+	if deleted {
+		r.recordEvent(object, EventTypeNormal, ReasonReconcileDeleted, fmt.Sprintf("Orphaned %s %s deleted, no live gameserver found", kind, name))
+		return
+	}
+
+	r.recordEvent(object, EventTypeWarning, ReasonOrphaned, fmt.Sprintf("%s %s is orphaned, no live gameserver found", kind, name))
 }
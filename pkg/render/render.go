@@ -0,0 +1,247 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/handlers"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// Suffix replaces the random suffixes Agones appends to the names of GameServerSets and GameServers.
+	Suffix = "xxxxx"
+	// FirstHostPort is the host port given to dynamic ports, the first port of the default Agones port range.
+	FirstHostPort int32 = 7000
+)
+
+type Options struct {
+	// Namespace is used for manifests without a namespace.
+	Namespace string
+	// Name overrides the name of the representative GameServer.
+	Name string
+	// State is the state of the representative GameServer.
+	State agonesv1.GameServerState
+}
+
+// Source is a representative GameServer of a manifest.
+type Source struct {
+	Kind       string
+	Namespace  string
+	Name       string
+	GameServer *agonesv1.GameServer
+}
+
+// Decode reads a YAML or JSON manifest, possibly with multiple documents, and returns a representative GameServer
+// for each Fleet, GameServerSet and GameServer. Other kinds are ignored.
+func Decode(r io.Reader, options Options) ([]Source, error) {
+	var sources []Source
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read manifest")
+		}
+
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		source, ok, err := FromManifest(doc, options)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			sources = append(sources, source)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("manifest has no Fleet, GameServerSet or GameServer")
+	}
+
+	return sources, nil
+}
+
+// FromManifest returns a representative GameServer for a single Fleet, GameServerSet or GameServer document. The
+// second value is false for other kinds.
+func FromManifest(doc []byte, options Options) (Source, bool, error) {
+	if len(options.Namespace) == 0 {
+		options.Namespace = metav1.NamespaceDefault
+	}
+
+	if len(options.State) == 0 {
+		options.State = agonesv1.GameServerStateReady
+	}
+
+	typeMeta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+		return Source{}, false, errors.Wrap(err, "failed to decode manifest")
+	}
+
+	gv, err := schema.ParseGroupVersion(typeMeta.APIVersion)
+	if err != nil || gv.Group != agonesv1.SchemeGroupVersion.Group {
+		return Source{}, false, nil
+	}
+
+	var meta metav1.ObjectMeta
+	var template agonesv1.GameServerTemplateSpec
+	labels := map[string]string{}
+	name := ""
+
+	switch typeMeta.Kind {
+	case "Fleet":
+		fleet := &agonesv1.Fleet{}
+		if err := yaml.Unmarshal(doc, fleet); err != nil {
+			return Source{}, false, errors.Wrap(err, "failed to decode Fleet")
+		}
+		meta, template = fleet.ObjectMeta, fleet.Spec.Template
+		name = fmt.Sprintf("%s-%s-%s", fleet.Name, Suffix, Suffix)
+		labels[gameserver.AgonesFleetNameLabel] = fleet.Name
+		labels[gameserver.AgonesGameServerSetNameLabel] = fmt.Sprintf("%s-%s", fleet.Name, Suffix)
+	case "GameServerSet":
+		gss := &agonesv1.GameServerSet{}
+		if err := yaml.Unmarshal(doc, gss); err != nil {
+			return Source{}, false, errors.Wrap(err, "failed to decode GameServerSet")
+		}
+		meta, template = gss.ObjectMeta, gss.Spec.Template
+		name = fmt.Sprintf("%s-%s", gss.Name, Suffix)
+		labels[gameserver.AgonesGameServerSetNameLabel] = gss.Name
+		if fleet, ok := gss.Labels[gameserver.AgonesFleetNameLabel]; ok {
+			labels[gameserver.AgonesFleetNameLabel] = fleet
+		}
+	case "GameServer":
+		gs := &agonesv1.GameServer{}
+		if err := yaml.Unmarshal(doc, gs); err != nil {
+			return Source{}, false, errors.Wrap(err, "failed to decode GameServer")
+		}
+		meta = gs.ObjectMeta
+		template = agonesv1.GameServerTemplateSpec{ObjectMeta: gs.ObjectMeta, Spec: gs.Spec}
+		name = gs.Name
+		if len(name) == 0 {
+			name = gs.GenerateName + Suffix
+		}
+	default:
+		return Source{}, false, nil
+	}
+
+	if len(options.Name) > 0 {
+		name = options.Name
+	}
+
+	sourceName := meta.Name
+	if len(sourceName) == 0 {
+		sourceName = meta.GenerateName
+	}
+
+	namespace := meta.Namespace
+	if len(namespace) == 0 {
+		namespace = options.Namespace
+	}
+
+	return Source{
+		Kind:       typeMeta.Kind,
+		Namespace:  namespace,
+		Name:       sourceName,
+		GameServer: newGameServer(namespace, name, labels, template, options.State),
+	}, true, nil
+}
+
+// newGameServer returns the GameServer Agones would create from the template, with a host port allocated to every
+// port.
+func newGameServer(namespace, name string, labels map[string]string, template agonesv1.GameServerTemplateSpec, state agonesv1.GameServerState) *agonesv1.GameServer {
+	gs := &agonesv1.GameServer{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: agonesv1.SchemeGroupVersion.String(),
+			Kind:       "GameServer",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{},
+			Annotations: map[string]string{},
+		},
+		Spec: template.Spec,
+		Status: agonesv1.GameServerStatus{
+			State: state,
+		},
+	}
+
+	for k, v := range template.Labels {
+		gs.Labels[k] = v
+	}
+	for k, v := range labels {
+		gs.Labels[k] = v
+	}
+	for k, v := range template.Annotations {
+		gs.Annotations[k] = v
+	}
+
+	for i, port := range gs.Spec.Ports {
+		hostPort := port.HostPort
+		if hostPort == 0 {
+			hostPort = FirstHostPort + int32(i)
+		}
+		gs.Status.Ports = append(gs.Status.Ports, agonesv1.GameServerStatusPort{Name: port.Name, Port: hostPort})
+	}
+
+	return gs
+}
+
+// Objects returns the resources of the plan, with their kind set so they can be applied.
+func Objects(plan *handlers.Plan) []runtime.Object {
+	var objects []runtime.Object
+	if plan.Service != nil {
+		plan.Service.TypeMeta = metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "Service"}
+		objects = append(objects, plan.Service)
+	}
+
+	if plan.Ingress != nil {
+		plan.Ingress.TypeMeta = metav1.TypeMeta{APIVersion: networkingv1.SchemeGroupVersion.String(), Kind: "Ingress"}
+		objects = append(objects, plan.Ingress)
+	}
+
+	if plan.HTTPRoute != nil {
+		plan.HTTPRoute.TypeMeta = metav1.TypeMeta{APIVersion: gatewayv1.GroupVersion.String(), Kind: "HTTPRoute"}
+		objects = append(objects, plan.HTTPRoute)
+	}
+
+	return objects
+}
+
+// Write writes the objects as YAML documents. The status is omitted, it is always empty before the object is created.
+func Write(w io.Writer, objects ...runtime.Object) error {
+	for _, obj := range objects {
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode object")
+		}
+		delete(u, "status")
+
+		b, err := yaml.Marshal(u)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode object")
+		}
+
+		if _, err := fmt.Fprintf(w, "---\n%s", b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
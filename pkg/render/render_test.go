@@ -0,0 +1,140 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/handlers"
+	"github.com/stretchr/testify/require"
+)
+
+const manifest = `
+apiVersion: agones.dev/v1
+kind: Fleet
+metadata:
+  name: octops-domain
+  namespace: games
+spec:
+  template:
+    metadata:
+      labels:
+        region: us-east-1
+      annotations:
+        octops.io/gameserver-ingress-mode: domain
+        octops.io/gameserver-ingress-domain: example.com
+        octops.io/router-backend: ingress,gateway
+        octops.io/gateway-name: gateway
+        octops.io/ingress-class-name: contour
+    spec:
+      ports:
+      - name: default
+        containerPort: 7654
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+---
+apiVersion: agones.dev/v1
+kind: GameServerSet
+metadata:
+  name: octops-path-abcde
+  labels:
+    agones.dev/fleet: octops-path
+spec:
+  template:
+    metadata:
+      annotations:
+        octops.io/gameserver-ingress-mode: path
+        octops.io/gameserver-ingress-fqdn: servers.example.com
+    spec:
+      ports:
+      - name: default
+        portPolicy: Static
+        hostPort: 7300
+        containerPort: 7654
+---
+apiVersion: agones.dev/v1
+kind: GameServer
+metadata:
+  generateName: standalone-
+spec:
+  ports:
+  - containerPort: 7654
+`
+
+func Test_Decode(t *testing.T) {
+	sources, err := Decode(strings.NewReader(manifest), Options{})
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+
+	fleet := sources[0]
+	require.Equal(t, "Fleet", fleet.Kind)
+	require.Equal(t, "games", fleet.GameServer.Namespace)
+	require.Equal(t, "octops-domain-xxxxx-xxxxx", fleet.GameServer.Name)
+	require.Equal(t, agonesv1.GameServerStateReady, fleet.GameServer.Status.State)
+	require.Equal(t, "octops-domain", fleet.GameServer.Labels[gameserver.AgonesFleetNameLabel])
+	require.Equal(t, "octops-domain-xxxxx", fleet.GameServer.Labels[gameserver.AgonesGameServerSetNameLabel])
+	require.Equal(t, "us-east-1", fleet.GameServer.Labels["region"])
+	require.Equal(t, "domain", fleet.GameServer.Annotations[gameserver.OctopsAnnotationIngressMode])
+	require.Equal(t, []agonesv1.GameServerStatusPort{{Name: "default", Port: FirstHostPort}}, fleet.GameServer.Status.Ports)
+
+	gss := sources[1]
+	require.Equal(t, "GameServerSet", gss.Kind)
+	require.Equal(t, "default", gss.GameServer.Namespace)
+	require.Equal(t, "octops-path-abcde-xxxxx", gss.GameServer.Name)
+	require.Equal(t, "octops-path", gss.GameServer.Labels[gameserver.AgonesFleetNameLabel])
+	require.Equal(t, int32(7300), gss.GameServer.Status.Ports[0].Port)
+
+	gs := sources[2]
+	require.Equal(t, "standalone-", gs.Name)
+	require.Equal(t, "standalone-xxxxx", gs.GameServer.Name)
+}
+
+func Test_Decode_Options(t *testing.T) {
+	sources, err := Decode(strings.NewReader(manifest), Options{
+		Namespace: "staging",
+		Name:      "game-1",
+		State:     agonesv1.GameServerStateScheduled,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "games", sources[0].GameServer.Namespace)
+	require.Equal(t, "staging", sources[1].GameServer.Namespace)
+	for _, source := range sources {
+		require.Equal(t, "game-1", source.GameServer.Name)
+		require.Equal(t, agonesv1.GameServerStateScheduled, source.GameServer.Status.State)
+	}
+}
+
+func Test_Decode_NoGameServers(t *testing.T) {
+	_, err := Decode(strings.NewReader("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n"), Options{})
+	require.Error(t, err)
+}
+
+func Test_Write(t *testing.T) {
+	sources, err := Decode(strings.NewReader(manifest), Options{})
+	require.NoError(t, err)
+
+	plan, err := handlers.NewPlanner().Plan(context.Background(), sources[0].GameServer)
+	require.NoError(t, err)
+	require.Empty(t, plan.Errors)
+
+	objects := Objects(plan)
+	require.Len(t, objects, 3)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, objects...))
+
+	out := buf.String()
+	require.Equal(t, 3, strings.Count(out, "---\n"))
+	require.Contains(t, out, "kind: Service\n")
+	require.Contains(t, out, "kind: Ingress\n")
+	require.Contains(t, out, "kind: HTTPRoute\n")
+	require.Contains(t, out, "host: octops-domain-xxxxx-xxxxx.example.com")
+	require.NotContains(t, out, "status:")
+}
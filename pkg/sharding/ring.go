@@ -0,0 +1,103 @@
+package sharding
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultVirtualNodes is the number of points each member gets on the ring. More points spread keys evenly at the
+// cost of a larger ring.
+const DefaultVirtualNodes = 128
+
+// Ring is an immutable consistent hash ring. Adding or removing a member only moves the keys of that member.
+type Ring struct {
+	members []string
+	hashes  []uint64
+	owners  map[uint64]string
+}
+
+func NewRing(members []string, virtualNodes int) *Ring {
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+
+	sorted := append([]string(nil), members...)
+	sort.Strings(sorted)
+
+	r := &Ring{
+		members: sorted,
+		owners:  make(map[uint64]string, len(sorted)*virtualNodes),
+	}
+
+	for _, member := range sorted {
+		for i := 0; i < virtualNodes; i++ {
+			h := hash(member + "#" + strconv.Itoa(i))
+			// Collisions are resolved in favour of the lowest member name so every replica builds the same ring
+			if owner, ok := r.owners[h]; ok && owner < member {
+				continue
+			}
+			if _, ok := r.owners[h]; !ok {
+				r.hashes = append(r.hashes, h)
+			}
+			r.owners[h] = member
+		}
+	}
+
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+
+	return r
+}
+
+// Owner returns the member that owns the key or an empty string if the ring has no members.
+func (r *Ring) Owner(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+
+	return r.owners[r.hashes[i]]
+}
+
+func (r *Ring) Members() []string {
+	return append([]string(nil), r.members...)
+}
+
+// Equal checks if both rings have the same members.
+func (r *Ring) Equal(other *Ring) bool {
+	if r == nil || other == nil {
+		return r == other
+	}
+
+	if len(r.members) != len(other.members) {
+		return false
+	}
+
+	for i := range r.members {
+		if r.members[i] != other.members[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hash uses FNV-1a followed by the splitmix64 finalizer, FNV alone clusters keys that only differ in a suffix.
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
@@ -0,0 +1,44 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Ring_Owner(t *testing.T) {
+	ring := NewRing([]string{"replica-b", "replica-a", "replica-c"}, 0)
+	same := NewRing([]string{"replica-c", "replica-a", "replica-b"}, 0)
+
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("default/game-%d", i)
+		owner := ring.Owner(key)
+		require.Equal(t, owner, same.Owner(key), "ring must not depend on member order")
+		counts[owner]++
+	}
+
+	require.Len(t, counts, 3)
+	for member, count := range counts {
+		require.InDelta(t, 1000, count, 350, member)
+	}
+}
+
+func Test_Ring_MinimalMovement(t *testing.T) {
+	before := NewRing([]string{"replica-a", "replica-b", "replica-c"}, 0)
+	after := NewRing([]string{"replica-a", "replica-b", "replica-c", "replica-d"}, 0)
+
+	for i := 0; i < 3000; i++ {
+		key := fmt.Sprintf("default/game-%d", i)
+		if owner := after.Owner(key); owner != before.Owner(key) {
+			require.Equal(t, "replica-d", owner, "keys only move to the new member")
+		}
+	}
+}
+
+func Test_Ring_Empty(t *testing.T) {
+	require.Equal(t, "", NewRing(nil, 0).Owner("default/game-1"))
+	require.True(t, NewRing(nil, 0).Equal(NewRing([]string{}, 0)))
+	require.False(t, NewRing([]string{"a"}, 0).Equal(nil))
+}
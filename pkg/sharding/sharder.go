@@ -0,0 +1,335 @@
+package sharding
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// ShardGroupLabel is set on the membership Leases of every replica that shares the same group.
+const ShardGroupLabel = "octops.io/shard-group"
+
+type Options struct {
+	// Identity is unique for each replica, usually the Pod name.
+	Identity  string
+	Namespace string
+	// Group is the prefix of the Lease names. Replicas only shard with members of the same group.
+	Group         string
+	LeaseDuration time.Duration
+	RenewInterval time.Duration
+	VirtualNodes  int
+}
+
+// Queue is the subset of the work queue used to report its depth.
+type Queue interface {
+	Len() int
+}
+
+// Sharder splits GameServers across replicas using a consistent hash of namespace/name. Each replica renews its own
+// Lease and builds the ring from the Leases that have not expired.
+//
+// When membership changes a replica stops reconciling the keys it lost right away, but only starts reconciling the
+// keys it gained after a handoff period. That gives the previous owners time to observe the new ring, so the same
+// GameServer is never reconciled by two replicas at once and resources are not created twice.
+//
+// A replica that can't renew its Lease or list the members stops reconciling before its Lease expires, since it can't
+// tell whether another replica took over its keys.
+type Sharder struct {
+	logger   *logrus.Entry
+	client   kubernetes.Interface
+	reader   client.Reader
+	options  Options
+	now      func() time.Time
+	events   chan event.GenericEvent
+	mu       sync.RWMutex
+	ring     *Ring
+	pending  *Ring
+	activate time.Time
+	synced   time.Time
+	queue    Queue
+}
+
+func NewSharder(client kubernetes.Interface, reader client.Reader, options Options) (*Sharder, error) {
+	if len(options.Identity) == 0 {
+		return nil, errors.New("sharding requires an identity")
+	}
+
+	if len(options.Namespace) == 0 {
+		return nil, errors.New("sharding requires a namespace for the membership leases")
+	}
+
+	if options.LeaseDuration <= 0 {
+		options.LeaseDuration = time.Second * 15
+	}
+
+	if options.RenewInterval <= 0 {
+		options.RenewInterval = time.Second * 5
+	}
+
+	if options.RenewInterval >= options.LeaseDuration {
+		return nil, errors.Errorf("shard renew interval %s must be shorter than the lease duration %s", options.RenewInterval, options.LeaseDuration)
+	}
+
+	return &Sharder{
+		logger:  runtime.Logger().WithFields(logrus.Fields{"component": "sharding", "shard": options.Identity}),
+		client:  client,
+		reader:  reader,
+		options: options,
+		now:     time.Now,
+		events:  make(chan event.GenericEvent, 1024),
+	}, nil
+}
+
+// Owns checks if the replica must reconcile the GameServer. It returns false until the replica joined the ring, and
+// once the last successful sync is too old to trust the ring.
+func (s *Sharder) Owns(key types.NamespacedName) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.ring == nil || s.expired() {
+		return false
+	}
+
+	owns := s.ring.Owner(key.String()) == s.options.Identity
+	if s.pending != nil {
+		// Keys moving to another member are released as soon as the change is observed
+		owns = owns && s.pending.Owner(key.String()) == s.options.Identity
+	}
+
+	return owns
+}
+
+// Events enqueues GameServers gained on a rebalance. It is meant to be used as a channel source by the controller.
+func (s *Sharder) Events() <-chan event.GenericEvent {
+	return s.events
+}
+
+// ObserveQueue sets the work queue whose depth is exported for this shard.
+func (s *Sharder) ObserveQueue(queue Queue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue = queue
+}
+
+// Start renews the membership Lease until the context is cancelled. The Lease is deleted on shutdown so the
+// remaining replicas rebalance without waiting for it to expire.
+func (s *Sharder) Start(ctx context.Context) error {
+	s.logger.Infof("sharding started, group=%s namespace=%s", s.options.Group, s.options.Namespace)
+
+	ticker := time.NewTicker(s.options.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil {
+			s.logger.WithError(err).Error("failed to sync shard membership")
+		}
+
+		select {
+		case <-ctx.Done():
+			s.leave()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns false since sharding replaces leader election.
+func (s *Sharder) NeedLeaderElection() bool {
+	return false
+}
+
+// Sync renews the Lease of the replica, refreshes the ring and activates a pending ring once the handoff is over.
+func (s *Sharder) Sync(ctx context.Context) error {
+	started := s.now()
+	if err := s.renew(ctx); err != nil {
+		return err
+	}
+
+	members, err := s.members(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.update(ctx, NewRing(members, s.options.VirtualNodes), started)
+	s.observe()
+
+	return nil
+}
+
+func (s *Sharder) update(ctx context.Context, next *Ring, synced time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.synced = synced
+
+	if s.pending != nil && !s.pending.Equal(next) {
+		s.logger.WithField("members", next.Members()).Info("shard membership changed during handoff")
+		s.pending = next
+		s.activate = now.Add(s.handoff())
+	}
+
+	if s.pending == nil && !s.ring.Equal(next) {
+		s.logger.WithField("members", next.Members()).Info("shard membership changed")
+		s.pending = next
+		s.activate = now.Add(s.handoff())
+	}
+
+	if s.pending != nil && !now.Before(s.activate) {
+		s.ring = s.pending
+		s.pending = nil
+		metrics.ShardRebalances.Inc()
+		metrics.ShardMembers.Set(float64(len(s.ring.Members())))
+		s.logger.WithField("members", s.ring.Members()).Info("shard ring activated")
+
+		go s.resync(ctx)
+	}
+}
+
+// expired checks if the ring is stale. The Lease was renewed at the start of the last successful sync, so the replica
+// stops owning keys one renew interval before the Lease expires and other replicas may take them over.
+func (s *Sharder) expired() bool {
+	return !s.now().Before(s.synced.Add(s.options.LeaseDuration - s.options.RenewInterval))
+}
+
+// handoff is the time other replicas need to observe a membership change. A replica that failed to sync may not observe
+// it at all, but it stops owning keys within a lease duration of its last successful sync.
+func (s *Sharder) handoff() time.Duration {
+	return s.options.LeaseDuration
+}
+
+// resync enqueues every GameServer owned by the replica, so keys gained on a rebalance are reconciled right away.
+func (s *Sharder) resync(ctx context.Context) {
+	if s.reader == nil {
+		return
+	}
+
+	list := &agonesv1.GameServerList{}
+	if err := s.reader.List(ctx, list); err != nil {
+		s.logger.WithError(err).Error("failed to list gameservers for resync")
+		return
+	}
+
+	var owned int
+	for i := range list.Items {
+		gs := &list.Items[i]
+		if !s.Owns(types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}) {
+			continue
+		}
+
+		owned++
+		select {
+		case s.events <- event.GenericEvent{Object: gs}:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	metrics.ShardOwnedGameServers.WithLabelValues(s.options.Identity).Set(float64(owned))
+}
+
+func (s *Sharder) observe() {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.queue != nil {
+		metrics.ShardQueueDepth.WithLabelValues(s.options.Identity).Set(float64(s.queue.Len()))
+	}
+}
+
+func (s *Sharder) leaseName() string {
+	return s.options.Group + "-" + s.options.Identity
+}
+
+func (s *Sharder) renew(ctx context.Context) error {
+	leases := s.client.CoordinationV1().Leases(s.options.Namespace)
+	now := metav1.NewMicroTime(s.now())
+
+	lease, err := leases.Get(ctx, s.leaseName(), metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get lease %s", s.leaseName())
+		}
+
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.leaseName(),
+				Namespace: s.options.Namespace,
+				Labels:    map[string]string{ShardGroupLabel: s.options.Group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(s.options.Identity),
+				LeaseDurationSeconds: ptr.To(int32(s.options.LeaseDuration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metav1.CreateOptions{})
+
+		return errors.Wrapf(err, "failed to create lease %s", s.leaseName())
+	}
+
+	lease.Spec.HolderIdentity = ptr.To(s.options.Identity)
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.options.LeaseDuration.Seconds()))
+	lease.Spec.RenewTime = &now
+	if _, err := leases.Update(ctx, lease, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrapf(err, "failed to renew lease %s", s.leaseName())
+	}
+
+	return nil
+}
+
+// members returns the identities of every Lease of the group that has not expired.
+func (s *Sharder) members(ctx context.Context) ([]string, error) {
+	list, err := s.client.CoordinationV1().Leases(s.options.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{ShardGroupLabel: s.options.Group}).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list shard leases")
+	}
+
+	now := s.now()
+	var members []string
+	for _, lease := range list.Items {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+
+		expires := lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+		if now.After(expires) {
+			continue
+		}
+
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+
+	return members, nil
+}
+
+func (s *Sharder) leave() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	err := s.client.CoordinationV1().Leases(s.options.Namespace).Delete(ctx, s.leaseName(), metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		s.logger.WithError(err).Warn("failed to delete shard lease")
+		return
+	}
+
+	s.logger.Info("shard lease released")
+}
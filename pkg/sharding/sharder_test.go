@@ -0,0 +1,150 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestSharder(t *testing.T, client *fake.Clientset, identity string, now *time.Time) *Sharder {
+	s, err := NewSharder(client, nil, Options{
+		Identity:      identity,
+		Namespace:     "octops-system",
+		Group:         "octops",
+		LeaseDuration: time.Second * 15,
+		RenewInterval: time.Second * 5,
+	})
+	require.NoError(t, err)
+	s.now = func() time.Time { return *now }
+
+	return s
+}
+
+func syncAll(t *testing.T, sharders ...*Sharder) {
+	for _, s := range sharders {
+		require.NoError(t, s.Sync(context.Background()))
+	}
+}
+
+// owners returns how many sharders own each key.
+func owners(sharders ...*Sharder) map[string]int {
+	result := map[string]int{}
+	for i := 0; i < 500; i++ {
+		key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("game-%d", i)}
+		result[key.String()] = 0
+		for _, s := range sharders {
+			if s.Owns(key) {
+				result[key.String()]++
+			}
+		}
+	}
+
+	return result
+}
+
+func Test_Sharder_Rebalance(t *testing.T) {
+	client := fake.NewClientset()
+	now := time.Now()
+
+	a := newTestSharder(t, client, "replica-a", &now)
+	syncAll(t, a)
+	for _, count := range owners(a) {
+		require.Equal(t, 0, count, "keys are not owned before the handoff")
+	}
+
+	now = now.Add(time.Second * 15)
+	syncAll(t, a)
+	for _, count := range owners(a) {
+		require.Equal(t, 1, count)
+	}
+
+	// replica-b joins, both replicas observe it before the handoff is over
+	b := newTestSharder(t, client, "replica-b", &now)
+	syncAll(t, b, a)
+	for key, count := range owners(a, b) {
+		require.LessOrEqual(t, count, 1, "key %s owned twice during handoff", key)
+	}
+
+	now = now.Add(time.Second * 15)
+	syncAll(t, a, b)
+	moved := 0
+	for key, count := range owners(a, b) {
+		require.Equal(t, 1, count, key)
+		if b.Owns(parseKey(key)) {
+			moved++
+		}
+	}
+	require.NotZero(t, moved)
+
+	// replica-a stops renewing and its lease expires
+	now = now.Add(time.Second * 20)
+	syncAll(t, b)
+	now = now.Add(time.Second * 15)
+	syncAll(t, b)
+	for key, count := range owners(b) {
+		require.Equal(t, 1, count, key)
+	}
+}
+
+func Test_Sharder_SyncFailure(t *testing.T) {
+	client := fake.NewClientset()
+	now := time.Now()
+
+	a := newTestSharder(t, client, "replica-a", &now)
+	b := newTestSharder(t, client, "replica-b", &now)
+	syncAll(t, a, b)
+	now = now.Add(time.Second * 15)
+	syncAll(t, a, b)
+
+	// replica-a can't renew its lease anymore but keeps its last ring
+	client.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if action.(k8stesting.UpdateAction).GetObject().(metav1.Object).GetName() == "octops-replica-a" {
+			return true, nil, errors.New("connection refused")
+		}
+		return false, nil, nil
+	})
+
+	for i := 0; i < 8; i++ {
+		now = now.Add(time.Second * 5)
+		require.Error(t, a.Sync(context.Background()))
+		require.NoError(t, b.Sync(context.Background()))
+		for key, count := range owners(a, b) {
+			require.LessOrEqual(t, count, 1, "key %s owned twice after %d failed syncs", key, i+1)
+		}
+	}
+
+	for key, count := range owners(a) {
+		require.Equal(t, 0, count, key)
+	}
+	for key, count := range owners(b) {
+		require.Equal(t, 1, count, key)
+	}
+}
+
+func Test_Sharder_Leave(t *testing.T) {
+	client := fake.NewClientset()
+	now := time.Now()
+
+	a := newTestSharder(t, client, "replica-a", &now)
+	syncAll(t, a)
+	a.leave()
+
+	members, err := a.members(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, members)
+}
+
+func parseKey(key string) types.NamespacedName {
+	ns, name, _ := strings.Cut(key, "/")
+	return types.NamespacedName{Namespace: ns, Name: name}
+}
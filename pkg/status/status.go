@@ -0,0 +1,332 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"agones.dev/agones/pkg/client/clientset/versioned"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+)
+
+const (
+	// Missing is reported for resources that don't exist
+	Missing = "missing"
+	// Pending is reported for routes without a status yet
+	Pending = "pending"
+	// None is reported for resources the GameServer doesn't request
+	None = "-"
+)
+
+// Clients read the cluster. Gateway is optional, HTTPRoutes are reported as missing without it.
+type Clients struct {
+	Agones     versioned.Interface
+	Kubernetes kubernetes.Interface
+	Gateway    gatewayclient.Interface
+}
+
+type Options struct {
+	// Namespace is empty for all namespaces
+	Namespace string
+	// Fleet only reports the GameServers of the Fleet, if set
+	Fleet           string
+	ControllerClass string
+	// NamespaceDefaults resolves the annotations a GameServer doesn't set from its Namespace
+	NamespaceDefaults bool
+	// Defaults are the controller default annotations, see the controller flag with the same name
+	Defaults map[string]string
+}
+
+// Row is the routing health of a managed GameServer.
+type Row struct {
+	Namespace    string                   `json:"namespace"`
+	Name         string                   `json:"name"`
+	State        agonesv1.GameServerState `json:"state"`
+	URL          string                   `json:"url"`
+	Backends     string                   `json:"backends"`
+	Service      string                   `json:"service"`
+	Ingress      string                   `json:"ingress"`
+	HTTPRoute    string                   `json:"httpRoute"`
+	IngressReady bool                     `json:"ingressReady"`
+	// Mismatches are the reasons the routing state doesn't match the GameServer
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// Healthy checks that the routing state matches the GameServer.
+func (r Row) Healthy() bool {
+	return len(r.Mismatches) == 0
+}
+
+func (r *Row) mismatchf(format string, args ...interface{}) {
+	r.Mismatches = append(r.Mismatches, fmt.Sprintf(format, args...))
+}
+
+// Check reports the routing health of the GameServers managed by a controller instance configured with the options.
+// GameServers without the octops.io/gameserver-ingress-mode annotation, after defaults are applied, are not reported.
+func Check(ctx context.Context, clients Clients, options Options) ([]Row, error) {
+	selector := labels.Everything()
+	if len(options.Fleet) > 0 {
+		selector = labels.SelectorFromSet(labels.Set{gameserver.AgonesFleetNameLabel: options.Fleet})
+	}
+
+	list, err := clients.Agones.AgonesV1().GameServers(options.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list gameservers")
+	}
+
+	gameservers := make([]*agonesv1.GameServer, 0, len(list.Items))
+	for i := range list.Items {
+		gameservers = append(gameservers, &list.Items[i])
+	}
+	if len(gameservers) == 0 {
+		return nil, nil
+	}
+
+	r, err := newResources(ctx, clients, options.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	for _, gs := range gameservers {
+		if !gameserver.MatchesControllerClass(gs, options.ControllerClass) {
+			continue
+		}
+
+		var namespace map[string]string
+		if options.NamespaceDefaults {
+			if namespace, err = r.namespace(ctx, gs.Namespace); err != nil {
+				return nil, err
+			}
+		}
+
+		resolved := gameserver.WithDefaults(gs, namespace, options.Defaults)
+		if _, ok := gameserver.HasAnnotation(resolved, gameserver.OctopsAnnotationIngressMode); !ok {
+			continue
+		}
+
+		rows = append(rows, r.row(resolved))
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Namespace != rows[j].Namespace {
+			return rows[i].Namespace < rows[j].Namespace
+		}
+		return rows[i].Name < rows[j].Name
+	})
+
+	return rows, nil
+}
+
+// resources indexes the Services, Ingresses and HTTPRoutes created for GameServers by namespace/name.
+type resources struct {
+	client     kubernetes.Interface
+	services   map[string]*corev1.Service
+	ingresses  map[string]*networkingv1.Ingress
+	routes     map[string]*gatewayv1.HTTPRoute
+	namespaces map[string]map[string]string
+}
+
+func newResources(ctx context.Context, clients Clients, namespace string) (*resources, error) {
+	r := &resources{
+		client:     clients.Kubernetes,
+		services:   map[string]*corev1.Service{},
+		ingresses:  map[string]*networkingv1.Ingress{},
+		routes:     map[string]*gatewayv1.HTTPRoute{},
+		namespaces: map[string]map[string]string{},
+	}
+
+	opts := metav1.ListOptions{LabelSelector: gameserver.ManagedSelector().String()}
+
+	services, err := clients.Kubernetes.CoreV1().Services(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list services")
+	}
+	for i := range services.Items {
+		r.services[k8sutil.Namespaced(&services.Items[i])] = &services.Items[i]
+	}
+
+	ingresses, err := clients.Kubernetes.NetworkingV1().Ingresses(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list ingresses")
+	}
+	for i := range ingresses.Items {
+		r.ingresses[k8sutil.Namespaced(&ingresses.Items[i])] = &ingresses.Items[i]
+	}
+
+	if clients.Gateway == nil {
+		return r, nil
+	}
+
+	routes, err := clients.Gateway.GatewayV1().HTTPRoutes(namespace).List(ctx, opts)
+	if err != nil {
+		// The Gateway API CRDs are not installed
+		if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return r, nil
+		}
+		return nil, errors.Wrap(err, "failed to list httproutes")
+	}
+	for i := range routes.Items {
+		r.routes[k8sutil.Namespaced(&routes.Items[i])] = &routes.Items[i]
+	}
+
+	return r, nil
+}
+
+// namespace returns the annotations of a Namespace, missing Namespaces have none.
+func (r *resources) namespace(ctx context.Context, name string) (map[string]string, error) {
+	if annotations, ok := r.namespaces[name]; ok {
+		return annotations, nil
+	}
+
+	ns, err := r.client.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, errors.Wrapf(err, "failed to get namespace %s", name)
+	}
+
+	var annotations map[string]string
+	if err == nil {
+		annotations = ns.Annotations
+	}
+	r.namespaces[name] = annotations
+
+	return annotations, nil
+}
+
+func (r *resources) row(gs *agonesv1.GameServer) Row {
+	key := k8sutil.Namespaced(gs)
+	row := Row{
+		Namespace:    gs.Namespace,
+		Name:         gs.Name,
+		State:        gs.Status.State,
+		URL:          url(gs),
+		Backends:     gameserver.RouterBackendsString(gs),
+		Service:      Missing,
+		Ingress:      None,
+		HTTPRoute:    None,
+		IngressReady: gs.Annotations[gameserver.OctopsAnnotationGameServerIngressReady] == "true",
+	}
+
+	if len(row.URL) == 0 {
+		row.URL = None
+		if _, err := gameserver.GetRouteTargets(gs); err != nil {
+			row.mismatchf("%s", err)
+		}
+	}
+
+	// Routes are only created for GameServers that can receive players
+	routable := gameserver.MustReconcile(gs) || gs.Status.State == agonesv1.GameServerStateAllocated
+
+	_, hasService := r.services[key]
+	if hasService {
+		row.Service = "found"
+	} else if routable {
+		row.mismatchf("service is missing")
+	}
+
+	for _, backend := range gameserver.GetRouterBackends(gs) {
+		switch backend {
+		case gameserver.RouterBackendGateway:
+			row.HTTPRoute = Missing
+			if route, ok := r.routes[key]; ok {
+				row.HTTPRoute = accepted(route, &row)
+			} else if routable {
+				row.mismatchf("httproute is missing")
+			}
+		default:
+			row.Ingress = Missing
+			if ingress, ok := r.ingresses[key]; ok {
+				row.Ingress = loadBalancer(ingress, &row)
+			} else if routable {
+				row.mismatchf("ingress is missing")
+			}
+		}
+	}
+
+	switch {
+	case row.IngressReady && !row.Healthy():
+		row.mismatchf("annotated with %s but the routes are not ready", gameserver.OctopsAnnotationGameServerIngressReady)
+	case !row.IngressReady && row.Healthy() && gameserver.MustReconcile(gs) && gs.Status.State != agonesv1.GameServerStateScheduled:
+		row.mismatchf("routes are ready but the gameserver is not annotated with %s", gameserver.OctopsAnnotationGameServerIngressReady)
+	}
+
+	return row
+}
+
+// loadBalancer returns the addresses published by the ingress controller.
+func loadBalancer(ingress *networkingv1.Ingress, row *Row) string {
+	var addresses []string
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if len(lb.IP) > 0 {
+			addresses = append(addresses, lb.IP)
+		}
+		if len(lb.Hostname) > 0 {
+			addresses = append(addresses, lb.Hostname)
+		}
+	}
+
+	if len(addresses) == 0 {
+		row.mismatchf("ingress has no load balancer address")
+		return Pending
+	}
+
+	return strings.Join(addresses, ",")
+}
+
+// accepted returns the Accepted condition reported by the Gateways the HTTPRoute is attached to.
+func accepted(route *gatewayv1.HTTPRoute, row *Row) string {
+	if len(route.Status.Parents) == 0 {
+		row.mismatchf("httproute has no status from a gateway")
+		return Pending
+	}
+
+	result := string(gatewayv1.RouteConditionAccepted)
+	for _, parent := range route.Status.Parents {
+		condition := meta.FindStatusCondition(parent.Conditions, string(gatewayv1.RouteConditionAccepted))
+		if condition == nil {
+			row.mismatchf("httproute has no %s condition from gateway %s", gatewayv1.RouteConditionAccepted, parent.ParentRef.Name)
+			result = Pending
+			continue
+		}
+
+		if condition.Status != metav1.ConditionTrue {
+			row.mismatchf("httproute is not accepted by gateway %s: %s", parent.ParentRef.Name, condition.Reason)
+			result = "Not" + string(gatewayv1.RouteConditionAccepted)
+		}
+	}
+
+	return result
+}
+
+// url returns the public URLs of the GameServer, using https if the Ingress terminates TLS.
+func url(gs *agonesv1.GameServer) string {
+	targets, err := gameserver.GetRouteTargets(gs)
+	if err != nil {
+		return ""
+	}
+
+	scheme := "http"
+	if gs.Annotations[gameserver.OctopsAnnotationTerminateTLS] == "true" {
+		scheme = "https"
+	}
+
+	urls := make([]string, len(targets))
+	for i, t := range targets {
+		urls[i] = fmt.Sprintf("%s://%s%s", scheme, t.Host, t.Path)
+	}
+
+	return strings.Join(urls, ",")
+}
@@ -0,0 +1,255 @@
+package status
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	agonesfake "agones.dev/agones/pkg/client/clientset/versioned/fake"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayfake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
+)
+
+func newGameServer(name string, state agonesv1.GameServerState, annotations map[string]string) *agonesv1.GameServer {
+	return &agonesv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "games",
+			Labels:      map[string]string{gameserver.AgonesFleetNameLabel: "octops"},
+			Annotations: annotations,
+		},
+		Status: agonesv1.GameServerStatus{
+			State: state,
+			Ports: []agonesv1.GameServerStatusPort{{Name: "default", Port: 7000}},
+		},
+	}
+}
+
+func managed(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "games",
+		Labels:    map[string]string{gameserver.AgonesGameServerNameLabel: name},
+	}
+}
+
+func newService(name string) *corev1.Service {
+	return &corev1.Service{ObjectMeta: managed(name)}
+}
+
+func newIngress(name string, ip string) *networkingv1.Ingress {
+	ingress := &networkingv1.Ingress{ObjectMeta: managed(name)}
+	if len(ip) > 0 {
+		ingress.Status.LoadBalancer.Ingress = []networkingv1.IngressLoadBalancerIngress{{IP: ip}}
+	}
+	return ingress
+}
+
+func newHTTPRoute(name string, accepted metav1.ConditionStatus) *gatewayv1.HTTPRoute {
+	route := &gatewayv1.HTTPRoute{ObjectMeta: managed(name)}
+	route.Status.Parents = []gatewayv1.RouteParentStatus{{
+		ParentRef:      gatewayv1.ParentReference{Name: "gateway"},
+		ControllerName: "example.com/gateway-controller",
+		Conditions: []metav1.Condition{{
+			Type:   string(gatewayv1.RouteConditionAccepted),
+			Status: accepted,
+			Reason: "NotAllowedByListeners",
+		}},
+	}}
+	return route
+}
+
+func newClients(agones []runtime.Object, k8s []runtime.Object, routes ...runtime.Object) Clients {
+	return Clients{
+		Agones:     agonesfake.NewSimpleClientset(agones...),
+		Kubernetes: fake.NewSimpleClientset(k8s...),
+		Gateway:    gatewayfake.NewSimpleClientset(routes...),
+	}
+}
+
+var domain = map[string]string{
+	gameserver.OctopsAnnotationIngressMode:   "domain",
+	gameserver.OctopsAnnotationIngressDomain: "example.com",
+}
+
+func with(annotations map[string]string, kv ...string) map[string]string {
+	result := map[string]string{}
+	for k, v := range annotations {
+		result[k] = v
+	}
+	for i := 0; i < len(kv); i += 2 {
+		result[kv[i]] = kv[i+1]
+	}
+	return result
+}
+
+func Test_Check(t *testing.T) {
+	testCases := []struct {
+		name       string
+		gs         *agonesv1.GameServer
+		objects    []runtime.Object
+		routes     []runtime.Object
+		expected   Row
+		mismatches []string
+	}{
+		{
+			name:    "routed ingress",
+			gs:      newGameServer("game-1", agonesv1.GameServerStateReady, with(domain, gameserver.OctopsAnnotationTerminateTLS, "true", gameserver.OctopsAnnotationGameServerIngressReady, "true")),
+			objects: []runtime.Object{newService("game-1"), newIngress("game-1", "10.0.0.1")},
+			expected: Row{
+				URL:          "https://game-1.example.com/",
+				Backends:     "ingress",
+				Service:      "found",
+				Ingress:      "10.0.0.1",
+				HTTPRoute:    None,
+				IngressReady: true,
+			},
+		},
+		{
+			name:    "ingress without load balancer",
+			gs:      newGameServer("game-1", agonesv1.GameServerStateReady, with(domain, gameserver.OctopsAnnotationGameServerIngressReady, "true")),
+			objects: []runtime.Object{newService("game-1"), newIngress("game-1", "")},
+			expected: Row{
+				URL:          "http://game-1.example.com/",
+				Backends:     "ingress",
+				Service:      "found",
+				Ingress:      Pending,
+				HTTPRoute:    None,
+				IngressReady: true,
+			},
+			mismatches: []string{
+				"ingress has no load balancer address",
+				"annotated with octops.io/ingress-ready but the routes are not ready",
+			},
+		},
+		{
+			name:   "httproute not accepted",
+			gs:     newGameServer("game-1", agonesv1.GameServerStateAllocated, with(domain, gameserver.OctopsAnnotationRouterBackend, "gateway")),
+			routes: []runtime.Object{newHTTPRoute("game-1", metav1.ConditionFalse)},
+			expected: Row{
+				URL:       "http://game-1.example.com/",
+				Backends:  "gateway",
+				Service:   Missing,
+				Ingress:   None,
+				HTTPRoute: "NotAccepted",
+			},
+			mismatches: []string{
+				"service is missing",
+				"httproute is not accepted by gateway gateway: NotAllowedByListeners",
+			},
+		},
+		{
+			name:    "ready routes without ingress-ready",
+			gs:      newGameServer("game-1", agonesv1.GameServerStateReady, with(domain, gameserver.OctopsAnnotationRouterBackend, "ingress,gateway")),
+			objects: []runtime.Object{newService("game-1"), newIngress("game-1", "10.0.0.1")},
+			routes:  []runtime.Object{newHTTPRoute("game-1", metav1.ConditionTrue)},
+			expected: Row{
+				URL:       "http://game-1.example.com/",
+				Backends:  "ingress,gateway",
+				Service:   "found",
+				Ingress:   "10.0.0.1",
+				HTTPRoute: "Accepted",
+			},
+			mismatches: []string{"routes are ready but the gameserver is not annotated with octops.io/ingress-ready"},
+		},
+		{
+			name: "starting gameserver",
+			gs:   newGameServer("game-1", agonesv1.GameServerStateCreating, with(domain)),
+			expected: Row{
+				URL:       "http://game-1.example.com/",
+				Backends:  "ingress",
+				Service:   Missing,
+				Ingress:   Missing,
+				HTTPRoute: None,
+			},
+		},
+		{
+			name: "missing domain",
+			gs: newGameServer("game-1", agonesv1.GameServerStateScheduled, map[string]string{
+				gameserver.OctopsAnnotationIngressMode: "domain",
+			}),
+			expected: Row{
+				URL:       None,
+				Backends:  "ingress",
+				Service:   Missing,
+				Ingress:   Missing,
+				HTTPRoute: None,
+			},
+			mismatches: []string{
+				"ingress routing mode domain requires the annotation octops.io/gameserver-ingress-domain to be set on gameserver games/game-1",
+				"service is missing",
+				"ingress is missing",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clients := newClients([]runtime.Object{tc.gs}, tc.objects, tc.routes...)
+
+			rows, err := Check(context.Background(), clients, Options{Namespace: "games"})
+			require.NoError(t, err)
+			require.Len(t, rows, 1)
+
+			tc.expected.Namespace = "games"
+			tc.expected.Name = tc.gs.Name
+			tc.expected.State = tc.gs.Status.State
+			tc.expected.Mismatches = tc.mismatches
+			require.Equal(t, tc.expected, rows[0])
+		})
+	}
+}
+
+func Test_Check_Filters(t *testing.T) {
+	other := newGameServer("game-2", agonesv1.GameServerStateReady, domain)
+	other.Labels[gameserver.AgonesFleetNameLabel] = "other"
+
+	class := newGameServer("game-3", agonesv1.GameServerStateReady, with(domain, gameserver.OctopsAnnotationControllerClass, "internal"))
+	unmanaged := newGameServer("game-4", agonesv1.GameServerStateReady, nil)
+	inherited := newGameServer("game-5", agonesv1.GameServerStateReady, nil)
+	inherited.Namespace = "defaults"
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "defaults", Annotations: domain}}
+
+	clients := newClients([]runtime.Object{
+		newGameServer("game-1", agonesv1.GameServerStateReady, domain), other, class, unmanaged, inherited,
+	}, []runtime.Object{ns})
+	clients.Gateway = nil
+
+	rows, err := Check(context.Background(), clients, Options{Fleet: "octops", NamespaceDefaults: true})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "defaults/game-5", rows[0].Namespace+"/"+rows[0].Name)
+	require.Equal(t, "http://game-5.example.com/", rows[0].URL)
+	require.Equal(t, "games/game-1", rows[1].Namespace+"/"+rows[1].Name)
+
+	rows, err = Check(context.Background(), clients, Options{Namespace: "games", ControllerClass: "internal"})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, "game-3", rows[0].Name)
+}
+
+func Test_Write(t *testing.T) {
+	rows := []Row{
+		{Namespace: "games", Name: "game-1", State: agonesv1.GameServerStateReady, URL: "http://game-1.example.com/", Backends: "ingress", Service: "found", Ingress: "10.0.0.1", HTTPRoute: None, IngressReady: true},
+		{Namespace: "games", Name: "game-2", State: agonesv1.GameServerStateReady, URL: "http://game-2.example.com/", Backends: "ingress", Service: Missing, Ingress: Missing, HTTPRoute: None, Mismatches: []string{"service is missing"}},
+	}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Write(buf, rows))
+	require.Equal(t, `NAMESPACE  NAME    STATE  URL                         BACKEND  SERVICE  INGRESS   HTTPROUTE  INGRESS-READY  HEALTH
+games      game-1  Ready  http://game-1.example.com/  ingress  found    10.0.0.1  -          true           ok
+games      game-2  Ready  http://game-2.example.com/  ingress  missing  missing   -          false          MISMATCH
+
+Mismatches:
+  games/game-2: service is missing
+`, buf.String())
+}
@@ -0,0 +1,39 @@
+package status
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Write tabulates the rows. Rows with mismatches are flagged and their mismatches are listed after the table.
+func Write(w io.Writer, rows []Row) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAMESPACE\tNAME\tSTATE\tURL\tBACKEND\tSERVICE\tINGRESS\tHTTPROUTE\tINGRESS-READY\tHEALTH")
+	for _, r := range rows {
+		health := "ok"
+		if !r.Healthy() {
+			health = "MISMATCH"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Namespace, r.Name, r.State, r.URL, r.Backends, r.Service, r.Ingress, r.HTTPRoute, strconv.FormatBool(r.IngressReady), health)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	header := false
+	for _, r := range rows {
+		for _, m := range r.Mismatches {
+			if !header {
+				fmt.Fprintln(w, "\nMismatches:")
+				header = true
+			}
+			fmt.Fprintf(w, "  %s/%s: %s\n", r.Namespace, r.Name, m)
+		}
+	}
+
+	return nil
+}
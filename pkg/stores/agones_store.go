@@ -6,10 +6,16 @@ import (
 	"agones.dev/agones/pkg/client/informers/externalversions"
 	v1 "agones.dev/agones/pkg/client/informers/externalversions/agones/v1"
 	"context"
-	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"encoding/json"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 	"github.com/pkg/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"time"
@@ -17,40 +23,165 @@ import (
 
 type AgonesStore struct {
 	*versioned.Clientset
-	v1.GameServerInformer
+	informers    *informerSet[v1.GameServerInformer]
+	resyncPeriod time.Duration
+	namespaced   bool
+	selector     labels.Selector
+	dryRun       *dryRun
+	cancels      namespaceCancels
 }
 
-func NewAgonesStore(ctx context.Context, config *rest.Config, resyncPeriod time.Duration) (*AgonesStore, error) {
+type AgonesStoreOption func(s *AgonesStore)
+
+// AgonesNamespaced does not start a cluster wide GameServer informer. Informers are started for each namespace added
+// with AddNamespace.
+func AgonesNamespaced() AgonesStoreOption {
+	return func(s *AgonesStore) {
+		s.namespaced = true
+	}
+}
+
+// WithGameServerSelector only caches the GameServers matching the label selector.
+func WithGameServerSelector(selector labels.Selector) AgonesStoreOption {
+	return func(s *AgonesStore) {
+		s.selector = selector
+	}
+}
+
+// AgonesDryRun sends the patches of GameServer annotations with server-side dry-run, so GameServers are never
+// annotated. Every patch is logged and counted.
+func AgonesDryRun() AgonesStoreOption {
+	return func(s *AgonesStore) {
+		s.dryRun = newDryRun()
+	}
+}
+
+func NewAgonesStore(ctx context.Context, config *rest.Config, resyncPeriod time.Duration, opts ...AgonesStoreOption) (*AgonesStore, error) {
 	agonesClient, err := versioned.NewForConfig(config)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not create the agones api clientset")
 	}
 
-	agonesInformerFactory := externalversions.NewSharedInformerFactory(agonesClient, resyncPeriod)
-	gameservers := agonesInformerFactory.Agones().V1().GameServers()
-
-	go agonesInformerFactory.Start(ctx.Done())
+	store := &AgonesStore{
+		Clientset:    agonesClient,
+		informers:    newInformerSet[v1.GameServerInformer](),
+		resyncPeriod: resyncPeriod,
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
 
-	store := &AgonesStore{agonesClient, gameservers}
+	if store.namespaced {
+		return store, nil
+	}
 
-	if err := store.HasSynced(ctx); err != nil {
+	syncFunc := store.startInformer(ctx, metav1.NamespaceAll)
+	if err := hasSynced(ctx, "Agones", []cache.InformerSynced{syncFunc}); err != nil {
 		return nil, errors.Wrap(err, "Agones failed to sync cache")
 	}
 
 	return store, nil
 }
 
-func (s *AgonesStore) UpdateGameServer(ctx context.Context, gs *agonesv1.GameServer) (*agonesv1.GameServer, error) {
-	result, err := s.AgonesV1().GameServers(gs.Namespace).Update(ctx, gs, metav1.UpdateOptions{})
+// AddNamespace starts the GameServer informer of a namespace and waits for it to sync.
+func (s *AgonesStore) AddNamespace(ctx context.Context, namespace string) error {
+	nsCtx, cancel := context.WithCancel(ctx)
+	if !s.cancels.add(namespace, cancel) {
+		cancel()
+		return nil
+	}
+
+	syncFunc := s.startInformer(nsCtx, namespace)
+
+	stopper, stop := context.WithTimeout(nsCtx, time.Second*30)
+	defer stop()
+
+	if !cache.WaitForCacheSync(stopper.Done(), syncFunc) {
+		s.RemoveNamespace(namespace)
+		return errors.Errorf("timed out waiting for Agones cache of namespace %s to sync", namespace)
+	}
+
+	return nil
+}
+
+// RemoveNamespace stops the GameServer informer of a namespace.
+func (s *AgonesStore) RemoveNamespace(namespace string) {
+	s.informers.delete(namespace)
+	s.cancels.remove(namespace)
+}
+
+// HasSynced checks that the GameServer informers of every watched namespace have synced.
+func (s *AgonesStore) HasSynced() bool {
+	return synced(s.informers)
+}
+
+func (s *AgonesStore) startInformer(ctx context.Context, namespace string) cache.InformerSynced {
+	factory := externalversions.NewSharedInformerFactoryWithOptions(
+		s.Clientset,
+		s.resyncPeriod,
+		externalversions.WithNamespace(namespace),
+		externalversions.WithTransform(gameserver.Transform),
+		externalversions.WithTweakListOptions(func(options *metav1.ListOptions) {
+			if s.selector != nil {
+				options.LabelSelector = s.selector.String()
+			}
+		}),
+	)
+	gameservers := factory.Agones().V1().GameServers()
+	syncFunc := gameservers.Informer().HasSynced
+	factory.Start(ctx.Done())
+
+	s.informers.set(namespace, gameservers)
+
+	return syncFunc
+}
+
+// PatchGameServerAnnotations sets annotations using a merge patch. The GameServer in the cache is stripped of fields
+// the controller does not read, so it must never be sent back to the API using an update.
+func (s *AgonesStore) PatchGameServerAnnotations(ctx context.Context, gs *agonesv1.GameServer, annotations map[string]string, remove ...string) (*agonesv1.GameServer, error) {
+	values := make(map[string]interface{}, len(annotations)+len(remove))
+	for k, v := range annotations {
+		values[k] = v
+	}
+	// A null value removes the annotation
+	for _, k := range remove {
+		values[k] = nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": values,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode patch for gameserver %s", k8sutil.Namespaced(gs))
+	}
+
+	ctx, span := tracing.Start(ctx, "PatchGameServerAnnotations", tracing.GameServer(gs)...)
+	result, err := s.AgonesV1().GameServers(gs.Namespace).Patch(ctx, gs.Name, types.MergePatchType, patch, s.dryRun.patch(metav1.PatchOptions{}))
+	tracing.End(span, err)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to update gameserver %s", k8sutil.Namespaced(gs))
+		return nil, errors.Wrapf(err, "failed to patch gameserver %s", k8sutil.Namespaced(gs))
 	}
 
+	s.dryRun.recordPatch(record.GameServerKind, gs, patch)
+
 	return result, nil
 }
 
 func (s *AgonesStore) GetGameServer(ctx context.Context, name, namespace string) (*agonesv1.GameServer, error) {
-	result, err := s.GameServerInformer.Lister().GameServers(namespace).Get(name)
+	informer, err := s.informers.get(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := informer.Lister().GameServers(namespace).Get(name)
+	if k8serrors.IsNotFound(err) && s.selector != nil && !s.selector.Empty() {
+		// GameServers not matching the selector are not cached, they must not be mistaken for deleted ones
+		spanCtx, span := tracing.Start(ctx, "GetGameServer", tracing.Request(types.NamespacedName{Namespace: namespace, Name: name})...)
+		result, err = s.AgonesV1().GameServers(namespace).Get(spanCtx, name, metav1.GetOptions{})
+		tracing.End(span, err)
+	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to retrieve gameserver %s/%s", namespace, name)
 	}
@@ -58,19 +189,27 @@ func (s *AgonesStore) GetGameServer(ctx context.Context, name, namespace string)
 	return result, nil
 }
 
-func (s *AgonesStore) HasSynced(ctx context.Context) error {
-	gsInformer := s.Informer()
+// ListGameServers returns the GameServers from the cache of a namespace, or of every watched namespace if the
+// namespace is empty.
+func (s *AgonesStore) ListGameServers(namespace string) ([]*agonesv1.GameServer, error) {
+	if len(namespace) > 0 {
+		informer, err := s.informers.get(namespace)
+		if err != nil {
+			return nil, err
+		}
 
-	f := func() error {
-		stopper, cancel := context.WithTimeout(ctx, time.Second*15)
-		defer cancel()
+		result, err := informer.Lister().GameServers(namespace).List(labels.Everything())
+		return result, errors.Wrapf(err, "failed to list gameservers from namespace %s", namespace)
+	}
 
-		runtime.Logger().WithField("component", "store").Info("waiting for Agones cache to sync")
-		if !cache.WaitForCacheSync(stopper.Done(), gsInformer.HasSynced) {
-			return errors.New("timed out waiting for Agones cache to sync")
+	var result []*agonesv1.GameServer
+	for _, informer := range s.informers.all() {
+		items, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list gameservers")
 		}
-		return nil
+		result = append(result, items...)
 	}
 
-	return withRetry(time.Second*5, 5, f)
+	return result, nil
 }
@@ -0,0 +1,116 @@
+package stores
+
+import (
+	"encoding/json"
+
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// dryRun sends every write with server-side dry-run, so requests are validated and admitted by the API server but
+// never persisted. Each write is logged with a diff and counted. A nil dryRun sends the writes as they are.
+type dryRun struct {
+	logger *logrus.Entry
+}
+
+func newDryRun() *dryRun {
+	return &dryRun{logger: runtime.Logger().WithField(runtime.FieldComponent, "dry_run")}
+}
+
+func (d *dryRun) create(options metav1.CreateOptions) metav1.CreateOptions {
+	if d != nil {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options
+}
+
+func (d *dryRun) update(options metav1.UpdateOptions) metav1.UpdateOptions {
+	if d != nil {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options
+}
+
+func (d *dryRun) patch(options metav1.PatchOptions) metav1.PatchOptions {
+	if d != nil {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options
+}
+
+func (d *dryRun) delete(options metav1.DeleteOptions) metav1.DeleteOptions {
+	if d != nil {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	return options
+}
+
+// record logs and counts a write accepted by the API server. The diff is a merge patch from before to after, before
+// is nil for creates. Deletes have no diff.
+func (d *dryRun) record(kind, verb string, obj metav1.Object, before, after interface{}) {
+	if d == nil {
+		return
+	}
+
+	metrics.DryRunWrites.WithLabelValues(kind, verb).Inc()
+
+	logger := d.logger.WithFields(logrus.Fields{
+		"kind":     kind,
+		"verb":     verb,
+		"resource": k8sutil.Namespaced(obj),
+	})
+	if after != nil {
+		diff, err := mergePatch(before, after)
+		if err != nil {
+			logger.WithError(err).Warn("failed to compute the diff of the write")
+		} else {
+			logger = logger.WithField("diff", diff)
+		}
+	}
+
+	logger.Infof("dry-run: %s %s %s", verb, kind, k8sutil.Namespaced(obj))
+}
+
+// recordPatch logs and counts a patch accepted by the API server, the patch is the diff.
+func (d *dryRun) recordPatch(kind string, obj metav1.Object, patch []byte) {
+	if d == nil {
+		return
+	}
+
+	metrics.DryRunWrites.WithLabelValues(kind, metrics.VerbPatch).Inc()
+	d.logger.WithFields(logrus.Fields{
+		"kind":     kind,
+		"verb":     metrics.VerbPatch,
+		"resource": k8sutil.Namespaced(obj),
+		"diff":     string(patch),
+	}).Infof("dry-run: %s %s %s", metrics.VerbPatch, kind, k8sutil.Namespaced(obj))
+}
+
+// mergePatch returns the fields that change between two objects of the same type. Lists with a patch strategy, e.g.
+// the ports of a Service, are merged by key like kubectl does.
+func mergePatch(before, after interface{}) (string, error) {
+	original := []byte("{}")
+	if before != nil {
+		b, err := json.Marshal(before)
+		if err != nil {
+			return "", err
+		}
+		original = b
+	}
+
+	modified, err := json.Marshal(after)
+	if err != nil {
+		return "", err
+	}
+
+	patch, err := strategicpatch.CreateTwoWayMergePatch(original, modified, after)
+	if err != nil {
+		return "", err
+	}
+
+	return string(patch), nil
+}
@@ -0,0 +1,105 @@
+package stores
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayfake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
+)
+
+func Test_DryRun_Options(t *testing.T) {
+	var disabled *dryRun
+	require.Empty(t, disabled.create(metav1.CreateOptions{}).DryRun)
+	require.Empty(t, disabled.delete(metav1.DeleteOptions{}).DryRun)
+
+	enabled := newDryRun()
+	require.Equal(t, []string{metav1.DryRunAll}, enabled.create(metav1.CreateOptions{}).DryRun)
+	require.Equal(t, []string{metav1.DryRunAll}, enabled.update(metav1.UpdateOptions{}).DryRun)
+	require.Equal(t, []string{metav1.DryRunAll}, enabled.patch(metav1.PatchOptions{}).DryRun)
+	require.Equal(t, []string{metav1.DryRunAll}, enabled.delete(metav1.DeleteOptions{}).DryRun)
+}
+
+func Test_DryRun_CreateService(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	var options metav1.CreateOptions
+	client.PrependReactor("create", "services", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		create := action.(k8stesting.CreateActionImpl)
+		options = create.CreateOptions
+		return true, create.Object, nil
+	})
+
+	store := newServiceStore(client)
+	store.dryRun = newDryRun()
+
+	before := testutil.ToFloat64(metrics.DryRunWrites.WithLabelValues(record.ServiceKind, metrics.VerbCreate))
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "game-1", Namespace: "games"}}
+	_, err := store.CreateService(context.Background(), service, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{metav1.DryRunAll}, options.DryRun)
+	require.Equal(t, before+1, testutil.ToFloat64(metrics.DryRunWrites.WithLabelValues(record.ServiceKind, metrics.VerbCreate)))
+}
+
+func Test_MergePatch(t *testing.T) {
+	before := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "game-1", Annotations: map[string]string{"a": "1", "b": "2"}},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 7000}},
+		},
+	}
+	after := before.DeepCopy()
+	after.Annotations["a"] = "3"
+	delete(after.Annotations, "b")
+
+	diff, err := mergePatch(before, after)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"metadata":{"annotations":{"a":"3","b":null}}}`, diff)
+
+	diff, err = mergePatch(nil, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "game-1"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"metadata":{"name":"game-1"},"spec":{},"status":{"loadBalancer":{}}}`, diff)
+}
+
+func Test_DryRun_UpdateReferenceGrant(t *testing.T) {
+	grant := &gatewayv1beta1.ReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "octops-placeholder", Namespace: "octops-system"},
+		Spec: gatewayv1beta1.ReferenceGrantSpec{
+			From: []gatewayv1beta1.ReferenceGrantFrom{{Group: gatewayv1.GroupName, Kind: "HTTPRoute", Namespace: "games"}},
+			To:   []gatewayv1beta1.ReferenceGrantTo{{Kind: "Service"}},
+		},
+	}
+	client := gatewayfake.NewSimpleClientset(grant)
+	// Writes sent with dry-run are not persisted
+	client.PrependReactor("update", "referencegrants", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		return true, action.(k8stesting.UpdateActionImpl).Object, nil
+	})
+
+	logger, hook := logtest.NewNullLogger()
+	store := newGatewayStore(client)
+	store.dryRun = &dryRun{logger: logrus.NewEntry(logger)}
+
+	updated := grant.DeepCopy()
+	updated.Spec.From = append(updated.Spec.From, gatewayv1beta1.ReferenceGrantFrom{Group: gatewayv1.GroupName, Kind: "HTTPRoute", Namespace: "arena"})
+	_, err := store.UpdateReferenceGrant(context.Background(), updated, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	// Only the spec changed, the diff is not the whole object
+	diff, ok := hook.LastEntry().Data["diff"].(string)
+	require.True(t, ok)
+	require.Contains(t, diff, "arena")
+	require.NotContains(t, diff, "metadata")
+}
@@ -4,34 +4,85 @@ import (
 	"context"
 
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 	"github.com/pkg/errors"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
 	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 	gatewayclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
 	gatewayinformersv1 "sigs.k8s.io/gateway-api/pkg/client/informers/externalversions/apis/v1"
+	gatewayinformersv1beta1 "sigs.k8s.io/gateway-api/pkg/client/informers/externalversions/apis/v1beta1"
 )
 
 type gatewayStore struct {
-	client   gatewayclient.Interface
-	informer gatewayinformersv1.HTTPRouteInformer
+	client    gatewayclient.Interface
+	informers *informerSet[gatewayinformersv1.HTTPRouteInformer]
+	// grants caches the ReferenceGrant of the placeholder backend, nil if it is not watched
+	grants gatewayinformersv1beta1.ReferenceGrantInformer
+	dryRun *dryRun
 }
 
-func newGatewayStore(client gatewayclient.Interface, informer gatewayinformersv1.HTTPRouteInformer) *gatewayStore {
-	return &gatewayStore{client: client, informer: informer}
+func newGatewayStore(client gatewayclient.Interface) *gatewayStore {
+	return &gatewayStore{client: client, informers: newInformerSet[gatewayinformersv1.HTTPRouteInformer]()}
 }
 
 func (s *gatewayStore) CreateHTTPRoute(ctx context.Context, route *gatewayv1.HTTPRoute, options metav1.CreateOptions) (*gatewayv1.HTTPRoute, error) {
-	result, err := s.client.GatewayV1().HTTPRoutes(route.Namespace).Create(ctx, route, options)
+	ctx, span := tracing.Start(ctx, "CreateHTTPRoute", tracing.Object(route)...)
+	result, err := s.client.GatewayV1().HTTPRoutes(route.Namespace).Create(ctx, route, s.dryRun.create(options))
+	tracing.End(span, err)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create HTTPRoute %s", k8sutil.Namespaced(route))
 	}
 
+	s.dryRun.record(record.HTTPRouteKind, metrics.VerbCreate, route, nil, route)
+
+	return result, nil
+}
+
+func (s *gatewayStore) UpdateHTTPRoute(ctx context.Context, route *gatewayv1.HTTPRoute, options metav1.UpdateOptions) (*gatewayv1.HTTPRoute, error) {
+	ctx, span := tracing.Start(ctx, "UpdateHTTPRoute", tracing.Object(route)...)
+	result, err := s.client.GatewayV1().HTTPRoutes(route.Namespace).Update(ctx, route, s.dryRun.update(options))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update HTTPRoute %s", k8sutil.Namespaced(route))
+	}
+
+	if s.dryRun != nil {
+		var before interface{}
+		if cached, err := s.GetHTTPRoute(route.Name, route.Namespace); err == nil {
+			before = cached
+		}
+		s.dryRun.record(record.HTTPRouteKind, metrics.VerbUpdate, route, before, route)
+	}
+
 	return result, nil
 }
 
+func (s *gatewayStore) DeleteHTTPRoute(ctx context.Context, route *gatewayv1.HTTPRoute, options metav1.DeleteOptions) error {
+	ctx, span := tracing.Start(ctx, "DeleteHTTPRoute", tracing.Object(route)...)
+	err := s.client.GatewayV1().HTTPRoutes(route.Namespace).Delete(ctx, route.Name, s.dryRun.delete(options))
+	tracing.End(span, err)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete HTTPRoute %s", k8sutil.Namespaced(route))
+	}
+
+	s.dryRun.record(record.HTTPRouteKind, metrics.VerbDelete, route, nil, nil)
+
+	return nil
+}
+
 func (s *gatewayStore) GetHTTPRoute(name, namespace string) (*gatewayv1.HTTPRoute, error) {
-	result, err := s.informer.Lister().HTTPRoutes(namespace).Get(name)
+	informer, err := s.informers.get(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := informer.Lister().HTTPRoutes(namespace).Get(name)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			return nil, err
@@ -42,3 +93,80 @@ func (s *gatewayStore) GetHTTPRoute(name, namespace string) (*gatewayv1.HTTPRout
 
 	return result, nil
 }
+
+// ListHTTPRoutes returns HTTPRoutes from the cache matching the selector across all watched namespaces.
+func (s *gatewayStore) ListHTTPRoutes(selector labels.Selector) ([]*gatewayv1.HTTPRoute, error) {
+	var result []*gatewayv1.HTTPRoute
+	for _, informer := range s.informers.all() {
+		items, err := informer.Lister().List(selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing HTTPRoutes")
+		}
+		result = append(result, items...)
+	}
+
+	return result, nil
+}
+
+// GetReferenceGrant reads the ReferenceGrant of the placeholder backend from the cache. Other ReferenceGrants are not
+// cached and are read from the API server.
+func (s *gatewayStore) GetReferenceGrant(ctx context.Context, name, namespace string) (*gatewayv1beta1.ReferenceGrant, error) {
+	var result *gatewayv1beta1.ReferenceGrant
+	var err error
+	if s.grants != nil {
+		result, err = s.grants.Lister().ReferenceGrants(namespace).Get(name)
+	} else {
+		result, err = s.client.GatewayV1beta1().ReferenceGrants(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		return nil, errors.Wrapf(err, "error retrieving ReferenceGrant %s from namespace %s", name, namespace)
+	}
+
+	return result, nil
+}
+
+// AddReferenceGrantHandler registers the handler on the informer of the placeholder ReferenceGrant, if it is watched.
+func (s *gatewayStore) AddReferenceGrantHandler(handler cache.ResourceEventHandler) error {
+	if s.grants == nil {
+		return nil
+	}
+
+	_, err := s.grants.Informer().AddEventHandler(handler)
+	return errors.Wrap(err, "failed to add ReferenceGrant event handler")
+}
+
+func (s *gatewayStore) CreateReferenceGrant(ctx context.Context, grant *gatewayv1beta1.ReferenceGrant, options metav1.CreateOptions) (*gatewayv1beta1.ReferenceGrant, error) {
+	ctx, span := tracing.Start(ctx, "CreateReferenceGrant", tracing.Object(grant)...)
+	result, err := s.client.GatewayV1beta1().ReferenceGrants(grant.Namespace).Create(ctx, grant, s.dryRun.create(options))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create ReferenceGrant %s", k8sutil.Namespaced(grant))
+	}
+
+	s.dryRun.record(record.ReferenceGrantKind, metrics.VerbCreate, grant, nil, grant)
+
+	return result, nil
+}
+
+func (s *gatewayStore) UpdateReferenceGrant(ctx context.Context, grant *gatewayv1beta1.ReferenceGrant, options metav1.UpdateOptions) (*gatewayv1beta1.ReferenceGrant, error) {
+	ctx, span := tracing.Start(ctx, "UpdateReferenceGrant", tracing.Object(grant)...)
+	result, err := s.client.GatewayV1beta1().ReferenceGrants(grant.Namespace).Update(ctx, grant, s.dryRun.update(options))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update ReferenceGrant %s", k8sutil.Namespaced(grant))
+	}
+
+	if s.dryRun != nil {
+		var before interface{}
+		if cached, err := s.GetReferenceGrant(ctx, grant.Name, grant.Namespace); err == nil {
+			before = cached
+		}
+		s.dryRun.record(record.ReferenceGrantKind, metrics.VerbUpdate, grant, before, grant)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package stores
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// informerSet keeps a single cluster wide informer, keyed by metav1.NamespaceAll, or one informer per namespace
+// when the controller is restricted to a set of namespaces.
+type informerSet[T any] struct {
+	mu        sync.RWMutex
+	informers map[string]T
+}
+
+func newInformerSet[T any]() *informerSet[T] {
+	return &informerSet[T]{informers: map[string]T{}}
+}
+
+func (s *informerSet[T]) set(namespace string, informer T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.informers[namespace] = informer
+}
+
+func (s *informerSet[T]) delete(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.informers, namespace)
+}
+
+func (s *informerSet[T]) get(namespace string) (T, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if informer, ok := s.informers[metav1.NamespaceAll]; ok {
+		return informer, nil
+	}
+
+	if informer, ok := s.informers[namespace]; ok {
+		return informer, nil
+	}
+
+	var empty T
+	return empty, errors.Errorf("namespace %s is not watched by the controller", namespace)
+}
+
+func (s *informerSet[T]) all() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]T, 0, len(s.informers))
+	for _, informer := range s.informers {
+		result = append(result, informer)
+	}
+
+	return result
+}
+
+// namespaces returns the watched namespaces, metav1.NamespaceAll when the informer is cluster wide.
+func (s *informerSet[T]) namespaces() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]string, 0, len(s.informers))
+	for namespace := range s.informers {
+		result = append(result, namespace)
+	}
+
+	return result
+}
+
+// sharedInformer is implemented by the generated informers of every resource type.
+type sharedInformer interface {
+	Informer() cache.SharedIndexInformer
+}
+
+// synced checks that the informers of every watched namespace have synced.
+func synced[T sharedInformer](set *informerSet[T]) bool {
+	for _, informer := range set.all() {
+		if !informer.Informer().HasSynced() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// namespaceCancels stops the informers of a namespace when it is removed.
+type namespaceCancels struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func (n *namespaceCancels) add(namespace string, cancel context.CancelFunc) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.cancels == nil {
+		n.cancels = map[string]context.CancelFunc{}
+	}
+
+	if _, ok := n.cancels[namespace]; ok {
+		return false
+	}
+
+	n.cancels[namespace] = cancel
+	return true
+}
+
+func (n *namespaceCancels) remove(namespace string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if cancel, ok := n.cancels[namespace]; ok {
+		cancel()
+		delete(n.cancels, namespace)
+	}
+}
@@ -3,34 +3,80 @@ package stores
 import (
 	"context"
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 	"github.com/pkg/errors"
 	networkingv1 "k8s.io/api/networking/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
 type ingressStore struct {
-	client   kubernetes.Interface
-	informer networkinginformers.IngressInformer
+	client    kubernetes.Interface
+	informers *informerSet[networkinginformers.IngressInformer]
+	dryRun    *dryRun
 }
 
-func newIngressStore(client kubernetes.Interface, informer networkinginformers.IngressInformer) *ingressStore {
-	return &ingressStore{client: client, informer: informer}
+func newIngressStore(client kubernetes.Interface) *ingressStore {
+	return &ingressStore{client: client, informers: newInformerSet[networkinginformers.IngressInformer]()}
 }
 
 func (s *ingressStore) CreateIngress(ctx context.Context, ingress *networkingv1.Ingress, options metav1.CreateOptions) (*networkingv1.Ingress, error) {
-	result, err := s.client.NetworkingV1().Ingresses(ingress.Namespace).Create(ctx, ingress, options)
+	ctx, span := tracing.Start(ctx, "CreateIngress", tracing.Object(ingress)...)
+	result, err := s.client.NetworkingV1().Ingresses(ingress.Namespace).Create(ctx, ingress, s.dryRun.create(options))
+	tracing.End(span, err)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create Ingress %s", k8sutil.Namespaced(ingress))
 	}
 
+	s.dryRun.record(record.IngressKind, metrics.VerbCreate, ingress, nil, ingress)
+
 	return result, nil
 }
 
+func (s *ingressStore) UpdateIngress(ctx context.Context, ingress *networkingv1.Ingress, options metav1.UpdateOptions) (*networkingv1.Ingress, error) {
+	ctx, span := tracing.Start(ctx, "UpdateIngress", tracing.Object(ingress)...)
+	result, err := s.client.NetworkingV1().Ingresses(ingress.Namespace).Update(ctx, ingress, s.dryRun.update(options))
+	tracing.End(span, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update Ingress %s", k8sutil.Namespaced(ingress))
+	}
+
+	if s.dryRun != nil {
+		var before interface{}
+		if cached, err := s.GetIngress(ingress.Name, ingress.Namespace); err == nil {
+			before = cached
+		}
+		s.dryRun.record(record.IngressKind, metrics.VerbUpdate, ingress, before, ingress)
+	}
+
+	return result, nil
+}
+
+func (s *ingressStore) DeleteIngress(ctx context.Context, ingress *networkingv1.Ingress, options metav1.DeleteOptions) error {
+	ctx, span := tracing.Start(ctx, "DeleteIngress", tracing.Object(ingress)...)
+	err := s.client.NetworkingV1().Ingresses(ingress.Namespace).Delete(ctx, ingress.Name, s.dryRun.delete(options))
+	tracing.End(span, err)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete Ingress %s", k8sutil.Namespaced(ingress))
+	}
+
+	s.dryRun.record(record.IngressKind, metrics.VerbDelete, ingress, nil, nil)
+
+	return nil
+}
+
 func (s *ingressStore) GetIngress(name, namespace string) (*networkingv1.Ingress, error) {
-	result, err := s.informer.Lister().Ingresses(namespace).Get(name)
+	informer, err := s.informers.get(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := informer.Lister().Ingresses(namespace).Get(name)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			return nil, err
@@ -41,3 +87,17 @@ func (s *ingressStore) GetIngress(name, namespace string) (*networkingv1.Ingress
 
 	return result, nil
 }
+
+// ListIngresses returns Ingresses from the cache matching the selector across all watched namespaces.
+func (s *ingressStore) ListIngresses(selector labels.Selector) ([]*networkingv1.Ingress, error) {
+	var result []*networkingv1.Ingress
+	for _, informer := range s.informers.all() {
+		items, err := informer.Lister().List(selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing Ingresses")
+		}
+		result = append(result, items...)
+	}
+
+	return result, nil
+}
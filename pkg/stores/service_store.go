@@ -3,34 +3,68 @@ package stores
 import (
 	"context"
 	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/Octops/gameserver-ingress-controller/pkg/tracing"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
 type serviceStore struct {
-	client   kubernetes.Interface
-	informer coreinformers.ServiceInformer
+	client    kubernetes.Interface
+	informers *informerSet[coreinformers.ServiceInformer]
+	// placeholders cache the ExternalName Services of the placeholder backend, they are not labelled with
+	// agones.dev/gameserver. They are only watched if the placeholder backend is enabled.
+	placeholders *informerSet[coreinformers.ServiceInformer]
+	dryRun       *dryRun
 }
 
-func newServiceStore(client kubernetes.Interface, informer coreinformers.ServiceInformer) *serviceStore {
-	return &serviceStore{client: client, informer: informer}
+func newServiceStore(client kubernetes.Interface) *serviceStore {
+	return &serviceStore{
+		client:       client,
+		informers:    newInformerSet[coreinformers.ServiceInformer](),
+		placeholders: newInformerSet[coreinformers.ServiceInformer](),
+	}
 }
 
 func (s *serviceStore) CreateService(ctx context.Context, service *corev1.Service, options metav1.CreateOptions) (*corev1.Service, error) {
-	result, err := s.client.CoreV1().Services(service.Namespace).Create(ctx, service, options)
+	ctx, span := tracing.Start(ctx, "CreateService", tracing.Object(service)...)
+	result, err := s.client.CoreV1().Services(service.Namespace).Create(ctx, service, s.dryRun.create(options))
+	tracing.End(span, err)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create Service %s", k8sutil.Namespaced(service))
 	}
 
+	s.dryRun.record(record.ServiceKind, metrics.VerbCreate, service, nil, service)
+
 	return result, nil
 }
 
+func (s *serviceStore) DeleteService(ctx context.Context, service *corev1.Service, options metav1.DeleteOptions) error {
+	ctx, span := tracing.Start(ctx, "DeleteService", tracing.Object(service)...)
+	err := s.client.CoreV1().Services(service.Namespace).Delete(ctx, service.Name, s.dryRun.delete(options))
+	tracing.End(span, err)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete Service %s", k8sutil.Namespaced(service))
+	}
+
+	s.dryRun.record(record.ServiceKind, metrics.VerbDelete, service, nil, nil)
+
+	return nil
+}
+
 func (s *serviceStore) GetService(name, namespace string) (*corev1.Service, error) {
-	result, err := s.informer.Lister().Services(namespace).Get(name)
+	informer, err := s.informers.get(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := informer.Lister().Services(namespace).Get(name)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			return nil, err
@@ -41,3 +75,52 @@ func (s *serviceStore) GetService(name, namespace string) (*corev1.Service, erro
 
 	return result, nil
 }
+
+// ListServices returns Services from the cache matching the selector across all watched namespaces.
+func (s *serviceStore) ListServices(selector labels.Selector) ([]*corev1.Service, error) {
+	var result []*corev1.Service
+	for _, informer := range s.informers.all() {
+		items, err := informer.Lister().List(selector)
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing Services")
+		}
+		result = append(result, items...)
+	}
+
+	return result, nil
+}
+
+// GetPlaceholderService returns the ExternalName Service created for the placeholder backend in the namespace from
+// the cache.
+func (s *serviceStore) GetPlaceholderService(name, namespace string) (*corev1.Service, error) {
+	informer, err := s.placeholders.get(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := informer.Lister().Services(namespace).Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		return nil, errors.Wrapf(err, "error retrieving placeholder Service %s from namespace %s", name, namespace)
+	}
+
+	return result, nil
+}
+
+// ListPlaceholderServices returns the ExternalName Services created for the placeholder backend across all watched
+// namespaces from the cache.
+func (s *serviceStore) ListPlaceholderServices(_ context.Context) ([]*corev1.Service, error) {
+	var result []*corev1.Service
+	for _, informer := range s.placeholders.all() {
+		items, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			return nil, errors.Wrap(err, "error listing placeholder Services")
+		}
+		result = append(result, items...)
+	}
+
+	return result, nil
+}
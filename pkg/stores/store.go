@@ -5,7 +5,13 @@ import (
 	"time"
 
 	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/placeholder"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -18,18 +24,62 @@ type Store struct {
 	*serviceStore
 	*ingressStore
 	*gatewayStore
+	client     kubernetes.Interface
+	gwClient   gatewayclient.Interface
+	namespaced bool
+	// placeholders watches the ExternalName Services of the placeholder backend
+	placeholders bool
+	// grant is the ReferenceGrant of the placeholder backend, watched if set
+	grant   types.NamespacedName
+	dryRun  *dryRun
+	cancels namespaceCancels
+	routes  []cache.ResourceEventHandler
 }
 
-func NewStore(ctx context.Context, client kubernetes.Interface, restConfig *rest.Config, gatewayEnabled bool) (*Store, error) {
-	factory := informers.NewSharedInformerFactory(client, 0)
-	services := factory.Core().V1().Services()
-	ingresses := factory.Networking().V1().Ingresses()
+type StoreOption func(s *Store)
+
+// Namespaced does not start cluster wide informers. Informers are started for each namespace added with AddNamespace.
+func Namespaced() StoreOption {
+	return func(s *Store) {
+		s.namespaced = true
+	}
+}
+
+// WithPlaceholderServices caches the ExternalName Services created for the placeholder backend.
+func WithPlaceholderServices() StoreOption {
+	return func(s *Store) {
+		s.placeholders = true
+	}
+}
+
+// WithPlaceholderReferenceGrant caches the ReferenceGrant kept for the placeholder backend when the Gateway API is
+// enabled.
+func WithPlaceholderReferenceGrant(namespace, name string) StoreOption {
+	return func(s *Store) {
+		s.grant = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+}
 
-	go factory.Start(ctx.Done())
+// WithRouteHandlers registers the handlers on the Ingress and HTTPRoute informers of every namespace.
+func WithRouteHandlers(handlers ...cache.ResourceEventHandler) StoreOption {
+	return func(s *Store) {
+		s.routes = append(s.routes, handlers...)
+	}
+}
 
+// WithDryRun sends the writes to Services, Ingresses and HTTPRoutes with server-side dry-run, so they are validated
+// but never persisted. Every write is logged with a diff and counted.
+func WithDryRun() StoreOption {
+	return func(s *Store) {
+		s.dryRun = newDryRun()
+	}
+}
+
+func NewStore(ctx context.Context, client kubernetes.Interface, restConfig *rest.Config, gatewayEnabled bool, opts ...StoreOption) (*Store, error) {
 	store := &Store{
-		serviceStore: newServiceStore(client, services),
-		ingressStore: newIngressStore(client, ingresses),
+		serviceStore: newServiceStore(client),
+		ingressStore: newIngressStore(client),
+		client:       client,
 	}
 
 	if gatewayEnabled {
@@ -37,35 +87,171 @@ func NewStore(ctx context.Context, client kubernetes.Interface, restConfig *rest
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create gateway-api client")
 		}
-		gwFactory := gatewayinformers.NewSharedInformerFactory(gwClient, 0)
-		httpRoutes := gwFactory.Gateway().V1().HTTPRoutes()
-		go gwFactory.Start(ctx.Done())
-		store.gatewayStore = newGatewayStore(gwClient, httpRoutes)
+		store.gwClient = gwClient
+		store.gatewayStore = newGatewayStore(gwClient)
 	}
 
-	if err := store.HasSynced(ctx); err != nil {
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	store.serviceStore.dryRun = store.dryRun
+	store.ingressStore.dryRun = store.dryRun
+	if store.gatewayStore != nil {
+		store.gatewayStore.dryRun = store.dryRun
+	}
+
+	// The ReferenceGrant lives in the namespace of the placeholder backend, that may not be watched otherwise
+	if store.gatewayStore != nil && len(store.grant.Name) > 0 {
+		if err := store.startReferenceGrantInformer(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if store.namespaced {
+		return store, nil
+	}
+
+	syncFuncs := store.startInformers(ctx, metav1.NamespaceAll)
+	if err := hasSynced(ctx, "K8S", syncFuncs); err != nil {
 		return nil, errors.Wrap(err, "store failed to sync K8S cache")
 	}
 
 	return store, nil
 }
 
-func (s *Store) HasSynced(ctx context.Context) error {
+// AddNamespace starts the informers of a namespace and waits for them to sync.
+func (s *Store) AddNamespace(ctx context.Context, namespace string) error {
+	nsCtx, cancel := context.WithCancel(ctx)
+	if !s.cancels.add(namespace, cancel) {
+		cancel()
+		return nil
+	}
+
+	syncFuncs := s.startInformers(nsCtx, namespace)
+
+	stopper, stop := context.WithTimeout(nsCtx, time.Second*30)
+	defer stop()
+
+	if !cache.WaitForCacheSync(stopper.Done(), syncFuncs...) {
+		s.RemoveNamespace(namespace)
+		return errors.Errorf("timed out waiting for K8S cache of namespace %s to sync", namespace)
+	}
+
+	return nil
+}
+
+// RemoveNamespace stops the informers of a namespace.
+func (s *Store) RemoveNamespace(namespace string) {
+	s.serviceStore.informers.delete(namespace)
+	s.serviceStore.placeholders.delete(namespace)
+	s.ingressStore.informers.delete(namespace)
+	if s.gatewayStore != nil {
+		s.gatewayStore.informers.delete(namespace)
+	}
+	s.cancels.remove(namespace)
+}
+
+// HasSynced checks that the Service, placeholder Service, Ingress and HTTPRoute informers of every watched namespace have synced.
+func (s *Store) HasSynced() bool {
+	if !synced(s.serviceStore.informers) || !synced(s.serviceStore.placeholders) || !synced(s.ingressStore.informers) {
+		return false
+	}
+
+	if s.gatewayStore == nil {
+		return true
+	}
+
+	if s.gatewayStore.grants != nil && !s.gatewayStore.grants.Informer().HasSynced() {
+		return false
+	}
+
+	return synced(s.gatewayStore.informers)
+}
+
+// startInformers only lists and watches objects labelled with agones.dev/gameserver. Unrelated Services and Ingresses
+// are never cached. The placeholder ExternalName Services are cached by a separate informer filtered on their label.
+func (s *Store) startInformers(ctx context.Context, namespace string) []cache.InformerSynced {
+	selector := gameserver.ManagedSelector().String()
+	tweak := func(options *metav1.ListOptions) {
+		options.LabelSelector = selector
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(s.client, 0, informers.WithNamespace(namespace), informers.WithTweakListOptions(tweak))
+	services := factory.Core().V1().Services()
+	ingresses := factory.Networking().V1().Ingresses()
+
+	// Informers must be requested before the factory starts
 	syncFuncs := []cache.InformerSynced{
-		s.serviceStore.informer.Informer().HasSynced,
-		s.ingressStore.informer.Informer().HasSynced,
+		services.Informer().HasSynced,
+		ingresses.Informer().HasSynced,
 	}
+	s.addRouteHandlers(ingresses.Informer())
+	factory.Start(ctx.Done())
+
+	s.serviceStore.informers.set(namespace, services)
+	s.ingressStore.informers.set(namespace, ingresses)
+
+	if s.placeholders {
+		selector := labels.SelectorFromSet(labels.Set{placeholder.ServiceLabel: "true"}).String()
+		factory := informers.NewSharedInformerFactoryWithOptions(s.client, 0, informers.WithNamespace(namespace), informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = selector
+		}))
+		placeholders := factory.Core().V1().Services()
+		syncFuncs = append(syncFuncs, placeholders.Informer().HasSynced)
+		factory.Start(ctx.Done())
+		s.serviceStore.placeholders.set(namespace, placeholders)
+	}
+
 	if s.gatewayStore != nil {
-		syncFuncs = append(syncFuncs, s.gatewayStore.informer.Informer().HasSynced)
+		gwFactory := gatewayinformers.NewSharedInformerFactoryWithOptions(s.gwClient, 0, gatewayinformers.WithNamespace(namespace), gatewayinformers.WithTweakListOptions(tweak))
+		httpRoutes := gwFactory.Gateway().V1().HTTPRoutes()
+		syncFuncs = append(syncFuncs, httpRoutes.Informer().HasSynced)
+		s.addRouteHandlers(httpRoutes.Informer())
+		gwFactory.Start(ctx.Done())
+		s.gatewayStore.informers.set(namespace, httpRoutes)
 	}
 
+	return syncFuncs
+}
+
+// startReferenceGrantInformer only lists and watches the ReferenceGrant of the placeholder backend.
+func (s *Store) startReferenceGrantInformer(ctx context.Context) error {
+	selector := fields.OneTermEqualSelector("metadata.name", s.grant.Name).String()
+	factory := gatewayinformers.NewSharedInformerFactoryWithOptions(s.gwClient, 0, gatewayinformers.WithNamespace(s.grant.Namespace), gatewayinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+		options.FieldSelector = selector
+	}))
+	grants := factory.Gateway().V1beta1().ReferenceGrants()
+	informer := grants.Informer()
+	factory.Start(ctx.Done())
+
+	stopper, stop := context.WithTimeout(ctx, time.Second*30)
+	defer stop()
+
+	if !cache.WaitForCacheSync(stopper.Done(), informer.HasSynced) {
+		return errors.Errorf("timed out waiting for ReferenceGrant %s cache to sync", s.grant)
+	}
+
+	s.gatewayStore.grants = grants
+	return nil
+}
+
+func (s *Store) addRouteHandlers(informer cache.SharedIndexInformer) {
+	for _, h := range s.routes {
+		if _, err := informer.AddEventHandler(h); err != nil {
+			runtime.Logger().WithField("component", "store").WithError(err).Error("failed to add route event handler")
+		}
+	}
+}
+
+func hasSynced(ctx context.Context, name string, syncFuncs []cache.InformerSynced) error {
 	f := func() error {
 		stopper, cancel := context.WithTimeout(ctx, time.Second*15)
 		defer cancel()
 
-		runtime.Logger().WithField("component", "store").Info("waiting for K8S cache to sync")
+		runtime.Logger().WithField("component", "store").Infof("waiting for %s cache to sync", name)
 		if !cache.WaitForCacheSync(stopper.Done(), syncFuncs...) {
-			return errors.New("timed out waiting for K8S cache to sync")
+			return errors.Errorf("timed out waiting for %s cache to sync", name)
 		}
 		return nil
 	}
@@ -0,0 +1,336 @@
+package sweeper
+
+import (
+	"context"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/internal/runtime"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/k8sutil"
+	"github.com/Octops/gameserver-ingress-controller/pkg/metrics"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+type Mode string
+
+const (
+	// ModeOff disables the sweeper.
+	ModeOff Mode = "off"
+	// ModeReport records events and metrics for orphaned resources without deleting them.
+	ModeReport Mode = "report"
+	// ModeDelete deletes orphaned resources.
+	ModeDelete Mode = "delete"
+)
+
+type ServiceStore interface {
+	ListServices(selector labels.Selector) ([]*corev1.Service, error)
+	DeleteService(ctx context.Context, service *corev1.Service, options metav1.DeleteOptions) error
+}
+
+type IngressStore interface {
+	ListIngresses(selector labels.Selector) ([]*networkingv1.Ingress, error)
+	DeleteIngress(ctx context.Context, ingress *networkingv1.Ingress, options metav1.DeleteOptions) error
+}
+
+type HTTPRouteStore interface {
+	ListHTTPRoutes(selector labels.Selector) ([]*gatewayv1.HTTPRoute, error)
+	DeleteHTTPRoute(ctx context.Context, route *gatewayv1.HTTPRoute, options metav1.DeleteOptions) error
+}
+
+// PlaceholderStore lists the ExternalName Services created for the placeholder backend.
+type PlaceholderStore interface {
+	ListPlaceholderServices(ctx context.Context) ([]*corev1.Service, error)
+}
+
+type GameServerStore interface {
+	GetGameServer(ctx context.Context, name, namespace string) (*agonesv1.GameServer, error)
+}
+
+type Options struct {
+	Mode     Mode
+	Interval time.Duration
+	// MinAge protects resources created recently from being swept while the Agones cache catches up.
+	MinAge time.Duration
+	// Owns filters the GameServers checked by this replica when sharding is enabled. Every GameServer is checked if nil.
+	Owns func(key types.NamespacedName) bool
+	// Placeholders sweeps the ExternalName Services of the placeholder backend that no Ingress references anymore.
+	// They are not checked if nil.
+	Placeholders PlaceholderStore
+}
+
+type object interface {
+	metav1.Object
+	k8sruntime.Object
+}
+
+type candidate struct {
+	kind   string
+	obj    object
+	orphan func(ctx context.Context) (bool, error)
+	delete func(ctx context.Context, options metav1.DeleteOptions) error
+}
+
+// Sweeper periodically looks for Services, Ingresses and HTTPRoutes labelled with agones.dev/gameserver
+// that do not belong to a live GameServer. Owner references usually handle the cleanup, but resources are left
+// behind when GameServers are deleted with orphan propagation or after restores from backup.
+// The ExternalName Services of the placeholder backend have no owner, they are orphans once no Ingress of their
+// namespace references them.
+type Sweeper struct {
+	logger      *logrus.Entry
+	services    ServiceStore
+	ingresses   IngressStore
+	routes      HTTPRouteStore
+	gameservers GameServerStore
+	recorder    *record.EventRecorder
+	options     Options
+	now         func() time.Time
+}
+
+// NewSweeper returns a Sweeper. The routes store is optional and must be nil when the Gateway API backend is disabled.
+func NewSweeper(services ServiceStore, ingresses IngressStore, routes HTTPRouteStore, gameservers GameServerStore, recorder *record.EventRecorder, options Options) *Sweeper {
+	if options.Interval <= 0 {
+		options.Interval = time.Minute * 10
+	}
+
+	return &Sweeper{
+		logger:      runtime.Logger().WithField("component", "orphan_sweeper"),
+		services:    services,
+		ingresses:   ingresses,
+		routes:      routes,
+		gameservers: gameservers,
+		recorder:    recorder,
+		options:     options,
+		now:         time.Now,
+	}
+}
+
+// Start runs a sweep on every interval until the context is cancelled.
+func (s *Sweeper) Start(ctx context.Context) error {
+	s.logger.Infof("orphan sweeper started, mode=%s interval=%s", s.options.Mode, s.options.Interval)
+
+	ticker := time.NewTicker(s.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := s.Sweep(ctx); err != nil {
+				s.logger.WithError(err).Error("orphan sweep failed")
+			}
+		}
+	}
+}
+
+// Sweep checks every labelled resource and returns the number of orphans found by kind.
+func (s *Sweeper) Sweep(ctx context.Context) (map[string]int, error) {
+	metrics.OrphanSweeps.Inc()
+	metrics.OrphanSweepLastRun.SetToCurrentTime()
+
+	candidates, err := s.candidates(ctx)
+	if err != nil {
+		metrics.OrphanSweepErrors.Inc()
+		return nil, err
+	}
+
+	orphans := map[string]int{
+		record.ServiceKind:   0,
+		record.IngressKind:   0,
+		record.HTTPRouteKind: 0,
+	}
+
+	var errs []error
+	for _, c := range candidates {
+		orphan, err := c.orphan(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if !orphan {
+			continue
+		}
+
+		orphans[c.kind]++
+		logger := s.logger.WithFields(logrus.Fields{
+			"kind":                 c.kind,
+			"name":                 c.obj.GetName(),
+			runtime.FieldNamespace: c.obj.GetNamespace(),
+		})
+
+		if s.options.Mode != ModeDelete {
+			logger.Warn("orphaned resource found")
+			s.recorder.RecordOrphan(c.obj, c.kind, k8sutil.Namespaced(c.obj), false)
+			continue
+		}
+
+		start := time.Now()
+		err = c.delete(ctx, k8sutil.DeleteOptions(c.obj))
+		metrics.APIWriteDuration.WithLabelValues(c.kind, metrics.VerbDelete).Observe(time.Since(start).Seconds())
+		if err != nil && !k8serrors.IsNotFound(err) {
+			errs = append(errs, err)
+			continue
+		}
+
+		logger.Info("orphaned resource deleted")
+		metrics.OrphanResourcesDeleted.WithLabelValues(c.kind).Inc()
+		s.recorder.RecordOrphan(c.obj, c.kind, k8sutil.Namespaced(c.obj), true)
+	}
+
+	for kind, count := range orphans {
+		metrics.OrphanResources.WithLabelValues(kind).Set(float64(count))
+	}
+
+	if len(errs) > 0 {
+		metrics.OrphanSweepErrors.Inc()
+		return orphans, errors.Wrapf(errs[0], "orphan sweep finished with %d errors", len(errs))
+	}
+
+	return orphans, nil
+}
+
+func (s *Sweeper) candidates(ctx context.Context) ([]candidate, error) {
+	selector := gameserver.ManagedSelector()
+
+	var candidates []candidate
+	managed := func(kind string, obj object, del func(ctx context.Context, opts metav1.DeleteOptions) error) {
+		candidates = append(candidates, candidate{kind, obj, func(ctx context.Context) (bool, error) {
+			return s.isOrphan(ctx, obj)
+		}, del})
+	}
+
+	services, err := s.services.ListServices(selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range services {
+		svc := svc
+		managed(record.ServiceKind, svc, func(ctx context.Context, opts metav1.DeleteOptions) error {
+			return s.services.DeleteService(ctx, svc, opts)
+		})
+	}
+
+	ingresses, err := s.ingresses.ListIngresses(selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, ig := range ingresses {
+		ig := ig
+		managed(record.IngressKind, ig, func(ctx context.Context, opts metav1.DeleteOptions) error {
+			return s.ingresses.DeleteIngress(ctx, ig, opts)
+		})
+	}
+
+	if s.options.Placeholders != nil {
+		placeholders, err := s.options.Placeholders.ListPlaceholderServices(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range placeholders {
+			svc := svc
+			candidates = append(candidates, candidate{record.ServiceKind, svc, func(context.Context) (bool, error) {
+				return s.isUnreferenced(svc, ingresses), nil
+			}, func(ctx context.Context, opts metav1.DeleteOptions) error {
+				return s.services.DeleteService(ctx, svc, opts)
+			}})
+		}
+	}
+
+	if s.routes == nil {
+		return candidates, nil
+	}
+
+	routes, err := s.routes.ListHTTPRoutes(selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		route := route
+		managed(record.HTTPRouteKind, route, func(ctx context.Context, opts metav1.DeleteOptions) error {
+			return s.routes.DeleteHTTPRoute(ctx, route, opts)
+		})
+	}
+
+	return candidates, nil
+}
+
+// isUnreferenced checks if no Ingress of the namespace of the Service has it as a backend.
+func (s *Sweeper) isUnreferenced(service *corev1.Service, ingresses []*networkingv1.Ingress) bool {
+	if service.DeletionTimestamp != nil || s.now().Sub(service.CreationTimestamp.Time) < s.options.MinAge {
+		return false
+	}
+
+	references := func(backend *networkingv1.IngressBackend) bool {
+		return backend != nil && backend.Service != nil && backend.Service.Name == service.Name
+	}
+
+	for _, ig := range ingresses {
+		if ig.Namespace != service.Namespace {
+			continue
+		}
+
+		if references(ig.Spec.DefaultBackend) {
+			return false
+		}
+
+		for _, rule := range ig.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+
+			for _, path := range rule.HTTP.Paths {
+				if references(&path.Backend) {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// isOrphan checks if the GameServer named by the agones.dev/gameserver label is gone
+// or was replaced by a GameServer with a different UID.
+func (s *Sweeper) isOrphan(ctx context.Context, obj metav1.Object) (bool, error) {
+	if obj.GetDeletionTimestamp() != nil {
+		return false, nil
+	}
+
+	if s.now().Sub(obj.GetCreationTimestamp().Time) < s.options.MinAge {
+		return false, nil
+	}
+
+	name := obj.GetLabels()[gameserver.AgonesGameServerNameLabel]
+	if s.options.Owns != nil && !s.options.Owns(types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}) {
+		return false, nil
+	}
+
+	gs, err := s.gameservers.GetGameServer(ctx, name, obj.GetNamespace())
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Kind == "GameServer" {
+			return ref.UID != gs.UID, nil
+		}
+	}
+
+	return false, nil
+}
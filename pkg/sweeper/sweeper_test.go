@@ -0,0 +1,208 @@
+package sweeper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/Octops/gameserver-ingress-controller/pkg/record"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	k8srecord "k8s.io/client-go/tools/record"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+func Test_Sweeper_Sweep(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name       string
+		mode       Mode
+		gameserver *agonesv1.GameServer
+		created    time.Time
+		orphans    int
+		deleted    bool
+	}{
+		{
+			name:       "reports resources when gameserver is gone",
+			mode:       ModeReport,
+			gameserver: nil,
+			created:    now.Add(-time.Hour),
+			orphans:    1,
+		},
+		{
+			name:       "deletes resources when gameserver is gone",
+			mode:       ModeDelete,
+			gameserver: nil,
+			created:    now.Add(-time.Hour),
+			orphans:    1,
+			deleted:    true,
+		},
+		{
+			name:       "deletes resources owned by a replaced gameserver",
+			mode:       ModeDelete,
+			gameserver: newGameServer("game-1", "another-uid"),
+			created:    now.Add(-time.Hour),
+			orphans:    1,
+			deleted:    true,
+		},
+		{
+			name:       "keeps resources owned by a live gameserver",
+			mode:       ModeDelete,
+			gameserver: newGameServer("game-1", "gs-uid"),
+			created:    now.Add(-time.Hour),
+			orphans:    0,
+		},
+		{
+			name:       "keeps resources younger than min age",
+			mode:       ModeDelete,
+			gameserver: nil,
+			created:    now,
+			orphans:    0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newFakeStore(newGameServer("game-1", "gs-uid"), tc.gameserver, tc.created)
+			fakeRecorder := k8srecord.NewFakeRecorder(10)
+
+			s := NewSweeper(store, store, store, store, record.NewEventRecorder(fakeRecorder), Options{
+				Mode:   tc.mode,
+				MinAge: time.Minute,
+			})
+			s.now = func() time.Time { return now }
+
+			orphans, err := s.Sweep(context.Background())
+			require.NoError(t, err)
+			for _, kind := range []string{record.ServiceKind, record.IngressKind, record.HTTPRouteKind} {
+				require.Equal(t, tc.orphans, orphans[kind], kind)
+				require.Equal(t, tc.deleted, store.deleted[kind], kind)
+			}
+			require.Len(t, fakeRecorder.Events, tc.orphans*3)
+		})
+	}
+}
+
+func Test_Sweeper_Placeholders(t *testing.T) {
+	now := time.Now()
+	store := newFakeStore(newGameServer("game-1", "gs-uid"), newGameServer("game-1", "gs-uid"), now.Add(-time.Hour))
+	store.ingress.Spec.Rules = []networkingv1.IngressRule{{
+		IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+			Paths: []networkingv1.HTTPIngressPath{{Backend: networkingv1.IngressBackend{
+				Service: &networkingv1.IngressServiceBackend{Name: "octops-placeholder"},
+			}}},
+		}},
+	}}
+
+	placeholder := func(namespace string, created time.Time) *corev1.Service {
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:              "octops-placeholder",
+			Namespace:         namespace,
+			CreationTimestamp: metav1.NewTime(created),
+		}}
+	}
+	placeholders := &fakePlaceholderStore{services: []*corev1.Service{
+		placeholder("default", now.Add(-time.Hour)),
+		placeholder("unused", now.Add(-time.Hour)),
+		placeholder("recent", now),
+	}}
+
+	s := NewSweeper(store, store, nil, store, record.NewEventRecorder(k8srecord.NewFakeRecorder(10)), Options{
+		Mode:         ModeDelete,
+		MinAge:       time.Minute,
+		Placeholders: placeholders,
+	})
+	s.now = func() time.Time { return now }
+
+	orphans, err := s.Sweep(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, orphans[record.ServiceKind])
+}
+
+type fakePlaceholderStore struct {
+	services []*corev1.Service
+}
+
+func (s *fakePlaceholderStore) ListPlaceholderServices(_ context.Context) ([]*corev1.Service, error) {
+	return s.services, nil
+}
+
+func newGameServer(name, uid string) *agonesv1.GameServer {
+	return &agonesv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID(uid),
+		},
+	}
+}
+
+type fakeStore struct {
+	gameserver *agonesv1.GameServer
+	service    *corev1.Service
+	ingress    *networkingv1.Ingress
+	route      *gatewayv1.HTTPRoute
+	deleted    map[string]bool
+}
+
+func newFakeStore(owner, live *agonesv1.GameServer, created time.Time) *fakeStore {
+	meta := func() metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:              owner.Name,
+			Namespace:         owner.Namespace,
+			CreationTimestamp: metav1.NewTime(created),
+			Labels:            map[string]string{gameserver.AgonesGameServerNameLabel: owner.Name},
+			OwnerReferences:   []metav1.OwnerReference{*metav1.NewControllerRef(owner, agonesv1.SchemeGroupVersion.WithKind("GameServer"))},
+		}
+	}
+
+	return &fakeStore{
+		gameserver: live,
+		service:    &corev1.Service{ObjectMeta: meta()},
+		ingress:    &networkingv1.Ingress{ObjectMeta: meta()},
+		route:      &gatewayv1.HTTPRoute{ObjectMeta: meta()},
+		deleted:    map[string]bool{},
+	}
+}
+
+func (s *fakeStore) GetGameServer(_ context.Context, name, _ string) (*agonesv1.GameServer, error) {
+	if s.gameserver == nil {
+		return nil, k8serrors.NewNotFound(agonesv1.Resource("gameservers"), name)
+	}
+	return s.gameserver, nil
+}
+
+func (s *fakeStore) ListServices(_ labels.Selector) ([]*corev1.Service, error) {
+	return []*corev1.Service{s.service}, nil
+}
+
+func (s *fakeStore) DeleteService(_ context.Context, _ *corev1.Service, _ metav1.DeleteOptions) error {
+	s.deleted[record.ServiceKind] = true
+	return nil
+}
+
+func (s *fakeStore) ListIngresses(_ labels.Selector) ([]*networkingv1.Ingress, error) {
+	return []*networkingv1.Ingress{s.ingress}, nil
+}
+
+func (s *fakeStore) DeleteIngress(_ context.Context, _ *networkingv1.Ingress, _ metav1.DeleteOptions) error {
+	s.deleted[record.IngressKind] = true
+	return nil
+}
+
+func (s *fakeStore) ListHTTPRoutes(_ labels.Selector) ([]*gatewayv1.HTTPRoute, error) {
+	return []*gatewayv1.HTTPRoute{s.route}, nil
+}
+
+func (s *fakeStore) DeleteHTTPRoute(_ context.Context, _ *gatewayv1.HTTPRoute, _ metav1.DeleteOptions) error {
+	s.deleted[record.HTTPRouteKind] = true
+	return nil
+}
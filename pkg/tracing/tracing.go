@@ -0,0 +1,159 @@
+package tracing
+
+import (
+	"context"
+	"os"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/internal/version"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ServiceName is reported by the traces unless OTEL_SERVICE_NAME is set.
+	ServiceName = "octops-gameserver-ingress-controller"
+
+	instrumentationName = "github.com/Octops/gameserver-ingress-controller"
+)
+
+// Attributes set on the spans. Every span of a GameServer reconcile is keyed by its namespace, name and UID.
+const (
+	NamespaceKey  = attribute.Key("k8s.namespace.name")
+	GameServerKey = attribute.Key("octops.gameserver.name")
+	UIDKey        = attribute.Key("octops.gameserver.uid")
+	StateKey      = attribute.Key("octops.gameserver.state")
+	FleetKey      = attribute.Key("octops.fleet.name")
+	NameKey       = attribute.Key("octops.object.name")
+	AttemptKey    = attribute.Key("octops.reconcile.attempt")
+)
+
+// Options configure the OTLP gRPC exporter. The standard OTEL_EXPORTER_OTLP_* environment variables are honoured,
+// the options take precedence.
+type Options struct {
+	// Endpoint is the host:port of the collector. Tracing is disabled if it is empty and neither
+	// OTEL_EXPORTER_OTLP_ENDPOINT nor OTEL_EXPORTER_OTLP_TRACES_ENDPOINT are set.
+	Endpoint string
+	// Insecure disables TLS towards the collector.
+	Insecure bool
+	// SampleRatio is the fraction of reconciles traced. Child spans follow the decision of their parent.
+	SampleRatio float64
+}
+
+// Enabled checks if an endpoint was set using the options or the environment.
+func (o Options) Enabled() bool {
+	return len(o.Endpoint) > 0 ||
+		len(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")) > 0 ||
+		len(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")) > 0
+}
+
+// Setup registers a global tracer provider exporting spans to the OTLP collector. The returned function flushes the
+// pending spans and must be called on shutdown. If tracing is disabled spans are not recorded and the function is
+// a no-op.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	if !options.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	if options.SampleRatio < 0 || options.SampleRatio > 1 {
+		return nil, errors.Errorf("tracing sample ratio %v must be between 0 and 1", options.SampleRatio)
+	}
+
+	var opts []otlptracegrpc.Option
+	if len(options.Endpoint) > 0 {
+		opts = append(opts, otlptracegrpc.WithEndpoint(options.Endpoint))
+	}
+	if options.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create OTLP trace exporter")
+	}
+
+	res, err := resource.Merge(
+		resource.NewSchemaless(
+			attribute.String("service.name", ServiceName),
+			attribute.String("service.version", version.Version),
+		),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create tracing resource")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the controller from the global provider. Spans are dropped until Setup is called.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start creates a span that is a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records the error, if any, on the span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Request returns the attributes of a GameServer known only by its key, e.g. before it is read from the cache.
+func Request(key types.NamespacedName) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		NamespaceKey.String(key.Namespace),
+		GameServerKey.String(key.Name),
+	}
+}
+
+// GameServer returns the attributes identifying the GameServer and its state.
+func GameServer(gs *agonesv1.GameServer) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		NamespaceKey.String(gs.Namespace),
+		GameServerKey.String(gs.Name),
+		UIDKey.String(string(gs.UID)),
+		StateKey.String(string(gs.Status.State)),
+	}
+
+	if fleet, ok := gs.Labels[gameserver.AgonesFleetNameLabel]; ok {
+		attrs = append(attrs, FleetKey.String(fleet))
+	}
+
+	return attrs
+}
+
+// Object returns the attributes of a resource sent to the API server. Resources owned by a GameServer have the same
+// namespace and name.
+func Object(obj metav1.Object) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		NamespaceKey.String(obj.GetNamespace()),
+		NameKey.String(obj.GetName()),
+	}
+}
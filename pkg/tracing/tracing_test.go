@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	agonesv1 "agones.dev/agones/pkg/apis/agones/v1"
+	"github.com/Octops/gameserver-ingress-controller/pkg/gameserver"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_Options_Enabled(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  Options
+		env      map[string]string
+		expected bool
+	}{
+		{
+			name:     "disabled without endpoint",
+			expected: false,
+		},
+		{
+			name:     "enabled by the endpoint option",
+			options:  Options{Endpoint: "otel-collector:4317"},
+			expected: true,
+		},
+		{
+			name:     "enabled by the environment",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://otel-collector:4317"},
+			expected: true,
+		},
+		{
+			name:     "enabled by the traces environment",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://otel-collector:4317"},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+			t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			require.Equal(t, tc.expected, tc.options.Enabled())
+		})
+	}
+}
+
+func Test_Setup(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	shutdown, err := Setup(context.Background(), Options{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), Options{Endpoint: "otel-collector:4317", SampleRatio: 2})
+	require.EqualError(t, err, "tracing sample ratio 2 must be between 0 and 1")
+}
+
+func Test_GameServer(t *testing.T) {
+	gs := &agonesv1.GameServer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "game-1",
+			Namespace: "default",
+			UID:       "gs-uid",
+			Labels:    map[string]string{gameserver.AgonesFleetNameLabel: "fleet-1"},
+		},
+		Status: agonesv1.GameServerStatus{State: agonesv1.GameServerStateReady},
+	}
+
+	require.ElementsMatch(t, []attribute.KeyValue{
+		NamespaceKey.String("default"),
+		GameServerKey.String("game-1"),
+		UIDKey.String("gs-uid"),
+		StateKey.String("Ready"),
+		FleetKey.String("fleet-1"),
+	}, GameServer(gs))
+}
+
+func Test_End(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+
+	_, span := tracer.Start(context.Background(), "succeeded")
+	End(span, nil)
+
+	_, span = tracer.Start(context.Background(), "failed")
+	End(span, errors.New("failed to create ingress"))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, codes.Unset, spans[0].Status.Code)
+	require.Empty(t, spans[0].Events)
+	require.Equal(t, codes.Error, spans[1].Status.Code)
+	require.Equal(t, "failed to create ingress", spans[1].Status.Description)
+	require.Len(t, spans[1].Events, 1)
+}